@@ -0,0 +1,43 @@
+package CodeTemplateGenerator
+
+// KnownTypes maps the simple names of commonly used library types to their fully
+// qualified names. Types of java.lang need no import and are not listed.
+var KnownTypes = map[string]string{
+	// java.util
+	"ArrayList":     "java.util.ArrayList",
+	"Arrays":        "java.util.Arrays",
+	"Collection":    "java.util.Collection",
+	"Collections":   "java.util.Collections",
+	"Deque":         "java.util.Deque",
+	"HashMap":       "java.util.HashMap",
+	"HashSet":       "java.util.HashSet",
+	"Iterator":      "java.util.Iterator",
+	"LinkedHashMap": "java.util.LinkedHashMap",
+	"LinkedHashSet": "java.util.LinkedHashSet",
+	"LinkedList":    "java.util.LinkedList",
+	"List":          "java.util.List",
+	"Map":           "java.util.Map",
+	"Objects":       "java.util.Objects",
+	"Optional":      "java.util.Optional",
+	"Queue":         "java.util.Queue",
+	"Set":           "java.util.Set",
+	"SortedMap":     "java.util.SortedMap",
+	"SortedSet":     "java.util.SortedSet",
+	"TreeMap":       "java.util.TreeMap",
+	"TreeSet":       "java.util.TreeSet",
+	"UUID":          "java.util.UUID",
+	// java.time
+	"Duration":       "java.time.Duration",
+	"Instant":        "java.time.Instant",
+	"LocalDate":      "java.time.LocalDate",
+	"LocalDateTime":  "java.time.LocalDateTime",
+	"LocalTime":      "java.time.LocalTime",
+	"OffsetDateTime": "java.time.OffsetDateTime",
+	"Period":         "java.time.Period",
+	"ZonedDateTime":  "java.time.ZonedDateTime",
+	"ZoneId":         "java.time.ZoneId",
+	// java.math
+	"BigDecimal":   "java.math.BigDecimal",
+	"BigInteger":   "java.math.BigInteger",
+	"RoundingMode": "java.math.RoundingMode",
+}
@@ -0,0 +1,263 @@
+package CodeTemplateGenerator
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Protected regions keep hand-written code when a file is generated again. The templates
+// mark the places for such code:
+//
+//	// merfolk:begin custom total
+//	sum += shipping;
+//	// merfolk:end
+//
+// When a file is regenerated, the lines between the markers are taken from the existing file.
+// Regions whose name no longer appears in the generated code, for example because the method
+// was removed from the diagram, are orphaned: they are kept commented out in front of the
+// closing brace of the class, marked as "orphaned <name>", so that no code is lost.
+const (
+	regionBegin = "// merfolk:begin "
+	regionEnd   = "// merfolk:end"
+	// orphanedPrefix is prepended to the names of orphaned regions.
+	orphanedPrefix = "orphaned "
+)
+
+// Region is a protected region of a generated file.
+type Region struct {
+	// Name identifies the region, e.g. "custom total" for the body of the method total.
+	Name string
+	// Line is the 1-based line of the begin marker.
+	Line int
+	// Lines are the lines between the markers.
+	Lines []string
+}
+
+// ParseRegions returns the protected regions of a file in the order they appear.
+func ParseRegions(code []byte) ([]Region, error) {
+	var regions []Region
+	var current *Region
+
+	for i, line := range splitLines(code) {
+		text := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(text, regionBegin):
+			if current != nil {
+				return nil, fmt.Errorf("line %d: region %q starts inside of region %q", i+1, strings.TrimPrefix(text, regionBegin), current.Name)
+			}
+			current = &Region{Name: strings.TrimSpace(strings.TrimPrefix(text, regionBegin)), Line: i + 1}
+		case text == regionEnd:
+			if current == nil {
+				return nil, fmt.Errorf("line %d: region end without begin", i+1)
+			}
+			regions = append(regions, *current)
+			current = nil
+		case current != nil:
+			current.Lines = append(current.Lines, line)
+		}
+	}
+
+	if current != nil {
+		return nil, fmt.Errorf("line %d: region %q is not closed", current.Line, current.Name)
+	}
+	return regions, nil
+}
+
+// MergeRegions copies the protected regions of the existing file into the freshly generated
+// code. Regions that occur more than once, like the bodies of overloaded methods, are matched
+// in order. base is the code generated for the existing file, or nil if it is unknown: regions
+// of existing that equal their counterpart in base, or are blank if there is none, were not
+// edited and keep the generated code.
+// The edited regions of existing that have no counterpart in generated are kept as orphaned
+// regions; the ones that were not orphaned before are returned so that they can be reported.
+func MergeRegions(generated, existing, base []byte) ([]byte, []Region, error) {
+	old, err := ParseRegions(existing)
+	if err != nil {
+		return nil, nil, fmt.Errorf("existing file: %w", err)
+	}
+	if len(old) == 0 {
+		return generated, nil, nil
+	}
+	baseRegions, err := ParseRegions(base)
+	if err != nil {
+		return nil, nil, fmt.Errorf("merge base: %w", err)
+	}
+
+	// The content of the regions in base by name, in order of appearance
+	baseContents := make(map[string][][]string)
+	for _, region := range baseRegions {
+		baseContents[region.Name] = append(baseContents[region.Name], region.Lines)
+	}
+
+	// The indices of the existing regions by name, in order of appearance
+	indices := make(map[string][]int)
+	edited := make([]bool, len(old))
+	for i, region := range old {
+		indices[region.Name] = append(indices[region.Name], i)
+		if queue := baseContents[region.Name]; len(queue) > 0 {
+			baseContents[region.Name] = queue[1:]
+			edited[i] = !slices.Equal(queue[0], region.Lines)
+		} else {
+			// Without base only regions with code count as edited
+			edited[i] = !isBlank(region.Lines)
+		}
+	}
+	matched := make([]bool, len(old))
+
+	merged := replaceRegions(splitLines(generated), func(name string) ([]string, bool) {
+		queue := indices[name]
+		if len(queue) == 0 {
+			return nil, false
+		}
+		indices[name] = queue[1:]
+		matched[queue[0]] = true
+		return old[queue[0]].Lines, edited[queue[0]]
+	})
+
+	// The edited regions left over are orphaned
+	var orphaned, newlyOrphaned []Region
+	for i, region := range old {
+		if edited[i] && !matched[i] {
+			orphaned = append(orphaned, region)
+			if !strings.HasPrefix(region.Name, orphanedPrefix) {
+				newlyOrphaned = append(newlyOrphaned, region)
+			}
+		}
+	}
+	if len(orphaned) > 0 {
+		merged = insertOrphaned(merged, orphaned)
+	}
+
+	result := strings.Join(merged, "\n")
+	if bytes.HasSuffix(generated, []byte("\n")) {
+		result += "\n"
+	}
+	return []byte(result), newlyOrphaned, nil
+}
+
+// ResetRegions replaces the content of the protected regions of code by the content of their
+// counterparts in base, matched by name and in order. Regions without counterpart are kept.
+// With the result, a three-way merge sees only the edits outside of the regions.
+func ResetRegions(code, base []byte) ([]byte, error) {
+	baseRegions, err := ParseRegions(base)
+	if err != nil {
+		return nil, fmt.Errorf("merge base: %w", err)
+	}
+	if len(baseRegions) == 0 {
+		return code, nil
+	}
+	contents := make(map[string][][]string)
+	for _, region := range baseRegions {
+		contents[region.Name] = append(contents[region.Name], region.Lines)
+	}
+
+	reset := replaceRegions(splitLines(code), func(name string) ([]string, bool) {
+		queue := contents[name]
+		if len(queue) == 0 {
+			return nil, false
+		}
+		contents[name] = queue[1:]
+		return queue[0], true
+	})
+
+	result := strings.Join(reset, "\n")
+	if bytes.HasSuffix(code, []byte("\n")) {
+		result += "\n"
+	}
+	return []byte(result), nil
+}
+
+// isBlank reports whether lines contain nothing but white space.
+func isBlank(lines []string) bool {
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// replaceRegions returns lines with the content of the protected regions for which content
+// reports a replacement replaced. content is called for every region in order.
+func replaceRegions(lines []string, content func(name string) ([]string, bool)) []string {
+	var replaced []string
+	inRegion := false
+	for _, line := range lines {
+		text := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(text, regionBegin):
+			replaced = append(replaced, line)
+			if region, ok := content(strings.TrimSpace(strings.TrimPrefix(text, regionBegin))); ok {
+				replaced = append(replaced, region...)
+				inRegion = true
+			}
+		case text == regionEnd:
+			replaced = append(replaced, line)
+			inRegion = false
+		case !inRegion:
+			replaced = append(replaced, line)
+		}
+	}
+	return replaced
+}
+
+// insertOrphaned adds the orphaned regions in front of the last closing brace. The code of
+// regions that become orphaned is commented out, so that the file still compiles.
+func insertOrphaned(lines []string, orphaned []Region) []string {
+	closing := len(lines)
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.TrimSpace(lines[i]) == "}" {
+			closing = i
+			break
+		}
+	}
+
+	const indent = "    "
+	var block []string
+	for _, region := range orphaned {
+		name, content := region.Name, region.Lines
+		if !strings.HasPrefix(name, orphanedPrefix) {
+			name = orphanedPrefix + name
+			content = commentOut(region.Lines, indent)
+		}
+		block = append(block, "", indent+regionBegin+name)
+		block = append(block, content...)
+		block = append(block, indent+regionEnd)
+	}
+
+	result := append([]string(nil), lines[:closing]...)
+	result = append(result, block...)
+	return append(result, lines[closing:]...)
+}
+
+// commentOut turns lines into line comments at the given indentation. The relative
+// indentation of the lines is kept.
+func commentOut(lines []string, indent string) []string {
+	common := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if width := len(line) - len(strings.TrimLeft(line, " \t")); common < 0 || width < common {
+			common = width
+		}
+	}
+
+	result := make([]string, len(lines))
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			result[i] = indent + "//"
+			continue
+		}
+		result[i] = indent + "// " + line[common:]
+	}
+	return result
+}
+
+// splitLines splits code into lines without line terminators.
+func splitLines(code []byte) []string {
+	text := strings.ReplaceAll(string(code), "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
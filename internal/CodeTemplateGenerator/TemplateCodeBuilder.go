@@ -1,27 +1,59 @@
 package CodeTemplateGenerator
 
 import (
+	"bytes"
+	"embed"
 	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"text/template"
 )
 
-// TemplateGeneratorUtility Utility functions for creating templates
-func TemplateGeneratorUtility() template.FuncMap {
+// Options control the naming and the parts of the code that are generated.
+type Options struct {
+	// Types maps type names to Java types and default values, nil uses the built-in mappings.
+	Types TypeMap
+	// InterfacePrefix is prepended to the names of generated interfaces.
+	InterfacePrefix string
+	// SkipGetters, SkipSetters and SkipConstructors turn off parts of the class template.
+	SkipGetters      bool
+	SkipSetters      bool
+	SkipConstructors bool
+}
+
+// DefaultOptions returns the options used if nothing is configured.
+func DefaultOptions() Options {
+	return Options{InterfacePrefix: "I"}
+}
+
+//go:embed ClassTemplate.tmpl InterfaceTemplate.tmpl
+var defaultTemplates embed.FS
+
+// TemplateGeneratorUtility Utility functions for creating templates. Default values are taken
+// from the type mapping of the options.
+func TemplateGeneratorUtility(options Options) template.FuncMap {
 	return template.FuncMap{
 		// Returns the default value for the given data type.
 		"defaultZero": func(typeName string) string {
-			switch typeName {
-			case "int":
-				return "0"
-			case "boolean":
-				return "false"
-			case "double", "float":
-				return "0.0"
+			return options.Types.DefaultValue(typeName)
+		},
+		// Returns the prefix of interface names
+		"interfacePrefix": func() string {
+			return options.InterfacePrefix
+		},
+		// Reports whether an optional part of the code (getters, setters, constructors) is generated
+		"generate": func(feature string) bool {
+			switch feature {
+			case "getters":
+				return !options.SkipGetters
+			case "setters":
+				return !options.SkipSetters
+			case "constructors":
+				return !options.SkipConstructors
 			default:
-				return "null"
+				return true
 			}
 		},
 		// Capitalizes the first letter of the input string, leaving the rest unchanged
@@ -31,13 +63,17 @@ func TemplateGeneratorUtility() template.FuncMap {
 			}
 			return string(str[0]^32) + str[1:]
 		},
-		// Formats the given string so that the result is enclosed in double quotes ("")
+		// Formats the given string so that the result is enclosed in double quotes ("").
+		// Values that already are string literals, such as default values, are kept.
 		"stringFormation": func(typeName string, value any) any {
 			if value == nil {
 				return "null"
 			}
 			switch typeName {
 			case "String":
+				if literal, ok := value.(string); ok && len(literal) >= 2 && strings.HasPrefix(literal, "\"") && strings.HasSuffix(literal, "\"") {
+					return literal
+				}
 				return fmt.Sprintf("\"%v\"", value)
 			default:
 				return value
@@ -46,20 +82,66 @@ func TemplateGeneratorUtility() template.FuncMap {
 	}
 }
 
-// GenerateJavaCode Generate out of the data an Interface or Java class
-func GenerateJavaCode[T Class | Interface](dataStruct T, outputPath string, outputFileName string, templateFile string) error {
+// JavaFileName returns the name of the Java file, without extension, for a class or interface.
+func JavaFileName[T Class | Interface](dataStruct T, options Options) string {
+	switch data := any(dataStruct).(type) {
+	case Interface:
+		return options.InterfacePrefix + data.InterfaceName
+	case Class:
+		return data.ClassName
+	}
+	return ""
+}
 
-	templateStruct, err := //template.New(templateFile).Funcs(TemplateGeneratorUtility()).ParseFiles(templateFile)
-		template.New(filepath.Base(templateFile)).Funcs(TemplateGeneratorUtility()).ParseFiles(templateFile)
+// RenderJavaCode fills the template with a class or interface. An empty templateFile selects
+// the built-in template for the type of dataStruct.
+func RenderJavaCode[T Class | Interface](dataStruct T, templateFile string, options Options) ([]byte, error) {
+	var templateStruct *template.Template
+	var err error
+	if templateFile == "" {
+		name := "ClassTemplate.tmpl"
+		if reflect.TypeOf(dataStruct).Name() == "Interface" {
+			name = "InterfaceTemplate.tmpl"
+		}
+		templateFile = name
+		templateStruct, err = template.New(name).Funcs(TemplateGeneratorUtility(options)).ParseFS(defaultTemplates, name)
+	} else {
+		templateStruct, err = template.New(filepath.Base(templateFile)).Funcs(TemplateGeneratorUtility(options)).ParseFiles(templateFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the %s template: %w", templateFile, err)
+	}
+
+	var buffer bytes.Buffer
+	err = templateStruct.Execute(&buffer, dataStruct)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fill the %s template: %w", templateFile, err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// GenerateJavaCode Generate out of the data an Interface or Java class. The protected regions
+// of an existing file are kept, see MergeRegions.
+func GenerateJavaCode[T Class | Interface](dataStruct T, outputPath string, outputFileName string, templateFile string, options Options) error {
 	if reflect.TypeOf(dataStruct).Name() == "Interface" {
-		outputFileName = "I" + outputFileName
+		outputFileName = options.InterfacePrefix + outputFileName
 	}
 
+	code, err := RenderJavaCode(dataStruct, templateFile, options)
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	fileName := outputPath + outputFileName + ".java"
+	if existing, err := os.ReadFile(fileName); err == nil {
+		code, _, err = MergeRegions(code, existing, nil)
+		if err != nil {
+			return fmt.Errorf("failed to keep the protected regions of %s: %w", fileName, err)
+		}
 	}
 
-	file, err := os.Create(outputPath + outputFileName + ".java")
+	file, err := os.Create(fileName)
 	if err != nil {
 		return fmt.Errorf("failed to create %v file: %w", file, err)
 	}
@@ -70,9 +152,9 @@ func GenerateJavaCode[T Class | Interface](dataStruct T, outputPath string, outp
 		}
 	}(file)
 
-	err = templateStruct.Execute(file, dataStruct)
+	_, err = file.Write(code)
 	if err != nil {
-		return fmt.Errorf("failed to fill the %s template: %w", templateFile, err)
+		return fmt.Errorf("failed to write %s: %w", file.Name(), err)
 	}
 
 	return nil
@@ -1,64 +1,90 @@
 package CodeTemplateGenerator
 
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// The JSON and YAML names of the fields are those of the model files that merfolk export
+// writes and merfolk import reads, see internal/exchange/model.schema.json.
+
 type Class struct {
-	ClassName   string
-	Abstraction []string
-	Inherits    string
-	Attributes  []Attribute
-	Methods     []Method
+	Package     string      `json:"package,omitempty" yaml:"package,omitempty"`
+	Imports     []string    `json:"imports,omitempty" yaml:"imports,omitempty"`
+	ClassName   string      `json:"name" yaml:"name"`
+	Abstraction []string    `json:"implements,omitempty" yaml:"implements,omitempty"`
+	Inherits    string      `json:"extends,omitempty" yaml:"extends,omitempty"`
+	Attributes  []Attribute `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+	Methods     []Method    `json:"methods,omitempty" yaml:"methods,omitempty"`
 }
 
 // Attribute IsClassVariable/ True, falls es sich um eine Klassenvariable handelt */
 // Attribute IsConstant/ True, falls es sich um eine Konstante handelt
 type Attribute struct {
-	AccessModifier         string
-	Name                   string
-	Type                   string
-	IsClassVariable        bool
-	IsConstant             bool
-	IsAttributeInitialized bool
-	ObjectConstructorArgs  []ConstructorArg
-	IsObject               bool
-	Value                  any
+	AccessModifier         string           `json:"access,omitempty" yaml:"access,omitempty"`
+	Name                   string           `json:"name" yaml:"name"`
+	Type                   string           `json:"type,omitempty" yaml:"type,omitempty"`
+	IsClassVariable        bool             `json:"static,omitempty" yaml:"static,omitempty"`
+	IsConstant             bool             `json:"constant,omitempty" yaml:"constant,omitempty"`
+	IsAttributeInitialized bool             `json:"initialized,omitempty" yaml:"initialized,omitempty"`
+	ObjectConstructorArgs  []ConstructorArg `json:"constructorArgs,omitempty" yaml:"constructorArgs,omitempty"`
+	IsObject               bool             `json:"object,omitempty" yaml:"object,omitempty"`
+	Value                  any              `json:"value,omitempty" yaml:"value,omitempty"`
 }
 
 type Body struct {
 	// Object creation
-	IsObjectCreation  bool
-	ObjectName        string
-	ObjectType        string
-	ObjFuncParameters []Attribute
+	IsObjectCreation  bool        `json:"objectCreation,omitempty" yaml:"objectCreation,omitempty"`
+	ObjectName        string      `json:"objectName,omitempty" yaml:"objectName,omitempty"`
+	ObjectType        string      `json:"objectType,omitempty" yaml:"objectType,omitempty"`
+	ObjFuncParameters []Attribute `json:"arguments,omitempty" yaml:"arguments,omitempty"`
 	// passing  method results into variable
-	IsVariable   bool
-	FunctionName string
+	IsVariable   bool   `json:"variable,omitempty" yaml:"variable,omitempty"`
+	FunctionName string `json:"function,omitempty" yaml:"function,omitempty"`
 	// if Condition
-	IsCondition bool
-	Condition   string
-	IfBody      []Body
-	ElseBody    []Body
+	IsCondition bool   `json:"conditional,omitempty" yaml:"conditional,omitempty"`
+	Condition   string `json:"condition,omitempty" yaml:"condition,omitempty"`
+	IfBody      []Body `json:"then,omitempty" yaml:"then,omitempty"`
+	ElseBody    []Body `json:"else,omitempty" yaml:"else,omitempty"`
 	// Deklaration und/oder Initialisierung von Variablen
-	IsDeclaration bool
-	Variable      Attribute
+	IsDeclaration bool      `json:"declaration,omitempty" yaml:"declaration,omitempty"`
+	Variable      Attribute `json:"declared" yaml:"declared,omitempty"`
+}
+
+// MarshalJSON leaves out the variable of statements that declare none, like yaml.v3 does for
+// omitempty structs.
+func (b Body) MarshalJSON() ([]byte, error) {
+	type body Body
+	value := struct {
+		body
+		Variable *Attribute `json:"declared,omitempty"`
+	}{body: body(b)}
+	if !reflect.ValueOf(b.Variable).IsZero() {
+		value.Variable = &b.Variable
+	}
+	return json.Marshal(value)
 }
 
 type Method struct {
-	AccessModifier string
-	Name           string
-	IsStatic       bool
-	ReturnType     string
-	Parameters     []Attribute
-	MethodBody     []Body
-	ReturnValue    string
+	AccessModifier string      `json:"access,omitempty" yaml:"access,omitempty"`
+	Name           string      `json:"name" yaml:"name"`
+	IsStatic       bool        `json:"static,omitempty" yaml:"static,omitempty"`
+	ReturnType     string      `json:"returnType,omitempty" yaml:"returnType,omitempty"`
+	Parameters     []Attribute `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	MethodBody     []Body      `json:"body,omitempty" yaml:"body,omitempty"`
+	ReturnValue    string      `json:"returnValue,omitempty" yaml:"returnValue,omitempty"`
 }
 
 type Interface struct {
-	InterfaceName      string
-	Inherits           []string
-	AbstractAttributes []Attribute
-	AbstractMethods    []Method
+	Package            string      `json:"package,omitempty" yaml:"package,omitempty"`
+	Imports            []string    `json:"imports,omitempty" yaml:"imports,omitempty"`
+	InterfaceName      string      `json:"name" yaml:"name"`
+	Inherits           []string    `json:"extends,omitempty" yaml:"extends,omitempty"`
+	AbstractAttributes []Attribute `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+	AbstractMethods    []Method    `json:"methods,omitempty" yaml:"methods,omitempty"`
 }
 
 type ConstructorArg struct {
-	Type  string
-	Value string
+	Type  string `json:"type" yaml:"type"`
+	Value string `json:"value" yaml:"value"`
 }
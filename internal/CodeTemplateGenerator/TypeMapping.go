@@ -0,0 +1,143 @@
+package CodeTemplateGenerator
+
+import (
+	"sort"
+	"strings"
+)
+
+// TypeMapping describes how a type used in the diagrams is generated.
+type TypeMapping struct {
+	// Target is the Java type. A fully qualified target is imported and used by its
+	// simple name. If empty, the diagram type name is used.
+	Target string `yaml:"target,omitempty" json:"target,omitempty"`
+	// Default is the value variables of this type are initialised with.
+	Default string `yaml:"default,omitempty" json:"default,omitempty"`
+	// Value marks primitives and immutable types that are initialised with Default
+	// instead of creating a new object.
+	Value bool `yaml:"value,omitempty" json:"value,omitempty"`
+	// Boxed is the type used in place of a primitive inside generic types.
+	Boxed string `yaml:"boxed,omitempty" json:"boxed,omitempty"`
+	// Import is the fully qualified name to import, derived from Target if empty.
+	Import string `yaml:"import,omitempty" json:"import,omitempty"`
+}
+
+// TypeMap maps diagram type names to their mappings. A nil TypeMap uses the built-in mappings.
+type TypeMap map[string]TypeMapping
+
+var builtinTypes = TypeMap{
+	"int":       {Default: "0", Value: true, Boxed: "Integer"},
+	"long":      {Default: "0L", Value: true, Boxed: "Long"},
+	"short":     {Default: "0", Value: true, Boxed: "Short"},
+	"byte":      {Default: "0", Value: true, Boxed: "Byte"},
+	"char":      {Default: `'\u0000'`, Value: true, Boxed: "Character"},
+	"float":     {Default: "0.0f", Value: true, Boxed: "Float"},
+	"double":    {Default: "0.0", Value: true, Boxed: "Double"},
+	"boolean":   {Default: "false", Value: true, Boxed: "Boolean"},
+	"Integer":   {Default: "0", Value: true},
+	"Long":      {Default: "0L", Value: true},
+	"Short":     {Default: "0", Value: true},
+	"Byte":      {Default: "0", Value: true},
+	"Character": {Default: `'\u0000'`, Value: true},
+	"Float":     {Default: "0.0f", Value: true},
+	"Double":    {Default: "0.0", Value: true},
+	"Boolean":   {Default: "false", Value: true},
+	"String":    {Default: `""`, Value: true},
+	// Collection interfaces cannot be instantiated, they default to an empty implementation
+	"List":       {Default: "new java.util.ArrayList<>()", Value: true},
+	"Set":        {Default: "new java.util.HashSet<>()", Value: true},
+	"Map":        {Default: "new java.util.HashMap<>()", Value: true},
+	"Collection": {Default: "new java.util.ArrayList<>()", Value: true},
+	"Optional":   {Default: "java.util.Optional.empty()", Value: true},
+}
+
+// NewTypeMap returns the built-in mappings extended by overrides.
+func NewTypeMap(overrides map[string]TypeMapping) TypeMap {
+	types := make(TypeMap, len(builtinTypes)+len(overrides))
+	for name, mapping := range builtinTypes {
+		types[name] = mapping
+	}
+	for name, mapping := range overrides {
+		types[name] = mapping
+	}
+	return types
+}
+
+// Lookup returns the mapping of a diagram type name. Generated Java type names are
+// found as well, so that templates can look up types that were already mapped.
+// Generic types are looked up by their raw type, e.g. List~int~ and List<Integer> by List.
+func (types TypeMap) Lookup(typeName string) (TypeMapping, bool) {
+	if types == nil {
+		types = builtinTypes
+	}
+	if i := strings.IndexAny(typeName, "<~"); i > 0 {
+		typeName = typeName[:i]
+	}
+	if mapping, exists := types[typeName]; exists {
+		return mapping, true
+	}
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		mapping := types[name]
+		if mapping.Target != "" && (mapping.Target == typeName || simpleName(mapping.Target) == typeName) {
+			return mapping, true
+		}
+	}
+	return TypeMapping{}, false
+}
+
+// JavaType returns the Java name of a diagram type.
+func (types TypeMap) JavaType(typeName string) string {
+	if mapping, exists := types.Lookup(typeName); exists && mapping.Target != "" {
+		return simpleName(mapping.Target)
+	}
+	return typeName
+}
+
+// BoxedType returns the type to use for a diagram type inside a generic type.
+func (types TypeMap) BoxedType(typeName string) string {
+	if mapping, exists := types.Lookup(typeName); exists && mapping.Boxed != "" {
+		return mapping.Boxed
+	}
+	return types.JavaType(typeName)
+}
+
+// IsValueType reports whether variables of the type are initialised with a default value
+// rather than a new object.
+func (types TypeMap) IsValueType(typeName string) bool {
+	mapping, exists := types.Lookup(typeName)
+	return exists && mapping.Value
+}
+
+// DefaultValue returns the default value of a type, null for unknown types.
+func (types TypeMap) DefaultValue(typeName string) string {
+	if mapping, exists := types.Lookup(typeName); exists && mapping.Default != "" {
+		return mapping.Default
+	}
+	return "null"
+}
+
+// Imports returns the simple names and fully qualified names of all mapped types that need an import.
+func (types TypeMap) Imports() map[string]string {
+	if types == nil {
+		types = builtinTypes
+	}
+	imports := make(map[string]string)
+	for _, mapping := range types {
+		fullName := mapping.Import
+		if fullName == "" && strings.Contains(mapping.Target, ".") {
+			fullName = mapping.Target
+		}
+		if fullName != "" {
+			imports[simpleName(fullName)] = fullName
+		}
+	}
+	return imports
+}
+
+func simpleName(fullName string) string {
+	return fullName[strings.LastIndex(fullName, ".")+1:]
+}
@@ -0,0 +1,150 @@
+package CodeTemplateGenerator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMergeRegions(t *testing.T) {
+	existing := `public class Order {
+    public int total() {
+        // merfolk:begin custom total
+        int sum = 0;
+        for (Item item : items) {
+            sum += item.price;
+        }
+        return sum;
+        // merfolk:end
+    }
+    public void cancel() {
+        // merfolk:begin custom cancel
+        status = "cancelled";
+        // merfolk:end
+    }
+
+    // merfolk:begin custom members
+    private String status;
+    // merfolk:end
+}
+`
+	generated := `public class Order {
+    public int total() {
+        // merfolk:begin custom total
+        return 0;
+        // merfolk:end
+    }
+    public void ship() {
+        // merfolk:begin custom ship
+        // merfolk:end
+    }
+
+    // merfolk:begin custom members
+    // merfolk:end
+}
+`
+	expected := `public class Order {
+    public int total() {
+        // merfolk:begin custom total
+        int sum = 0;
+        for (Item item : items) {
+            sum += item.price;
+        }
+        return sum;
+        // merfolk:end
+    }
+    public void ship() {
+        // merfolk:begin custom ship
+        // merfolk:end
+    }
+
+    // merfolk:begin custom members
+    private String status;
+    // merfolk:end
+
+    // merfolk:begin orphaned custom cancel
+    // status = "cancelled";
+    // merfolk:end
+}
+`
+
+	merged, orphaned, err := MergeRegions([]byte(generated), []byte(existing), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(merged) != expected {
+		t.Errorf("Mismatch!\nExpected:\n%s\nGot:\n%s\n", expected, merged)
+	}
+	if len(orphaned) != 1 || orphaned[0].Name != "custom cancel" || orphaned[0].Line != 12 {
+		t.Errorf("Unexpected orphaned regions %+v", orphaned)
+	}
+
+	// Orphaned regions are kept as they are and not reported again
+	again, orphaned, err := MergeRegions([]byte(generated), merged, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != expected || len(orphaned) != 0 {
+		t.Errorf("Merging twice is not stable:\n%s\norphaned: %+v", again, orphaned)
+	}
+}
+
+func TestMergeRegionsOverloads(t *testing.T) {
+	generated := "class A {\n// merfolk:begin custom f\n// merfolk:end\n// merfolk:begin custom f\n// merfolk:end\n}"
+	existing := "class A {\n// merfolk:begin custom f\none();\n// merfolk:end\n// merfolk:begin custom f\ntwo();\n// merfolk:end\n}"
+
+	merged, orphaned, err := MergeRegions([]byte(generated), []byte(existing), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(merged) != existing || len(orphaned) != 0 {
+		t.Errorf("Unexpected merge result:\n%s\norphaned: %+v", merged, orphaned)
+	}
+}
+
+// TestMergeRegionsBase checks that only the regions that differ from the merge base are kept.
+func TestMergeRegionsBase(t *testing.T) {
+	base := "class A {\n// merfolk:begin custom f\nreturn 0;\n// merfolk:end\n// merfolk:begin custom g\nreturn 0;\n// merfolk:end\n// merfolk:begin custom h\n// merfolk:end\n}"
+	existing := "class A {\n// merfolk:begin custom f\nreturn 0;\n// merfolk:end\n// merfolk:begin custom g\nreturn 1;\n// merfolk:end\n// merfolk:begin custom h\n// merfolk:end\n}"
+	generated := "class A {\n// merfolk:begin custom f\nreturn 2;\n// merfolk:end\n// merfolk:begin custom g\nreturn 2;\n// merfolk:end\n}"
+	expected := "class A {\n// merfolk:begin custom f\nreturn 2;\n// merfolk:end\n// merfolk:begin custom g\nreturn 1;\n// merfolk:end\n}"
+
+	merged, orphaned, err := MergeRegions([]byte(generated), []byte(existing), []byte(base))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(merged) != expected || len(orphaned) != 0 {
+		t.Errorf("Unexpected merge result:\n%s\norphaned: %+v", merged, orphaned)
+	}
+}
+
+// TestMergeRegionsWithoutBase checks that blank regions are not orphaned without merge base.
+func TestMergeRegionsWithoutBase(t *testing.T) {
+	existing := "class A {\n// merfolk:begin custom f\n\n// merfolk:end\n// merfolk:begin custom g\ng();\n// merfolk:end\n}"
+	generated := "class A {\n}"
+	expected := "class A {\n\n    // merfolk:begin orphaned custom g\n    // g();\n    // merfolk:end\n}"
+
+	merged, orphaned, err := MergeRegions([]byte(generated), []byte(existing), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(merged) != expected || len(orphaned) != 1 || orphaned[0].Name != "custom g" {
+		t.Errorf("Unexpected merge result:\n%s\norphaned: %+v", merged, orphaned)
+	}
+}
+
+func TestParseRegionsErrors(t *testing.T) {
+	tests := map[string]string{
+		"not closed":     "// merfolk:begin custom f\ncode();",
+		"nested":         "// merfolk:begin custom f\n// merfolk:begin custom g\n// merfolk:end",
+		"end of nothing": "code();\n// merfolk:end",
+	}
+	for name, code := range tests {
+		if _, err := ParseRegions([]byte(code)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	if _, _, err := MergeRegions([]byte("class A {}"), []byte("// merfolk:begin custom f"), nil); err == nil || !strings.Contains(err.Error(), "not closed") {
+		t.Errorf("expected the error of the existing file, got %v", err)
+	}
+}
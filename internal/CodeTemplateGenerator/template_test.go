@@ -2,6 +2,7 @@ package CodeTemplateGenerator
 
 import (
 	"bytes"
+	"os"
 	"regexp"
 	"strings"
 	"testing"
@@ -128,7 +129,7 @@ public interface {{.InterfaceName}} {{- if .Inherits}} extends {{ range $index,
 
 func renderTemplate(tmplStr string, data any) (string, error) {
 
-	templateStruct, err := template.New("test").Funcs(TemplateGeneratorUtility()).Parse(tmplStr)
+	templateStruct, err := template.New("test").Funcs(TemplateGeneratorUtility(DefaultOptions())).Parse(tmplStr)
 	//templateStruct, err := template.New("test").Funcs(TemplateGeneratorUtility()).ParseFiles(tmplStr)
 	if err != nil {
 		return "", err
@@ -1203,3 +1204,89 @@ func TestIfStatement(t *testing.T) {
 		t.Errorf("Mismatch!\nExpected:\n%s\nGot:\n%s\n", normalizeCode(expected), normalizeCode(output))
 	}
 }
+
+func TestPackageAndImports(t *testing.T) {
+	class := Class{
+		Package:   "com.acme.orders",
+		Imports:   []string{"com.acme.crm.Customer"},
+		ClassName: "Order",
+		Methods: []Method{
+			{AccessModifier: "public", Name: "cancel", ReturnType: "void",
+				Parameters: []Attribute{{Name: "customer", Type: "Customer"}}},
+		},
+	}
+
+	outputDir := t.TempDir() + "/"
+	if err := GenerateJavaCode(class, outputDir, class.ClassName, "ClassTemplate.tmpl", DefaultOptions()); err != nil {
+		t.Fatalf("Error generating class: %v", err)
+	}
+	output, err := os.ReadFile(outputDir + "Order.java")
+	if err != nil {
+		t.Fatalf("Error reading generated class: %v", err)
+	}
+
+	expected := `package com.acme.orders;
+
+import com.acme.crm.Customer;
+
+// merfolk:begin custom imports
+// merfolk:end
+
+public class Order {`
+	if !strings.HasPrefix(string(output), expected) {
+		t.Errorf("Expected output to start with:\n%s\nGot:\n%s", expected, output)
+	}
+
+	iface := Interface{Package: "com.acme.orders", InterfaceName: "Order"}
+	if err := GenerateJavaCode(iface, outputDir, iface.InterfaceName, "InterfaceTemplate.tmpl", DefaultOptions()); err != nil {
+		t.Fatalf("Error generating interface: %v", err)
+	}
+	output, err = os.ReadFile(outputDir + "IOrder.java")
+	if err != nil {
+		t.Fatalf("Error reading generated interface: %v", err)
+	}
+	if !strings.HasPrefix(string(output), "package com.acme.orders;\n\npublic interface IOrder {") {
+		t.Errorf("Unexpected interface header:\n%s", output)
+	}
+}
+
+func TestTypeMappingDefaults(t *testing.T) {
+	types := NewTypeMap(map[string]TypeMapping{
+		"Money": {Target: "java.math.BigDecimal", Default: "BigDecimal.ZERO", Value: true},
+	})
+
+	class := Class{
+		ClassName: "Account",
+		Attributes: []Attribute{
+			{AccessModifier: "private", Name: "count", Type: "long"},
+			{AccessModifier: "private", Name: "initial", Type: "char"},
+			{AccessModifier: "private", Name: "rate", Type: "float"},
+			{AccessModifier: "private", Name: "balance", Type: "BigDecimal"},
+		},
+	}
+
+	tmpl := `{{- range .Attributes }}{{ .Name }}={{ defaultZero .Type }} {{ end }}`
+	templateStruct, err := template.New("test").Funcs(TemplateGeneratorUtility(Options{Types: types})).Parse(tmpl)
+	if err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := templateStruct.Execute(&buf, class); err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+
+	expected := `count=0L initial='\u0000' rate=0.0f balance=BigDecimal.ZERO`
+	if normalizeCode(buf.String()) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, normalizeCode(buf.String()))
+	}
+
+	if types.JavaType("Money") != "BigDecimal" || !types.IsValueType("Money") {
+		t.Errorf("Expected Money to map to the value type BigDecimal")
+	}
+	if types.Imports()["BigDecimal"] != "java.math.BigDecimal" {
+		t.Errorf("Expected an import for java.math.BigDecimal, got %v", types.Imports())
+	}
+	if types.BoxedType("int") != "Integer" || types.DefaultValue("Order") != "null" {
+		t.Errorf("Unexpected built-in mappings")
+	}
+}
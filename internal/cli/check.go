@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+func init() {
+	register(&Command{
+		Name:    "check",
+		Args:    "[<input dir>]",
+		Summary: "validate the diagrams without writing any files",
+		Description: `
+Check parses all diagrams below the input directory, transforms them and fills the templates in
+memory. It reports every error and exits with a non-zero code if there was one, which makes it
+suitable for continuous integration.`,
+		Flags: func(flags *flag.FlagSet) any { return addProjectFlags(flags) },
+		Run:   runCheck,
+	})
+}
+
+func runCheck(env *Env, flags *flag.FlagSet, value any, args []string) error {
+	if len(args) > 1 {
+		return usagef("too many arguments")
+	}
+
+	cfg, err := value.(*projectFlags).load(flags, args)
+	if err != nil {
+		return fmt.Errorf("error reading configuration: %w", err)
+	}
+	if cfg.Input == "" {
+		return usagef("specify the input directory")
+	}
+
+	// Progress messages and the log of the connector are not of interest, only the diagnostics
+	quiet := &Env{Stdout: io.Discard, Stderr: env.Stderr}
+	defer discardConnectorLog()()
+	p, err := buildProject(quiet, cfg, nil)
+	if err != nil {
+		return err
+	}
+	files := renderProject(quiet, cfg, p)
+	env.errors += quiet.errors
+
+	env.Printf("Checked %d diagrams: %d classes, %d interfaces, %d files\n",
+		p.Diagrams, len(p.Model.Classes), len(p.Model.Interfaces), len(files))
+	return nil
+}
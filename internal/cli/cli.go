@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Exit codes of Run.
+const (
+	// ExitOK reports success.
+	ExitOK = 0
+	// ExitFailure reports that the command ran but found errors, e.g. diagrams that do not parse.
+	ExitFailure = 1
+	// ExitUsage reports an unknown command or invalid flags and arguments.
+	ExitUsage = 2
+)
+
+// Command is a subcommand of merfolk.
+type Command struct {
+	// Name is the word that selects the command.
+	Name string
+	// Args describes the positional arguments in the usage line.
+	Args string
+	// Summary is a one-line description shown in the command list.
+	Summary string
+	// Description is shown in the help of the command, below the usage line.
+	Description string
+	// Flags registers the flags of the command. It returns a value that is passed to Run.
+	Flags func(flags *flag.FlagSet) any
+	// Run executes the command with the parsed flags and the positional arguments.
+	Run func(env *Env, flags *flag.FlagSet, value any, args []string) error
+}
+
+var commands = map[string]*Command{}
+
+// register adds a command to the command table, commands register themselves in init functions.
+func register(cmd *Command) {
+	if _, exists := commands[cmd.Name]; exists {
+		panic("command registered twice: " + cmd.Name)
+	}
+	commands[cmd.Name] = cmd
+}
+
+// commandList returns all commands ordered by name.
+func commandList() []*Command {
+	list := make([]*Command, 0, len(commands))
+	for _, cmd := range commands {
+		list = append(list, cmd)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Env is the environment a command runs in. Commands write their output to Stdout and
+// report errors with Errorf, which writes to Stderr and marks the run as failed.
+type Env struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	errors int
+}
+
+// Printf writes to the standard output.
+func (env *Env) Printf(format string, a ...any) {
+	fmt.Fprintf(env.Stdout, format, a...)
+}
+
+// Println writes to the standard output.
+func (env *Env) Println(a ...any) {
+	fmt.Fprintln(env.Stdout, a...)
+}
+
+// Errorf reports an error on the standard error output. The command continues, but Run
+// exits with ExitFailure.
+func (env *Env) Errorf(format string, a ...any) {
+	env.errors++
+	fmt.Fprintf(env.Stderr, format, a...)
+	if !strings.HasSuffix(format, "\n") {
+		fmt.Fprintln(env.Stderr)
+	}
+}
+
+// Warnf reports a warning on the standard error output. Unlike Errorf it does not change the exit code.
+func (env *Env) Warnf(format string, a ...any) {
+	fmt.Fprintf(env.Stderr, "Warning: "+format, a...)
+	if !strings.HasSuffix(format, "\n") {
+		fmt.Fprintln(env.Stderr)
+	}
+}
+
+// Failed reports whether errors were reported with Errorf.
+func (env *Env) Failed() bool {
+	return env.errors > 0
+}
+
+// failure returns an error that summarizes the errors reported with Errorf, nil if there were none.
+func (env *Env) failure() error {
+	if env.errors == 0 {
+		return nil
+	}
+	if env.errors == 1 {
+		return errReported{"1 error"}
+	}
+	return errReported{fmt.Sprintf("%d errors", env.errors)}
+}
+
+// errReported is returned by commands whose errors were already reported with Errorf.
+type errReported struct {
+	summary string
+}
+
+func (e errReported) Error() string {
+	return e.summary
+}
+
+// usageError reports invalid arguments of a command. Run prints the usage of the command and exits with ExitUsage.
+type usageError struct {
+	msg string
+}
+
+func (e usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, a ...any) error {
+	return usageError{fmt.Sprintf(format, a...)}
+}
+
+// Run executes the command given by args, without the program name, and returns the exit code.
+func Run(args []string, stdout, stderr io.Writer) int {
+	env := &Env{Stdout: stdout, Stderr: stderr}
+
+	if len(args) == 0 {
+		printCommands(stderr)
+		return ExitUsage
+	}
+
+	switch args[0] {
+	case "-h", "-help", "--help":
+		printCommands(stdout)
+		return ExitOK
+	case "-version", "--version":
+		args = []string{"version"}
+	}
+
+	cmd, exists := commands[args[0]]
+	if !exists {
+		fmt.Fprintf(stderr, "merfolk: unknown command %q\n", args[0])
+		fmt.Fprintln(stderr, "Run 'merfolk help' for a list of commands.")
+		return ExitUsage
+	}
+
+	return runCommand(env, cmd, args[1:])
+}
+
+func runCommand(env *Env, cmd *Command, args []string) int {
+	flags := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	flags.SetOutput(env.Stderr)
+	flags.Usage = func() {
+		printUsage(flags.Output(), cmd, flags)
+	}
+
+	var value any
+	if cmd.Flags != nil {
+		value = cmd.Flags(flags)
+	}
+
+	// The flag package prints the usage on every parse error, including -help, which is
+	// printed to stdout instead
+	usage := flags.Usage
+	flags.Usage = func() {}
+	args, err := parseInterspersed(flags, args)
+	flags.Usage = usage
+	if errors.Is(err, flag.ErrHelp) {
+		flags.SetOutput(env.Stdout)
+		flags.Usage()
+		return ExitOK
+	}
+	if err != nil {
+		// The flag package already printed the error
+		flags.Usage()
+		return ExitUsage
+	}
+
+	err = cmd.Run(env, flags, value, args)
+	if err == nil {
+		err = env.failure()
+	}
+
+	var usageErr usageError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(env.Stderr, "merfolk %s: %s\n", cmd.Name, usageErr.msg)
+		flags.Usage()
+		return ExitUsage
+	default:
+		fmt.Fprintf(env.Stderr, "merfolk %s: %s\n", cmd.Name, err)
+		return ExitFailure
+	}
+}
+
+func printUsage(w io.Writer, cmd *Command, flags *flag.FlagSet) {
+	usage := "merfolk " + cmd.Name
+	hasFlags := false
+	flags.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		usage += " [flags]"
+	}
+	if cmd.Args != "" {
+		usage += " " + cmd.Args
+	}
+
+	fmt.Fprintf(w, "Usage: %s\n\n", usage)
+	if cmd.Description != "" {
+		fmt.Fprintf(w, "%s\n\n", strings.TrimSpace(cmd.Description))
+	} else {
+		fmt.Fprintf(w, "%s.\n\n", cmd.Summary)
+	}
+	if hasFlags {
+		fmt.Fprintln(w, "Flags:")
+		flags.PrintDefaults()
+	}
+}
+
+func printCommands(w io.Writer) {
+	fmt.Fprintln(w, "Merfolk generates Java code from Mermaid class and sequence diagrams.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Usage: merfolk <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commandList() {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.Name, cmd.Summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'merfolk help <command>' for the flags of a command.")
+}
@@ -0,0 +1,790 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/config"
+	"github.com/MarmaidTranspiler/Merfolk/internal/connector"
+	"github.com/MarmaidTranspiler/Merfolk/internal/server"
+)
+
+func run(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := Run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunUsage(t *testing.T) {
+	if code, _, stderr := run(t); code != ExitUsage || !strings.Contains(stderr, "Commands:") {
+		t.Errorf("no arguments: exit code %d, stderr %q", code, stderr)
+	}
+	if code, _, stderr := run(t, "frobnicate"); code != ExitUsage || !strings.Contains(stderr, "unknown command") {
+		t.Errorf("unknown command: exit code %d, stderr %q", code, stderr)
+	}
+	if code, stdout, stderr := run(t, "convert", "--help"); code != ExitOK || !strings.Contains(stdout, "Usage: merfolk convert") || stderr != "" {
+		t.Errorf("convert --help: exit code %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+	if code, stdout, _ := run(t, "help", "convert"); code != ExitOK || !strings.Contains(stdout, "-base-package") {
+		t.Errorf("help convert: exit code %d, stdout %q", code, stdout)
+	}
+	if code, _, stderr := run(t, "convert", "--no-such-flag"); code != ExitUsage || strings.Count(stderr, "Usage: merfolk convert") != 1 {
+		t.Errorf("unknown flag: exit code %d, stderr %q", code, stderr)
+	}
+	if code, stdout, _ := run(t, "version"); code != ExitOK || !strings.HasPrefix(stdout, "merfolk ") {
+		t.Errorf("version: exit code %d, stdout %q", code, stdout)
+	}
+}
+
+func TestConvertExitCode(t *testing.T) {
+	input := t.TempDir()
+	output := t.TempDir()
+	files := map[string]string{
+		"good.md": "```mermaid\nclassDiagram\nclass Order\nOrder : +int id\n```\n",
+		"bad.md":  "```mermaid\nclassDiagram\nclass\n```\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(input, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	code, _, stderr := run(t, "convert", input, output)
+	if code != ExitFailure {
+		t.Errorf("expected exit code %d, got %d", ExitFailure, code)
+	}
+	if !strings.Contains(stderr, "bad.md") {
+		t.Errorf("error of bad.md not reported: %q", stderr)
+	}
+	if _, err := os.Stat(filepath.Join(output, "Order.java")); err != nil {
+		t.Errorf("valid file was not converted: %v", err)
+	}
+
+	if err := os.Remove(filepath.Join(input, "bad.md")); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, stderr := run(t, "convert", input, output); code != ExitOK {
+		t.Errorf("expected exit code %d, got %d: %s", ExitOK, code, stderr)
+	}
+}
+
+func TestInit(t *testing.T) {
+	dir := t.TempDir()
+	if code, _, stderr := run(t, "init", dir); code != ExitOK {
+		t.Fatalf("init failed with exit code %d: %s", code, stderr)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "merfolk.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, _ := run(t, "init", dir); code != ExitFailure {
+		t.Errorf("init over an existing configuration: exit code %d", code)
+	}
+	if code, _, _ := run(t, "init", "--force", dir); code != ExitOK {
+		t.Errorf("init --force: exit code %d", code)
+	}
+}
+
+func TestCheckWritesNothing(t *testing.T) {
+	input := t.TempDir()
+	output := filepath.Join(t.TempDir(), "out")
+	content := "```mermaid\nclassDiagram\nclass Order\nOrder : +int id\n```\n"
+	if err := os.WriteFile(filepath.Join(input, "model.md"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var log bytes.Buffer
+	connector.Log = &log
+	defer func() { connector.Log = os.Stdout }()
+	code, stdout, stderr := run(t, "check", input)
+	if code != ExitOK {
+		t.Fatalf("check failed with exit code %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "1 classes") {
+		t.Errorf("unexpected summary: %q", stdout)
+	}
+	if log.Len() > 0 {
+		t.Errorf("check wrote the log of the connector: %q", log.String())
+	}
+
+	if code, _, stderr := run(t, "convert", "--dry-run", input, output); code != ExitOK {
+		t.Fatalf("dry run failed with exit code %d: %s", code, stderr)
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("dry run created the output directory")
+	}
+
+	bad := "```mermaid\nsequenceDiagram\nA->>\n```\n"
+	if err := os.WriteFile(filepath.Join(input, "flow.md"), []byte(bad), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, stderr := run(t, "check", input); code != ExitFailure || !strings.Contains(stderr, "flow.md") {
+		t.Errorf("expected failure for flow.md, got exit code %d: %s", code, stderr)
+	}
+}
+
+func TestConvertKeepsRegions(t *testing.T) {
+	input := t.TempDir()
+	output := t.TempDir()
+	model := filepath.Join(input, "model.md")
+	content := "```mermaid\nclassDiagram\nclass Order\nOrder : +total() int\nOrder : +cancel() void\n```\n"
+	if err := os.WriteFile(model, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, stderr := run(t, "convert", input, output); code != ExitOK {
+		t.Fatalf("convert failed with exit code %d: %s", code, stderr)
+	}
+
+	// Fill in the bodies of total and cancel
+	file := filepath.Join(output, "Order.java")
+	generated, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := strings.NewReplacer(
+		"// merfolk:begin custom total\n", "// merfolk:begin custom total\n        totalResult = items.size();\n",
+		"// merfolk:begin custom cancel\n", "// merfolk:begin custom cancel\n        items.clear();\n",
+	).Replace(string(generated))
+	if strings.Count(edited, "\n") != strings.Count(string(generated), "\n")+2 {
+		t.Fatalf("no regions for total and cancel in\n%s", generated)
+	}
+	if err := os.WriteFile(file, []byte(edited), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Remove cancel from the diagram
+	content = strings.Replace(content, "Order : +cancel() void\n", "Order : +ship() void\n", 1)
+	if err := os.WriteFile(model, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	code, _, stderr := run(t, "convert", input, output)
+	if code != ExitOK {
+		t.Fatalf("convert failed with exit code %d: %s", code, stderr)
+	}
+	if !strings.Contains(stderr, `region "custom cancel"`) {
+		t.Errorf("expected a warning about the orphaned region, got %q", stderr)
+	}
+
+	regenerated, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"totalResult = items.size();\n        // merfolk:end\n        return totalResult;", "public void ship()", "// merfolk:begin orphaned custom cancel"} {
+		if !strings.Contains(string(regenerated), want) {
+			t.Errorf("expected %q in\n%s", want, regenerated)
+		}
+	}
+}
+
+func TestConvertMergesEdits(t *testing.T) {
+	input := t.TempDir()
+	output := t.TempDir()
+	model := filepath.Join(input, "model.md")
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		t.Helper()
+		content, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+	convert := func(args ...string) (int, string) {
+		t.Helper()
+		code, _, stderr := run(t, append(append([]string{"convert"}, args...), input, output)...)
+		return code, stderr
+	}
+
+	write(model, "```mermaid\nclassDiagram\nclass Order\nOrder : -int id\n```\n")
+	if code, stderr := convert(); code != ExitOK {
+		t.Fatalf("convert failed with exit code %d: %s", code, stderr)
+	}
+	if _, err := os.Stat(filepath.Join(output, ".merfolk", "base", "Order.java")); err != nil {
+		t.Fatalf("merge base not written: %v", err)
+	}
+
+	// An edit outside of the protected regions is merged with a new attribute
+	file := filepath.Join(output, "Order.java")
+	write(file, strings.Replace(read(file), "public class Order {", "@Entity\npublic class Order {", 1))
+	write(model, "```mermaid\nclassDiagram\nclass Order\nOrder : -int id\nOrder : -String note\n```\n")
+	if code, stderr := convert(); code != ExitOK {
+		t.Fatalf("convert failed with exit code %d: %s", code, stderr)
+	}
+	if merged := read(file); !strings.Contains(merged, "@Entity\npublic class Order {") || !strings.Contains(merged, "private String note;") {
+		t.Errorf("edit and new attribute not merged:\n%s", merged)
+	}
+
+	// Changing the same line as the generator is a conflict
+	write(file, strings.Replace(read(file), "private String note;", "private String note = \"\";", 1))
+	write(model, "```mermaid\nclassDiagram\nclass Order\nOrder : -int id\nOrder : -int note\n```\n")
+	code, stderr := convert()
+	if code != ExitFailure || !strings.Contains(stderr, "conflict") {
+		t.Errorf("expected a conflict, got exit code %d: %s", code, stderr)
+	}
+	if conflicted := read(file); !strings.Contains(conflicted, "<<<<<<< current") || !strings.Contains(conflicted, "private int note;") {
+		t.Errorf("expected conflict markers:\n%s", conflicted)
+	}
+
+	// Unresolved conflicts stop the regeneration until --force is given
+	if code, stderr := convert(); code != ExitFailure || !strings.Contains(stderr, "unresolved conflict markers") {
+		t.Errorf("expected unresolved conflict markers, got exit code %d: %s", code, stderr)
+	}
+	if code, stderr := convert("--force"); code != ExitOK {
+		t.Fatalf("convert --force failed with exit code %d: %s", code, stderr)
+	}
+	if forced := read(file); strings.Contains(forced, "<<<<<<<") || strings.Contains(forced, "@Entity") {
+		t.Errorf("expected the generated code:\n%s", forced)
+	}
+}
+
+func TestConvertManifest(t *testing.T) {
+	input := t.TempDir()
+	output := t.TempDir()
+	model := filepath.Join(input, "model.md")
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	convert := func(args ...string) (string, string) {
+		t.Helper()
+		code, stdout, stderr := run(t, append(append([]string{"convert"}, args...), input, output)...)
+		if code != ExitOK {
+			t.Fatalf("convert failed with exit code %d: %s", code, stderr)
+		}
+		return stdout, stderr
+	}
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(output, name))
+		return err == nil
+	}
+
+	write(model, "```mermaid\nclassDiagram\nclass Order\nOrder : -int id\nclass Item\nItem : -int id\nclass Note\nNote : -int id\n```\n")
+	write(filepath.Join(output, "Handwritten.java"), "public class Handwritten {}\n")
+	convert()
+
+	content, err := os.ReadFile(filepath.Join(output, ".merfolk", "manifest.json"))
+	if err != nil {
+		t.Fatalf("manifest not written: %v", err)
+	}
+	var m manifest
+	if err := json.Unmarshal(content, &m); err != nil {
+		t.Fatal(err)
+	}
+	if entry := m.Files["Order.java"]; entry == nil || entry.Hash == "" || entry.Sources["model.md"] == "" {
+		t.Fatalf("Order.java not recorded: %s", content)
+	}
+	if m.Files["Handwritten.java"] != nil {
+		t.Errorf("unmanaged file recorded: %s", content)
+	}
+
+	// Unchanged files are not written again
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	order := filepath.Join(output, "Order.java")
+	if err := os.Chtimes(order, past, past); err != nil {
+		t.Fatal(err)
+	}
+	convert()
+	if info, err := os.Stat(order); err != nil || !info.ModTime().Equal(past) {
+		t.Errorf("unchanged file was written again: %v", err)
+	}
+
+	// Removed classes are reported with --keep-stale and with --dry-run
+	write(model, "```mermaid\nclassDiagram\nclass Order\nOrder : -int id\n```\n")
+	if _, stderr := convert("--keep-stale"); !strings.Contains(stderr, "Item.java is no longer generated") || !exists("Item.java") {
+		t.Errorf("--keep-stale: expected a warning and the file to be kept: %s", stderr)
+	}
+	if stdout, _ := convert("--dry-run"); !strings.Contains(stdout, "Would remove: "+filepath.Join(output, "Item.java")) {
+		t.Errorf("--dry-run: expected the stale file to be listed: %s", stdout)
+	}
+
+	// Stale files are removed, but edited ones are kept and no longer managed
+	write(filepath.Join(output, "Note.java"), "// edited\n")
+	stdout, stderr := convert()
+	if exists("Item.java") || exists("IItem.java") || exists(".merfolk/base/Item.java") || !strings.Contains(stdout, "Removed:") {
+		t.Errorf("stale files not removed: %s", stdout)
+	}
+	if !exists("Note.java") || !strings.Contains(stderr, "Note.java is no longer generated, but it was edited and is kept") {
+		t.Errorf("edited stale file not kept: %s", stderr)
+	}
+	if !exists("Handwritten.java") {
+		t.Error("unmanaged file was removed")
+	}
+	if _, stderr := convert(); strings.Contains(stderr, "Note.java") {
+		t.Errorf("released file reported again: %s", stderr)
+	}
+
+	// Manifests with missing entries or paths outside of the output directory are rejected
+	for _, files := range []string{`{"Foo.java": null}`, `{"../outside.java": {"hash": "x"}}`, `{"/outside.java": {"hash": "x"}}`} {
+		write(filepath.Join(output, ".merfolk", "manifest.json"), `{"version": 1, "files": `+files+`}`)
+		code, _, stderr := run(t, "convert", input, output)
+		if code != ExitFailure || !strings.Contains(stderr, "remove it to start over") {
+			t.Errorf("%s: expected an invalid manifest, got exit code %d: %s", files, code, stderr)
+		}
+	}
+}
+
+func TestFmt(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "model.md")
+	content := "# Model\n\n```mermaid\nclassDiagram\nOrder:+int   id\n```\n"
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if code, stdout, _ := run(t, "fmt", "--check", dir); code != ExitFailure || !strings.Contains(stdout, "model.md") {
+		t.Errorf("fmt --check: exit code %d, stdout %q", code, stdout)
+	}
+	if code, _, stderr := run(t, "fmt", dir); code != ExitOK {
+		t.Fatalf("fmt failed with exit code %d: %s", code, stderr)
+	}
+
+	formatted, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# Model\n\n```mermaid\nclassDiagram\n    Order : +int id\n```\n"; string(formatted) != want {
+		t.Errorf("unexpected file content:\n%s", formatted)
+	}
+
+	if code, stdout, _ := run(t, "fmt", "--check", file); code != ExitOK || stdout != "" {
+		t.Errorf("fmt --check after fmt: exit code %d, stdout %q", code, stdout)
+	}
+}
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "model.md")
+	content := "```mermaid\nclassDiagram\nOrder : +int id\nOrder : +int id\nOrder : +Total() int\n```\n"
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, _ := run(t, "lint", dir)
+	if code != ExitFailure {
+		t.Errorf("expected exit code %d, got %d", ExitFailure, code)
+	}
+	if !strings.Contains(stdout, "model.md:4: error:") || !strings.Contains(stdout, "[method-case]") {
+		t.Errorf("unexpected findings: %q", stdout)
+	}
+
+	if code, _, stderr := run(t, "lint", "--rule", "duplicate-member=warning", dir); code != ExitOK {
+		t.Errorf("expected exit code %d, got %d: %s", ExitOK, code, stderr)
+	}
+	if code, _, _ := run(t, "lint", "--rule", "no-such-rule=off", dir); code != ExitUsage {
+		t.Errorf("unknown rule: exit code %d", code)
+	}
+}
+
+func TestReverse(t *testing.T) {
+	src := t.TempDir()
+	files := map[string]string{
+		"com/shop/Order.java":  "package com.shop;\n\nimport java.util.List;\n\npublic class Order {\n    private List<Item> items;\n\n    public int total() { return 0; }\n}\n",
+		"com/shop/Item.java":   "package com.shop;\n\nclass Item {\n    private String name;\n}\n",
+		"com/shop/Broken.java": "package com.shop;\n\nclass Broken {\n",
+		"test/OrderTest.java":  "class OrderTest {}\n",
+		".hidden/Ignored.java": "class Ignored {}\n",
+		"com/shop/package.md":  "not Java\n",
+	}
+	for name, content := range files {
+		file := filepath.Join(src, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if code, _, stderr := run(t, "reverse", src); code != ExitUsage || !strings.Contains(stderr, "--lang") {
+		t.Errorf("missing --lang: exit code %d, stderr %q", code, stderr)
+	}
+	if code, _, stderr := run(t, "reverse", "--lang", "cobol", src); code != ExitUsage || !strings.Contains(stderr, "unsupported language") {
+		t.Errorf("unknown language: exit code %d, stderr %q", code, stderr)
+	}
+
+	// A file with errors fails the run, the other files are still reverse engineered
+	code, stdout, stderr := run(t, "reverse", "--lang", "java", "--exclude", "test", src)
+	if code != ExitFailure || !strings.Contains(stderr, "Broken.java:4: expected \"}\", found end of file") {
+		t.Errorf("expected the error of Broken.java, got exit code %d: %s", code, stderr)
+	}
+	if !strings.HasPrefix(stdout, "```mermaid\nclassDiagram\n") || !strings.Contains(stdout, `Order "1" --> "*" Item : items`) {
+		t.Errorf("unexpected diagram:\n%s", stdout)
+	}
+	for _, unwanted := range []string{"Broken", "OrderTest", "Ignored"} {
+		if strings.Contains(stdout, unwanted) {
+			t.Errorf("unexpected %s in\n%s", unwanted, stdout)
+		}
+	}
+
+	// The diagram converts to Java again
+	if err := os.Remove(filepath.Join(src, "com", "shop", "Broken.java")); err != nil {
+		t.Fatal(err)
+	}
+	input := t.TempDir()
+	output := t.TempDir()
+	if code, _, stderr := run(t, "reverse", "--lang", "java", "--output", filepath.Join(input, "model.mmd"), filepath.Join(src, "com")); code != ExitOK {
+		t.Fatalf("reverse failed with exit code %d: %s", code, stderr)
+	}
+	if code, _, stderr := run(t, "convert", input, output); code != ExitOK {
+		t.Fatalf("convert failed with exit code %d: %s", code, stderr)
+	}
+	order, err := os.ReadFile(filepath.Join(output, "com", "shop", "Order.java"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"package com.shop;", "private List<Item> items", "public int total()"} {
+		if !strings.Contains(string(order), want) {
+			t.Errorf("expected %q in\n%s", want, order)
+		}
+	}
+
+	// Sequence diagrams of a method
+	code, stdout, stderr = run(t, "reverse", "--lang", "java", "--entry", "Order.total", "--actor", "shop", src)
+	if code != ExitOK || stdout != "```mermaid\nsequenceDiagram\n    actor shop\n    shop->>Order: total()\n    Order-->>shop: totalResult\n```\n" {
+		t.Errorf("unexpected sequence diagram, exit code %d:\n%s%s", code, stdout, stderr)
+	}
+	if code, _, stderr := run(t, "reverse", "--lang", "java", "--entry", "Order.cancel", src); code != ExitFailure || !strings.Contains(stderr, "method Order.cancel not found") {
+		t.Errorf("unknown entry: exit code %d, stderr %q", code, stderr)
+	}
+	if code, _, stderr := run(t, "reverse", "--lang", "go", "--entry", "Order.total", src); code != ExitUsage || !strings.Contains(stderr, "not supported for go") {
+		t.Errorf("entry for Go: exit code %d, stderr %q", code, stderr)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	input := t.TempDir()
+	output := t.TempDir()
+	source := filepath.Join(input, "model.md")
+	writeSource := func(attribute string) {
+		content := "```mermaid\nclassDiagram\nclass Order\nOrder : +int " + attribute + "\n```\n"
+		if err := os.WriteFile(source, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeSource("id")
+
+	cfg := config.Default()
+	cfg.Input, cfg.Output = input, output
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	var stdout, stderr bytes.Buffer
+	go func() {
+		done <- watch(ctx, &Env{Stdout: &stdout, Stderr: &stderr}, cfg, 10*time.Millisecond)
+	}()
+
+	target := filepath.Join(output, "Order.java")
+	waitFor := func(text string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if content, err := os.ReadFile(target); err == nil && strings.Contains(string(content), text) {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("%s does not contain %q", target, text)
+	}
+
+	waitFor("int id")
+	writeSource("number")
+	waitFor("int number")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var stdout bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		done <- serve(ctx, &Env{Stdout: &stdout, Stderr: io.Discard}, listener, server.New(config.Default(), server.Limits{}, "test"))
+	}()
+
+	response, err := http.Get("http://" + listener.Addr().String() + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, response.StatusCode)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRoundtrip(t *testing.T) {
+	input := t.TempDir()
+	content := "```mermaid\nclassDiagram\nclass Order {\n    +total() int\n    +cancel() void\n}\nclass Item {\n    +price() int\n}\n```\n\n" +
+		"```mermaid\nsequenceDiagram\nactor user\nuser->>Order: total()\nOrder->>Item: price()\nItem-->>Order: sum\nOrder-->>user: sum\n```\n"
+	if err := os.WriteFile(filepath.Join(input, "model.md"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := run(t, "roundtrip", input)
+	if code != ExitFailure {
+		t.Fatalf("expected differences, got exit code %d: %s%s", code, stdout, stderr)
+	}
+	// The connector creates the callee before it calls it
+	expected := "added call Order ->> Item: Item in Order.total\nRound trip of 2 diagrams: 1 differences\n"
+	if stdout != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, stdout)
+	}
+
+	lossless := "```mermaid\nclassDiagram\nclass Order {\n    +total() int\n    +add(Item item) void\n}\nclass Item\n```\n"
+	if err := os.WriteFile(filepath.Join(input, "model.md"), []byte(lossless), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	code, stdout, stderr = run(t, "roundtrip", "--getters=false", "--setters=false", input)
+	if code != ExitOK || !strings.Contains(stdout, "0 differences") {
+		t.Errorf("expected no differences, got exit code %d: %s%s", code, stdout, stderr)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "old.md")
+	new := filepath.Join(dir, "new.mmd")
+	files := map[string]string{
+		old: "```mermaid\nclassDiagram\nclass Order {\n    +total() int\n}\nDataService : +fetch(String token) Data\n```\n\n" +
+			"```mermaid\nsequenceDiagram\nactor user\nuser->>Application: login()\nApplication->>DataService: fetch(token)\nDataService->>AuthService: verify(token)\n```\n",
+		new: "classDiagram\nOrder : +total() int\nOrder : +cancel() void\nDataService : +fetch(String token) Optional~Data~\n",
+	}
+	for file, content := range files {
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	code, stdout, stderr := run(t, "diff", old, new)
+	expected := "changed DataService.fetch return type from Data to Optional~Data~\nadded method Order.cancel()\nremoved sequence Application.login\n"
+	if code != ExitOK || stdout != expected {
+		t.Errorf("expected\n%s\ngot exit code %d:\n%s%s", expected, code, stdout, stderr)
+	}
+
+	code, stdout, _ = run(t, "diff", "--format", "markdown", old, new)
+	if code != ExitOK || !strings.HasPrefix(stdout, "- changed `DataService.fetch` return type from `Data` to `Optional~Data~`\n- added method `Order.cancel()`\n") {
+		t.Errorf("unexpected Markdown report, exit code %d:\n%s", code, stdout)
+	}
+	if _, stdout, _ := run(t, "diff", "--format", "markdown", old, old); stdout != "No changes.\n" {
+		t.Errorf("unexpected Markdown report without changes: %q", stdout)
+	}
+
+	code, stdout, _ = run(t, "diff", "--format", "json", old, new)
+	var report struct {
+		Changes []map[string]string `json:"changes"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &report); err != nil || code != ExitOK {
+		t.Fatalf("invalid JSON report, exit code %d: %v\n%s", code, err, stdout)
+	}
+	if len(report.Changes) != 3 || report.Changes[0]["kind"] != "changed" || report.Changes[0]["property"] != "return type" || report.Changes[1]["subject"] != "Order.cancel()" {
+		t.Errorf("unexpected changes %v", report.Changes)
+	}
+
+	if code, _, stderr := run(t, "diff", "--format", "html", old, new); code != ExitUsage || !strings.Contains(stderr, "unknown format") {
+		t.Errorf("unknown format: exit code %d, stderr %q", code, stderr)
+	}
+	if code, _, _ := run(t, "diff", old); code != ExitUsage {
+		t.Errorf("single file: exit code %d", code)
+	}
+}
+
+func TestDiffRevision(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	gitRun := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	docs := filepath.Join(dir, "docs")
+	if err := os.MkdirAll(docs, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	model := filepath.Join(docs, "model.md")
+	// The skipped diagram and the excluded file are left out of both versions
+	skipped := "```mermaid\n---\nmerfolk:\n  skip: true\n---\nclassDiagram\nSketch : +draw() void\n```\n"
+	files := map[string]string{
+		model:                                    "```mermaid\nclassDiagram\nOrder : +total() int\n```\n" + skipped,
+		filepath.Join(docs, "drafts", "idea.md"): "```mermaid\nclassDiagram\nclass Draft\n```\n",
+		filepath.Join(docs, "merfolk.yaml"):      "exclude:\n  - drafts/**\n",
+	}
+	for file, content := range files {
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gitRun("init", "-q")
+	gitRun("add", ".")
+	gitRun("commit", "-q", "-m", "model")
+	if err := os.WriteFile(model, []byte("```mermaid\nclassDiagram\nOrder : +total() long\n```\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(docs); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	code, stdout, stderr := run(t, "diff", "--rev", "HEAD", ".")
+	if code != ExitOK || stdout != "changed Order.total return type from int to long\n" {
+		t.Errorf("unexpected report, exit code %d:\n%s%s", code, stdout, stderr)
+	}
+	if code, _, stderr := run(t, "diff", "--rev", "nonexistent", "."); code != ExitFailure || !strings.Contains(stderr, "git ls-tree") {
+		t.Errorf("unknown revision: exit code %d, stderr %q", code, stderr)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	input := t.TempDir()
+	dir := t.TempDir()
+	content := "```mermaid\nclassDiagram\nnamespace com.shop {\n    class Order {\n        -List~Item~ items\n        +total() int\n    }\n}\nclass Item {\n    +price() int\n}\n```\n\n" +
+		"```mermaid\nsequenceDiagram\nactor user\nuser->>Order: total()\nOrder->>Item: price()\nItem-->>Order: sum\nOrder-->>user: sum\n```\n"
+	if err := os.WriteFile(filepath.Join(input, "model.md"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	converted := filepath.Join(dir, "converted")
+	if code, _, stderr := run(t, "convert", input, converted); code != ExitOK {
+		t.Fatalf("convert failed with exit code %d: %s", code, stderr)
+	}
+
+	for _, format := range []string{"json", "yaml"} {
+		model := filepath.Join(dir, "model."+format)
+		code, stdout, stderr := run(t, "export", "--output", model, input)
+		if code != ExitOK || stdout != "" {
+			t.Fatalf("export failed with exit code %d: %s%s", code, stdout, stderr)
+		}
+		imported := filepath.Join(dir, format)
+		if code, _, stderr := run(t, "import", model, imported); code != ExitOK {
+			t.Fatalf("import failed with exit code %d: %s", code, stderr)
+		}
+		for _, file := range []string{"com/shop/Order.java", "Item.java"} {
+			expected, err := os.ReadFile(filepath.Join(converted, filepath.FromSlash(file)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual, err := os.ReadFile(filepath.Join(imported, filepath.FromSlash(file)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(actual) != string(expected) {
+				t.Errorf("%s: imported %s differs from the converted one\n%s\nexpected\n%s", format, file, actual, expected)
+			}
+		}
+	}
+
+	code, stdout, _ := run(t, "export", "--format", "yaml", input)
+	if code != ExitOK || !strings.HasPrefix(stdout, "version: 1\nclasses:\n  - package: com.shop\n") {
+		t.Errorf("unexpected YAML export, exit code %d:\n%s", code, stdout)
+	}
+	if code, stdout, _ := run(t, "export", "--schema"); code != ExitOK || !json.Valid([]byte(stdout)) {
+		t.Errorf("expected the JSON Schema, got exit code %d:\n%s", code, stdout)
+	}
+
+	// Code is generated without any diagram
+	model := filepath.Join(dir, "handwritten.json")
+	handwritten := `{"version": 1, "classes": [{"name": "Invoice", "attributes": [{"access": "private", "name": "dueDate", "type": "LocalDate"}]}], "interfaces": []}`
+	if err := os.WriteFile(model, []byte(handwritten), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "handwritten")
+	if code, _, stderr := run(t, "import", "--base-package", "com.billing", model, output); code != ExitOK {
+		t.Fatalf("import failed with exit code %d: %s", code, stderr)
+	}
+	java, err := os.ReadFile(filepath.Join(output, "com", "billing", "Invoice.java"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"package com.billing;", "import java.time.LocalDate;", "private LocalDate dueDate;"} {
+		if !strings.Contains(string(java), expected) {
+			t.Errorf("expected %q in\n%s", expected, java)
+		}
+	}
+
+	if code, _, stderr := run(t, "import", model); code != ExitUsage {
+		t.Errorf("expected a usage error without output directory, got exit code %d: %s", code, stderr)
+	}
+	if err := os.WriteFile(model, []byte(`{"version": 1, "classes": [{"name": "Invoice", "fields": []}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, stderr := run(t, "import", model, output); code != ExitFailure || !strings.Contains(stderr, `unknown field "fields"`) {
+		t.Errorf("expected an error for an unknown field, got exit code %d: %s", code, stderr)
+	}
+}
+
+func TestConvertPlantUML(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"plantuml/orders.puml": "@startuml\npackage com.shop {\n  class Order {\n    - items : List<Item>\n    + total() : int\n  }\n}\nclass Item {\n  + price() : int\n}\nOrder --> Item\n@enduml\n",
+		"plantuml/total.md":    "# Total\n\n```plantuml\n@startuml\nactor user\nuser -> Order ++ : total()\nOrder -> Item : price()\nItem --> Order : sum\nreturn sum\n@enduml\n```\n",
+		"mermaid/orders.mmd":   "classDiagram\nnamespace com.shop {\n  class Order {\n    -List~Item~ items\n    +total() int\n  }\n}\nclass Item {\n  +price() int\n}\nOrder --> Item\n",
+		"mermaid/total.md":     "# Total\n\n```mermaid\nsequenceDiagram\nactor user\nuser ->> Order: total()\nOrder ->> Item: price()\nItem -->> Order: sum\nOrder -->> user: sum\n```\n",
+	}
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, input := range []string{"plantuml", "mermaid"} {
+		if code, _, stderr := run(t, "convert", filepath.Join(dir, input), filepath.Join(dir, input+"-java")); code != ExitOK {
+			t.Fatalf("%s: convert failed with exit code %d: %s", input, code, stderr)
+		}
+	}
+	for _, file := range []string{"com/shop/Order.java", "Item.java"} {
+		expected, err := os.ReadFile(filepath.Join(dir, "mermaid-java", filepath.FromSlash(file)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err := os.ReadFile(filepath.Join(dir, "plantuml-java", filepath.FromSlash(file)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(actual) != string(expected) {
+			t.Errorf("%s generated from PlantUML differs from Mermaid\n%s\nexpected\n%s", file, actual, expected)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+)
+
+func init() {
+	register(&Command{
+		Name:    "config",
+		Args:    "print [<input dir> [<output dir>]]",
+		Summary: "print the effective project configuration",
+		Description: `
+"config print" accepts the flags of the convert command and prints the configuration that
+results from the configuration file and the flags.`,
+		Flags: func(flags *flag.FlagSet) any { return addProjectFlags(flags) },
+		Run:   runConfig,
+	})
+}
+
+func runConfig(env *Env, flags *flag.FlagSet, value any, args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return usagef("unrecognized config command, try \"config print\"")
+	}
+	if len(args) > 3 {
+		return usagef("too many arguments")
+	}
+
+	cfg, err := value.(*projectFlags).load(flags, args[1:])
+	if err != nil {
+		return fmt.Errorf("error reading configuration: %w", err)
+	}
+
+	if cfg.File != "" {
+		env.Println("# Configuration file:", cfg.File)
+	} else {
+		env.Println("# No configuration file found, using defaults")
+	}
+	env.Printf("%s", cfg)
+	return nil
+}
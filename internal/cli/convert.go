@@ -1,137 +1,188 @@
 package cli
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	generator "github.com/MarmaidTranspiler/Merfolk/internal/CodeTemplateGenerator"
+	"github.com/MarmaidTranspiler/Merfolk/internal/config"
 	"github.com/MarmaidTranspiler/Merfolk/internal/connector"
+	"github.com/MarmaidTranspiler/Merfolk/internal/project"
 	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
-	"io/fs"
+	"io"
 	"os"
-	"path"
+	"os/signal"
+	"path/filepath"
+	"time"
 )
 
-func Convert(args []string) {
+type convertFlags struct {
+	*projectFlags
+	dryRun    bool
+	force     bool
+	keepStale bool
+	watch     bool
+	debounce  time.Duration
+}
+
+func init() {
+	register(&Command{
+		Name:    "convert",
+		Args:    "[<input dir> [<output dir>]]",
+		Summary: "generate Java code from the diagrams in a directory",
+		Description: `
+Convert reads all diagrams below the input directory and generates Java code into the output directory.
+Input and output directory default to the values of the project configuration.
+
+Generated files may be edited. Convert keeps the last generated version of every file in the
+.merfolk directory of the output directory and merges the edits with the newly generated code.
+Edits that conflict with changes of the generated code are marked with conflict markers, which
+must be resolved before the file is regenerated again. --force discards the edits instead; the
+code in protected regions (// merfolk:begin ... // merfolk:end) is always kept.
+
+The manifest .merfolk/manifest.json records the generated files with the hashes of their content
+and of the documents they were generated from. Files whose content does not change are not
+written again. Generated files of classes that were removed from the diagrams are deleted, unless
+they were edited or --keep-stale is given; files that convert did not create are never touched.`,
+		Flags: func(flags *flag.FlagSet) any {
+			f := &convertFlags{projectFlags: addProjectFlags(flags)}
+			flags.BoolVar(&f.dryRun, "dry-run", false, "generate the code in memory and list the files instead of writing them")
+			flags.BoolVar(&f.force, "force", false, "overwrite edits of generated files instead of merging them")
+			flags.BoolVar(&f.keepStale, "keep-stale", false, "report generated files of removed classes instead of removing them")
+			flags.BoolVar(&f.watch, "watch", false, "keep running and regenerate the code whenever an input file changes")
+			flags.DurationVar(&f.debounce, "debounce", 200*time.Millisecond, "time to wait for further changes before regenerating in watch mode")
+			return f
+		},
+		Run: runConvert,
+	})
+}
+
+func runConvert(env *Env, flags *flag.FlagSet, value any, args []string) error {
+	f := value.(*convertFlags)
+	if len(args) > 2 {
+		return usagef("too many arguments")
+	}
+
+	cfg, err := f.load(flags, args)
+	if err != nil {
+		return fmt.Errorf("error reading configuration: %w", err)
+	}
+
 	// Check input arguments
-	if len(args) < 2 {
-		fmt.Println("Specify both input and output directory.")
-		return
+	if cfg.Input == "" || cfg.Output == "" {
+		return usagef("specify both input and output directory")
+	}
+
+	if f.watch {
+		if f.dryRun || f.force {
+			return usagef("--watch cannot be combined with --dry-run or --force")
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		return watch(ctx, env, cfg, f.debounce)
 	}
 
-	inputDir := args[0]
-	outputDir := args[1]
+	return convert(env, cfg, f.dryRun, writeOptions{force: f.force, keepStale: f.keepStale})
+}
 
+// convert generates the code for the project configuration cfg. Errors in single files are
+// reported to env and do not stop the conversion of the other files. With dryRun the code is
+// generated in memory only.
+func convert(env *Env, cfg *config.Config, dryRun bool, options writeOptions) error {
 	// Print the current working directory
 	cwd, err := os.Getwd()
 	if err != nil {
-		fmt.Println("Error fetching current working directory:", err)
-		return
+		return fmt.Errorf("error fetching current working directory: %w", err)
 	}
-	fmt.Println("Current Working Directory:", cwd)
-
-	// Check input directory
-	if _, err := os.Stat(inputDir); os.IsNotExist(err) {
-		fmt.Println("Input directory does not exist:", inputDir)
-		return
+	env.Println("Current Working Directory:", cwd)
+	if cfg.File != "" {
+		env.Println("Configuration:", cfg.File)
 	}
 
-	// Ensure output directory exists
-	if _, err := os.Stat(outputDir); os.IsNotExist(err) {
-		err := os.MkdirAll(outputDir, os.ModePerm)
-		if err != nil {
-			fmt.Println("Failed to create output directory:", err)
-			return
-		}
+	p, err := buildProject(env, cfg, nil)
+	if err != nil {
+		return err
 	}
+	files := renderProject(env, cfg, p)
 
-	// Find all .md files in the input directory
-	mdMatches, err := fs.Glob(os.DirFS(inputDir), "*.md")
-	if err != nil {
-		fmt.Println("Error reading input directory:", err)
-		return
+	if dryRun {
+		return listGeneratedFiles(env, cfg.Output, files)
 	}
 
-	var files []string
-	for _, match := range mdMatches {
-		files = append(files, path.Join(inputDir, match))
+	_, err = writeGeneratedFiles(env, cfg.Output, files, options)
+	return err
+}
+
+// diagramCache keeps the parsed input files, keyed by their path relative to the input
+// directory, so that watch mode only parses the files that changed.
+type diagramCache map[string]project.Document
+
+// buildProject reads all diagrams below the input directory of cfg and transforms them into one model.
+// Files found in cache are not parsed again, cache may be nil.
+func buildProject(env *Env, cfg *config.Config, cache diagramCache) (*project.Project, error) {
+	inputDir := cfg.Input
+
+	// Check input directory
+	if _, err := os.Stat(inputDir); os.IsNotExist(err) {
+		return nil, fmt.Errorf("input directory does not exist: %s", inputDir)
 	}
 
-	// Initialize maps for classes and interfaces
-	classes := make(map[string]*generator.Class)
-	interfaces := make(map[string]*generator.Interface)
-	var sequenceDiagrams []*reader.SequenceDiagram
+	// Find all Markdown and Mermaid files below the input directory
+	files, err := findInputFiles(inputDir, cfg.Include, cfg.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("error reading input directory: %w", err)
+	}
 
 	// Parse Mermaid files
-	for _, file := range files {
-		fmt.Println("Processing file:", file)
+	var documents []project.Document
+	for _, source := range files {
+		file := filepath.Join(inputDir, filepath.FromSlash(source))
 
 		// Parse file into diagrams
-		diagrams, err := reader.ParseFile(file)
-		if err != nil {
-			fmt.Println("Error parsing file", file, ":", err)
-			continue
-		}
-
-		// Separate class and sequence diagrams
-		for _, diagram := range diagrams {
-			if diagram.IsClass && diagram.Class != nil {
-				// Process class diagrams
-				classMap, interfaceMap, err := connector.TransformClassDiagram(
-					diagram.Class,
-					"internal/CodeTemplateGenerator/ClassTemplate.tmpl",     // No immediate output yet
-					"internal/CodeTemplateGenerator/InterfaceTemplate.tmpl", // No immediate output yet
-					outputDir+"/",
-				)
-				if err != nil {
-					fmt.Println("Error processing class diagram in file", file, ":", err)
-					continue
-				}
-
-				// Merge class and interface maps
-				for k, v := range classMap {
-					classes[k] = v
-				}
-				for k, v := range interfaceMap {
-					interfaces[k] = v
-				}
-			} else if diagram.IsSequence && diagram.Sequence != nil {
-				// Store sequence diagrams for later processing
-				sequenceDiagrams = append(sequenceDiagrams, diagram.Sequence)
-			} else {
-				fmt.Println("Unknown or unsupported diagram type in file:", file)
+		document, cached := cache[source]
+		if !cached {
+			env.Println("Processing file:", file)
+			content, err := os.ReadFile(file)
+			if err != nil {
+				env.Errorf("Error reading file %s: %v", file, err)
+				continue
+			}
+			diagrams, err := reader.ParseDocument(string(content), reader.SyntaxOf(file))
+			if err != nil {
+				env.Errorf("Error parsing file %s: %v", file, err)
+				continue
+			}
+			document = project.Document{Path: source, Diagrams: diagrams, Fingerprint: project.Hash(content)}
+			if cache != nil {
+				cache[source] = document
 			}
 		}
-	}
 
-	// Process sequence diagrams and integrate with classes
-	for _, sequenceDiagram := range sequenceDiagrams {
-		err := connector.TransformSequenceDiagram(
-			sequenceDiagram,
-			classes, // Modify existing class definitions
-			"internal/CodeTemplateGenerator/ClassTemplate.tmpl", // No immediate output yet
-			outputDir+"/",
-		)
-		if err != nil {
-			fmt.Println("Error processing sequence diagram:", err)
-		} else {
-			fmt.Println("Successfully processed sequence diagram.")
-		}
+		documents = append(documents, document)
 	}
 
-	// Generate Java code for all classes and interfaces
-	classTemplatePath := "internal/CodeTemplateGenerator/ClassTemplate.tmpl"
-	interfaceTemplatePath := "internal/CodeTemplateGenerator/InterfaceTemplate.tmpl"
+	return project.Build(cfg, documents, reportTo(env, inputDir)), nil
+}
 
-	for _, class := range classes {
-		err := generator.GenerateJavaCode(*class, outputDir+"/", class.ClassName, classTemplatePath)
-		if err != nil {
-			fmt.Println("Error generating Java class:", class.ClassName, ":", err)
-		}
-	}
+// renderProject generates the Java code of all classes and interfaces of p in memory.
+func renderProject(env *Env, cfg *config.Config, p *project.Project) []project.File {
+	return project.Render(cfg, p, reportTo(env, cfg.Input))
+}
+
+// discardConnectorLog discards the progress messages of the connector and returns a function
+// that restores its log.
+func discardConnectorLog() (restore func()) {
+	log := connector.Log
+	connector.Log = io.Discard
+	return func() { connector.Log = log }
+}
 
-	for _, iface := range interfaces {
-		err := generator.GenerateJavaCode(*iface, outputDir+"/", iface.InterfaceName, interfaceTemplatePath)
-		if err != nil {
-			fmt.Println("Error generating Java interface:", iface.InterfaceName, ":", err)
+// reportTo returns a reporter that prints diagnostics as errors of env. Document paths are
+// shown relative to the working directory.
+func reportTo(env *Env, inputDir string) project.Reporter {
+	return func(d project.Diagnostic) {
+		if d.Path != "" {
+			d.Path = filepath.Join(inputDir, filepath.FromSlash(d.Path))
 		}
+		env.Errorf("%s", d)
 	}
 }
@@ -0,0 +1,204 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/config"
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+	"github.com/MarmaidTranspiler/Merfolk/internal/semantic"
+)
+
+type diffFlags struct {
+	configFile string
+	format     string
+	rev        string
+}
+
+func init() {
+	register(&Command{
+		Name:    "diff",
+		Args:    "[--format text|markdown|json] (<old> <new> | --rev <revision> [<file or dir>...])",
+		Summary: "compare the meaning of two versions of the diagrams",
+		Description: `
+Diff compares the diagrams of two files or directories by their meaning instead of their text:
+it lists the classes, members, relationships, sequence diagrams and calls that were added or
+removed, the members whose types, parameters or visibility changed, and the calls that are made
+at another position of their sequence. Sequence diagrams are identified by the method their
+first message calls. The layout of the diagrams and the documents they are spread over do not
+matter.
+
+With --rev the files or directories as of the given git revision are compared with their
+current content. Without arguments the input directory of the project configuration is
+compared.
+
+The report is a list of sentences, a Markdown list for review comments, or JSON.`,
+		Flags: func(flags *flag.FlagSet) any {
+			f := &diffFlags{}
+			flags.StringVar(&f.configFile, "config", "", "configuration file (default: merfolk.yaml in the working directory)")
+			flags.StringVar(&f.format, "format", "text", "report format: text, markdown or json")
+			flags.StringVar(&f.rev, "rev", "", "compare with the files as of this git revision")
+			return f
+		},
+		Run: runDiff,
+	})
+}
+
+func runDiff(env *Env, _ *flag.FlagSet, value any, args []string) error {
+	f := value.(*diffFlags)
+	switch f.format {
+	case "text", "markdown", "json":
+	default:
+		return usagef("unknown format %q, expected text, markdown or json", f.format)
+	}
+
+	var old, new *semantic.Model
+	if f.rev == "" {
+		if len(args) != 2 {
+			return usagef("specify the old and the new version, or a revision with --rev")
+		}
+		var err error
+		if old, err = workingModel(env, f.configFile, args[:1]); err != nil {
+			return err
+		}
+		if new, err = workingModel(env, f.configFile, args[1:]); err != nil {
+			return err
+		}
+	} else {
+		cfg, err := config.Discover(f.configFile)
+		if err != nil {
+			return fmt.Errorf("error reading configuration: %w", err)
+		}
+		if len(args) == 0 {
+			if cfg.Input == "" {
+				return usagef("specify the files or directories")
+			}
+			args = []string{cfg.Input}
+		}
+		if old, err = revisionModel(env, cfg, f.rev, args); err != nil {
+			return err
+		}
+		if new, err = workingModel(env, f.configFile, args); err != nil {
+			return err
+		}
+	}
+
+	changes := semantic.Compare(old, new)
+	switch f.format {
+	case "text":
+		for _, change := range changes {
+			env.Println(change)
+		}
+	case "markdown":
+		if len(changes) == 0 {
+			env.Println("No changes.")
+		}
+		for _, change := range changes {
+			env.Println("- " + change.Markdown())
+		}
+	case "json":
+		if changes == nil {
+			changes = []semantic.Change{}
+		}
+		content, err := json.MarshalIndent(struct {
+			Changes []semantic.Change `json:"changes"`
+		}{changes}, "", "  ")
+		if err != nil {
+			return err
+		}
+		env.Printf("%s\n", content)
+	}
+	return nil
+}
+
+// workingModel returns the model of the diagrams of the given files and directories.
+func workingModel(env *Env, configFile string, args []string) (*semantic.Model, error) {
+	files, err := documentFiles(configFile, args)
+	if err != nil {
+		return nil, err
+	}
+	m := semantic.NewModel()
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			env.Errorf("%v", err)
+			continue
+		}
+		addDocument(env, m, file, content)
+	}
+	return m, nil
+}
+
+// revisionModel returns the model of the diagrams of the given files and directories as of a
+// git revision. The files of directories are selected like by documentFiles, files that did
+// not exist are left out.
+func revisionModel(env *Env, cfg *config.Config, rev string, args []string) (*semantic.Model, error) {
+	m := semantic.NewModel()
+	for _, arg := range args {
+		listing, err := git("ls-tree", "-r", "-z", "--name-only", rev, "--", arg)
+		if err != nil {
+			return nil, err
+		}
+		dir := path.Clean(filepath.ToSlash(arg))
+		for _, file := range strings.Split(string(listing), "\x00") {
+			if file == "" {
+				continue
+			}
+			// Files named by an argument are always read
+			if file != dir {
+				rel := file
+				if dir != "." {
+					rel = strings.TrimPrefix(file, dir+"/")
+				}
+				if !isInputFile(rel, cfg.Include, cfg.Exclude) {
+					continue
+				}
+			}
+			content, err := git("show", rev+":./"+file)
+			if err != nil {
+				return nil, err
+			}
+			addDocument(env, m, rev+":"+file, content)
+		}
+	}
+	return m, nil
+}
+
+// addDocument adds the diagrams of a document to m, except the ones that are skipped.
+// Documents that do not parse are reported.
+func addDocument(env *Env, m *semantic.Model, name string, content []byte) {
+	diagrams, err := reader.ParseDocument(string(content), reader.SyntaxOf(name))
+	if err != nil {
+		env.Errorf("Error parsing file %s: %v", name, err)
+		return
+	}
+	for _, diagram := range diagrams {
+		if !diagram.Options.Skip {
+			m.Add(diagram)
+		}
+	}
+}
+
+// git runs git in the working directory and returns its output.
+func git(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) {
+			return nil, fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+		}
+		return nil, err
+	}
+	return output, nil
+}
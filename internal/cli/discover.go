@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/config"
+)
+
+// inputExtensions lists the file types that may contain Mermaid or PlantUML diagrams.
+var inputExtensions = map[string]bool{
+	".md":       true,
+	".markdown": true,
+	".mdx":      true,
+	".adoc":     true,
+	".asciidoc": true,
+	".asc":      true,
+	".mmd":      true,
+	".mermaid":  true,
+	".puml":     true,
+	".plantuml": true,
+	".pu":       true,
+}
+
+// findInputFiles walks inputDir recursively and returns the paths of all diagram sources,
+// relative to inputDir and in lexical order. A file is selected if it has a known extension,
+// matches at least one include pattern (or no include patterns are given) and matches none
+// of the exclude patterns. Hidden directories such as .git are skipped.
+func findInputFiles(inputDir string, include, exclude []string) ([]string, error) {
+	var files []string
+
+	err := filepath.WalkDir(inputDir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(inputDir, filePath)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if entry.IsDir() {
+			if rel != "." && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if rel != "." && matchAny(exclude, rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if isInputFile(rel, include, exclude) {
+			files = append(files, rel)
+		}
+		return nil
+	})
+
+	// WalkDir visits entries in lexical order, so files is already sorted
+	return files, err
+}
+
+// isInputFile reports whether findInputFiles selects the file with the slash separated path
+// rel below the input directory.
+func isInputFile(rel string, include, exclude []string) bool {
+	for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+		if strings.HasPrefix(path.Base(dir), ".") || matchAny(exclude, dir) {
+			return false
+		}
+	}
+	if !inputExtensions[strings.ToLower(path.Ext(rel))] {
+		return false
+	}
+	if len(include) > 0 && !matchAny(include, rel) {
+		return false
+	}
+	return !matchAny(exclude, rel)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob reports whether the slash separated name matches pattern. Besides the
+// syntax of path.Match, a "**" segment matches any number of directories. Patterns
+// without a slash are matched against the base name, so "*.mmd" selects files at any depth.
+func matchGlob(pattern, name string) bool {
+	pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// documentFiles returns the files named by args. Directories are searched like the input
+// directory of convert; without args the input directory of the project configuration is used.
+func documentFiles(configFile string, args []string) ([]string, error) {
+	cfg, err := config.Discover(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading configuration: %w", err)
+	}
+	if len(args) == 0 {
+		if cfg.Input == "" {
+			return nil, usagef("specify the files or directories")
+		}
+		args = []string{cfg.Input}
+	}
+
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+
+		found, err := findInputFiles(arg, cfg.Include, cfg.Exclude)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range found {
+			files = append(files, filepath.Join(arg, filepath.FromSlash(name)))
+		}
+	}
+	return files, nil
+}
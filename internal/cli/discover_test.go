@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.md", "README.md", true},
+		{"*.md", "docs/deep/README.md", true},
+		{"docs/*.md", "docs/README.md", true},
+		{"docs/*.md", "docs/deep/README.md", false},
+		{"docs/**/*.md", "docs/README.md", true},
+		{"docs/**/*.md", "docs/a/b/README.md", true},
+		{"**/drafts/**", "x/drafts/y/z.md", true},
+		{"**/drafts/**", "x/final/z.md", false},
+		{"./docs/*.mmd", "docs/flow.mmd", true},
+	}
+
+	for _, test := range tests {
+		if got := matchGlob(test.pattern, test.name); got != test.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", test.pattern, test.name, got, test.want)
+		}
+	}
+}
+
+func TestFindInputFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"README.md",
+		"notes.txt",
+		"docs/orders/model.md",
+		"docs/orders/flow.mmd",
+		"docs/drafts/old.md",
+		"diagrams/auth.mermaid",
+		".git/info.md",
+	} {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := findInputFiles(dir, nil, []string{"drafts"})
+	if err != nil {
+		t.Fatalf("Error finding input files: %v", err)
+	}
+	expected := "README.md diagrams/auth.mermaid docs/orders/flow.mmd docs/orders/model.md"
+	if got := strings.Join(files, " "); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+
+	files, err = findInputFiles(dir, []string{"docs/**"}, nil)
+	if err != nil {
+		t.Fatalf("Error finding input files: %v", err)
+	}
+	expected = "docs/drafts/old.md docs/orders/flow.mmd docs/orders/model.md"
+	if got := strings.Join(files, " "); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/exchange"
+)
+
+type exportFlags struct {
+	*projectFlags
+	format string
+	output string
+	schema bool
+}
+
+func init() {
+	register(&Command{
+		Name:    "export",
+		Args:    "[--format json|yaml] [<input dir>]",
+		Summary: "write the model of the diagrams as JSON or YAML",
+		Description: `
+Export transforms all diagrams below the input directory like convert, but writes the resulting
+model instead of the Java code: the classes and interfaces with their packages, imports,
+attributes and methods, including the method bodies derived from the sequence diagrams. Other
+tools can read the model without parsing Mermaid, and merfolk import generates the code from it.
+
+The format defaults to the extension of the --output file, or JSON. --schema writes the JSON
+Schema of the documents instead.`,
+		Flags: func(flags *flag.FlagSet) any {
+			f := &exportFlags{projectFlags: addProjectFlags(flags)}
+			flags.StringVar(&f.format, "format", "", "document format: json or yaml")
+			flags.StringVar(&f.output, "output", "", "file to write the model to (default: standard output)")
+			flags.BoolVar(&f.schema, "schema", false, "write the JSON Schema of the model instead of the model")
+			return f
+		},
+		Run: runExport,
+	})
+}
+
+func runExport(env *Env, flags *flag.FlagSet, value any, args []string) error {
+	f := value.(*exportFlags)
+	if len(args) > 1 {
+		return usagef("too many arguments")
+	}
+	format := exchange.FormatOf(f.output)
+	if f.format != "" {
+		var err error
+		if format, err = exchange.ParseFormat(f.format); err != nil {
+			return usagef("%v", err)
+		}
+	}
+
+	var content []byte
+	if f.schema {
+		content = exchange.Schema
+	} else {
+		cfg, err := f.load(flags, args)
+		if err != nil {
+			return fmt.Errorf("error reading configuration: %w", err)
+		}
+		if cfg.Input == "" {
+			return usagef("specify the input directory")
+		}
+
+		// Progress messages and the log of the connector would mix with the model
+		quiet := &Env{Stdout: io.Discard, Stderr: env.Stderr}
+		defer discardConnectorLog()()
+		p, err := buildProject(quiet, cfg, nil)
+		if err != nil {
+			return err
+		}
+		env.errors += quiet.errors
+
+		if content, err = exchange.Marshal(p.Model, format); err != nil {
+			return fmt.Errorf("error writing the model: %w", err)
+		}
+	}
+
+	if f.output == "" {
+		env.Printf("%s", content)
+		return nil
+	}
+	return os.WriteFile(f.output, content, 0o644)
+}
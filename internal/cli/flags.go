@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"flag"
+	"strings"
+)
+
+// stringList is a flag that can be given multiple times and collects all values.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// parseInterspersed parses args with fs and returns the positional arguments.
+// Unlike fs.Parse, flags may also follow the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/format"
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+)
+
+type fmtFlags struct {
+	configFile string
+	check      bool
+	order      string
+	indent     int
+}
+
+func init() {
+	register(&Command{
+		Name:    "fmt",
+		Args:    "[<file or dir>...]",
+		Summary: "format the Mermaid diagrams in place",
+		Description: `
+Fmt rewrites the class and sequence diagrams of the given files, or of all files below the given
+directories, in a canonical layout. Without arguments the input directory of the project
+configuration is formatted. Text outside of the diagrams is not changed.`,
+		Flags: func(flags *flag.FlagSet) any {
+			f := &fmtFlags{}
+			flags.StringVar(&f.configFile, "config", "", "configuration file (default: merfolk.yaml in the working directory)")
+			flags.BoolVar(&f.check, "check", false, "list the files that are not formatted and fail instead of rewriting them")
+			flags.StringVar(&f.order, "order", "source", "order of class members: source, kind (attributes first) or name")
+			flags.IntVar(&f.indent, "indent", 4, "number of spaces per nesting level")
+			return f
+		},
+		Run: runFmt,
+	})
+}
+
+func runFmt(env *Env, _ *flag.FlagSet, value any, args []string) error {
+	f := value.(*fmtFlags)
+
+	order, err := format.ParseOrder(f.order)
+	if err != nil {
+		return usagef("%v", err)
+	}
+	if f.indent < 1 {
+		return usagef("indent must be positive")
+	}
+	options := format.Options{Indent: fmt.Sprintf("%*s", f.indent, ""), Order: order}
+
+	files, err := documentFiles(f.configFile, args)
+	if err != nil {
+		return err
+	}
+
+	unformatted := 0
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			env.Errorf("%v", err)
+			continue
+		}
+
+		formatted, err := format.Document(string(content), reader.SyntaxOf(file), options)
+		if err != nil {
+			env.Errorf("%s: %v", file, err)
+			continue
+		}
+		if formatted == string(content) {
+			continue
+		}
+
+		unformatted++
+		env.Println(file)
+		if f.check {
+			continue
+		}
+		if err := os.WriteFile(file, []byte(formatted), 0o644); err != nil {
+			env.Errorf("%v", err)
+		}
+	}
+
+	if f.check && unformatted > 0 {
+		return fmt.Errorf("%d files are not formatted", unformatted)
+	}
+	return nil
+}
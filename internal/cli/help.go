@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"flag"
+)
+
+func init() {
+	register(&Command{
+		Name:    "help",
+		Args:    "[<command>]",
+		Summary: "show the commands or the help of a command",
+		Run:     runHelp,
+	})
+}
+
+func runHelp(env *Env, _ *flag.FlagSet, _ any, args []string) error {
+	if len(args) == 0 {
+		printCommands(env.Stdout)
+		return nil
+	}
+	if len(args) > 1 {
+		return usagef("too many arguments")
+	}
+
+	cmd, exists := commands[args[0]]
+	if !exists {
+		return usagef("unknown command %q", args[0])
+	}
+
+	flags := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	flags.SetOutput(env.Stdout)
+	if cmd.Flags != nil {
+		cmd.Flags(flags)
+	}
+	printUsage(env.Stdout, cmd, flags)
+	return nil
+}
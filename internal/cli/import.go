@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/exchange"
+	"github.com/MarmaidTranspiler/Merfolk/internal/project"
+)
+
+type importFlags struct {
+	*projectFlags
+	format    string
+	dryRun    bool
+	force     bool
+	keepStale bool
+}
+
+func init() {
+	register(&Command{
+		Name:    "import",
+		Args:    "[--format json|yaml] <model file> [<output dir>]",
+		Summary: "generate Java code from a model written as JSON or YAML",
+		Description: `
+Import generates Java code from a model file instead of diagrams, as written by merfolk export
+or by other tools; merfolk export --schema prints the JSON Schema of the file. The code is
+written into the output directory like by convert, including the merge of edits and the
+removal of stale files. The output directory defaults to the value of the project
+configuration.
+
+The format defaults to the extension of the model file: YAML for .yaml and .yml, JSON
+otherwise. Classes and interfaces without package are placed in the base package, and the
+imports of types that list none are derived from the types they reference.`,
+		Flags: func(flags *flag.FlagSet) any {
+			f := &importFlags{projectFlags: addProjectFlags(flags)}
+			flags.StringVar(&f.format, "format", "", "document format: json or yaml")
+			flags.BoolVar(&f.dryRun, "dry-run", false, "generate the code in memory and list the files instead of writing them")
+			flags.BoolVar(&f.force, "force", false, "overwrite edits of generated files instead of merging them")
+			flags.BoolVar(&f.keepStale, "keep-stale", false, "report generated files of removed classes instead of removing them")
+			return f
+		},
+		Run: runImport,
+	})
+}
+
+func runImport(env *Env, flags *flag.FlagSet, value any, args []string) error {
+	f := value.(*importFlags)
+	switch {
+	case len(args) == 0:
+		return usagef("specify the model file")
+	case len(args) > 2:
+		return usagef("too many arguments")
+	}
+	file := args[0]
+	format := exchange.FormatOf(file)
+	if f.format != "" {
+		var err error
+		if format, err = exchange.ParseFormat(f.format); err != nil {
+			return usagef("%v", err)
+		}
+	}
+
+	cfg, err := f.load(flags, nil)
+	if err != nil {
+		return fmt.Errorf("error reading configuration: %w", err)
+	}
+	if len(args) > 1 {
+		cfg.Output = args[1]
+	}
+	if cfg.Output == "" {
+		return usagef("specify the output directory")
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	model, err := exchange.Unmarshal(content, format)
+	if err != nil {
+		return fmt.Errorf("error reading model %s: %w", file, err)
+	}
+	p := project.FromModel(cfg, model, filepath.ToSlash(file), project.Hash(content))
+	files := renderProject(env, cfg, p)
+
+	if f.dryRun {
+		return listGeneratedFiles(env, cfg.Output, files)
+	}
+	_, err = writeGeneratedFiles(env, cfg.Output, files, writeOptions{force: f.force, keepStale: f.keepStale})
+	return err
+}
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/config"
+)
+
+// initTemplate is the configuration file written by the init command.
+const initTemplate = `# Merfolk project configuration, see "merfolk config print" for all settings.
+
+# Directory that is searched for Markdown, AsciiDoc and Mermaid files
+input: %s
+# exclude: ["drafts/**"]
+
+# Directory the generated Java code is written to
+output: %s
+
+# Package of classes that are not declared in a namespace
+# basePackage: com.example
+
+# Additional type mappings
+# types:
+#   Money:
+#     target: java.math.BigDecimal
+#     default: BigDecimal.ZERO
+#     value: true
+
+naming:
+  interfacePrefix: I
+
+features:
+  getters: true
+  setters: true
+  constructors: true
+`
+
+type initFlags struct {
+	force  bool
+	input  string
+	output string
+}
+
+func init() {
+	register(&Command{
+		Name:    "init",
+		Args:    "[<dir>]",
+		Summary: "create a merfolk.yaml configuration file",
+		Description: `
+Init writes a merfolk.yaml with the default settings into the given directory or the working directory.`,
+		Flags: func(flags *flag.FlagSet) any {
+			f := &initFlags{}
+			flags.BoolVar(&f.force, "force", false, "overwrite an existing configuration file")
+			flags.StringVar(&f.input, "input", "docs", "input directory written to the configuration")
+			flags.StringVar(&f.output, "output", "src/main/java", "output directory written to the configuration")
+			return f
+		},
+		Run: runInit,
+	})
+}
+
+func runInit(env *Env, _ *flag.FlagSet, value any, args []string) error {
+	f := value.(*initFlags)
+	if len(args) > 1 {
+		return usagef("too many arguments")
+	}
+
+	dir := "."
+	if len(args) == 1 {
+		dir = args[0]
+	}
+
+	existing, err := config.Find(dir)
+	if err != nil {
+		return err
+	}
+	if existing != "" && !f.force {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", existing)
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	file := existing
+	if file == "" {
+		file = filepath.Join(dir, config.FileNames[0])
+	}
+	content := fmt.Sprintf(initTemplate, f.input, f.output)
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		return err
+	}
+
+	// The written file must be readable by the configuration loader
+	if _, err := config.Load(file); err != nil {
+		return errors.Join(errors.New("generated configuration is invalid"), err)
+	}
+
+	env.Println("Created", file)
+	return nil
+}
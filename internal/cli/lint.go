@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/config"
+	"github.com/MarmaidTranspiler/Merfolk/internal/lint"
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+)
+
+type lintFlags struct {
+	configFile string
+	rules      stringList
+	list       bool
+}
+
+func init() {
+	register(&Command{
+		Name:    "lint",
+		Args:    "[<file or dir>...]",
+		Summary: "report style and correctness problems in the diagrams",
+		Description: `
+Lint checks the diagrams of the given files, or of all files below the given directories, with
+the registered rules. Without arguments the input directory of the project configuration is
+checked. The severity of the rules is set in the lint section of the configuration or with
+--rule, single findings are suppressed with a "%% merfolk-ignore <rule>" comment on the line of
+the statement or the line in front of it. Lint fails if a finding has the severity error.`,
+		Flags: func(flags *flag.FlagSet) any {
+			f := &lintFlags{}
+			flags.StringVar(&f.configFile, "config", "", "configuration file (default: merfolk.yaml in the working directory)")
+			flags.Var(&f.rules, "rule", "set the severity of a rule, e.g. method-case=error (repeatable)")
+			flags.BoolVar(&f.list, "list", false, "list the rules and their severity")
+			return f
+		},
+		Run: runLint,
+	})
+}
+
+func runLint(env *Env, _ *flag.FlagSet, value any, args []string) error {
+	f := value.(*lintFlags)
+
+	cfg, err := config.Discover(f.configFile)
+	if err != nil {
+		return fmt.Errorf("error reading configuration: %w", err)
+	}
+	rules, err := lint.NewConfig(cfg.Lint.Rules)
+	if err != nil {
+		return fmt.Errorf("error reading configuration: %w", err)
+	}
+	for _, entry := range f.rules {
+		name, severity, found := strings.Cut(entry, "=")
+		if !found {
+			return usagef("invalid rule %q, expected rule=severity", entry)
+		}
+		if err := rules.Set(name, severity); err != nil {
+			return usagef("%v", err)
+		}
+	}
+
+	if f.list {
+		for _, rule := range lint.Rules() {
+			env.Printf("%-20s %-8s %s\n", rule.Name, rules.Severity(rule.Name), rule.Description)
+		}
+		return nil
+	}
+
+	files, err := documentFiles(f.configFile, args)
+	if err != nil {
+		return err
+	}
+
+	counts := make(map[lint.Severity]int)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			env.Errorf("%v", err)
+			continue
+		}
+
+		diagnostics, err := lint.Document(string(content), reader.SyntaxOf(file), rules)
+		if err != nil {
+			env.Errorf("%s: %v", file, err)
+			continue
+		}
+		for _, d := range diagnostics {
+			env.Printf("%s:%d: %s: %s [%s]\n", file, d.Line, d.Severity, d.Message, d.Rule)
+			counts[d.Severity]++
+		}
+	}
+
+	if counts[lint.Error] > 0 {
+		return fmt.Errorf("%d errors, %d warnings", counts[lint.Error], counts[lint.Warning])
+	}
+	return nil
+}
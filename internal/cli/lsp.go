@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"flag"
+	"io"
+	"os"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/connector"
+	"github.com/MarmaidTranspiler/Merfolk/internal/lsp"
+)
+
+func init() {
+	register(&Command{
+		Name:    "lsp",
+		Summary: "run a language server for editors on standard input and output",
+		Description: `
+Lsp speaks the Language Server Protocol on standard input and output. Editors start it for
+Markdown, AsciiDoc and Mermaid files and show the diagnostics of check and lint while typing.
+Inside sequence diagrams it completes participants, class names and the methods of the
+receiver, shows the generated Java declaration on hover and jumps from a message to the
+member in the class diagram.
+
+The project configuration is read from the workspace root, the files of its input directory
+are analyzed together with the open documents.`,
+		Flags: func(flags *flag.FlagSet) any {
+			// Language clients pass --stdio to select the transport, it is the only one supported
+			flags.Bool("stdio", true, "communicate over standard input and output")
+			return nil
+		},
+		Run: runLSP,
+	})
+}
+
+func runLSP(env *Env, _ *flag.FlagSet, _ any, args []string) error {
+	if len(args) > 0 {
+		return usagef("too many arguments")
+	}
+
+	// Standard output carries the protocol, the progress messages of the transformation would corrupt it
+	connector.Log = io.Discard
+
+	server := lsp.NewServer(lsp.Options{
+		Version:   version(),
+		FindFiles: findInputFiles,
+		Log:       env.Stderr,
+	})
+	return server.Serve(os.Stdin, env.Stdout)
+}
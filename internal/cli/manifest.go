@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// manifestVersion is the version of the manifest format.
+const manifestVersion = 1
+
+// manifest records the files merfolk generated in an output directory. It is used to skip
+// files whose generated code did not change and to find the files of classes that were removed
+// from the diagrams. Files that are not in the manifest are never changed or removed.
+type manifest struct {
+	Version int                       `json:"version"`
+	Files   map[string]*manifestEntry `json:"files"`
+}
+
+// manifestEntry describes a generated file.
+type manifestEntry struct {
+	// Hash is the hash of the file as merfolk wrote it, edits of the file change its hash
+	Hash string `json:"hash"`
+	// Generated is the hash of the generated code, before edits and protected regions were merged
+	Generated string `json:"generated"`
+	// Sources maps the input documents the file was generated from to their fingerprints
+	Sources map[string]string `json:"sources,omitempty"`
+}
+
+func manifestPath(outputDir string) string {
+	return filepath.Join(outputDir, cacheDir, "manifest.json")
+}
+
+// loadManifest reads the manifest of an output directory. Without a manifest an empty one is
+// returned.
+func loadManifest(outputDir string) (*manifest, error) {
+	m := &manifest{Version: manifestVersion, Files: make(map[string]*manifestEntry)}
+	content, err := os.ReadFile(manifestPath(outputDir))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s, remove it to start over: %w", manifestPath(outputDir), err)
+	}
+	if m.Version != manifestVersion {
+		return nil, fmt.Errorf("manifest %s has the unsupported version %d", manifestPath(outputDir), m.Version)
+	}
+	if m.Files == nil {
+		m.Files = make(map[string]*manifestEntry)
+	}
+	// The recorded files are removed when they become stale, so they must lie below outputDir
+	for path, entry := range m.Files {
+		if entry == nil {
+			return nil, fmt.Errorf("invalid manifest %s, remove it to start over: %s has no entry", manifestPath(outputDir), path)
+		}
+		if !filepath.IsLocal(filepath.FromSlash(path)) || slices.Contains(strings.Split(path, "/"), "..") {
+			return nil, fmt.Errorf("invalid manifest %s, remove it to start over: %s is not a path below the output directory", manifestPath(outputDir), path)
+		}
+	}
+	return m, nil
+}
+
+// save writes the manifest if its content changed.
+func (m *manifest) save(outputDir string) error {
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	content = append(content, '\n')
+
+	file := manifestPath(outputDir)
+	if existing, err := os.ReadFile(file); err == nil && bytes.Equal(existing, content) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(file, content, 0o644)
+}
+
+// stale returns the paths of the recorded files that are not generated anymore, in lexical order.
+func (m *manifest) stale(generated map[string]bool) []string {
+	var paths []string
+	for path := range m.Files {
+		if !generated[path] {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,235 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	generator "github.com/MarmaidTranspiler/Merfolk/internal/CodeTemplateGenerator"
+	"github.com/MarmaidTranspiler/Merfolk/internal/merge"
+	"github.com/MarmaidTranspiler/Merfolk/internal/project"
+)
+
+// cacheDir is the directory below the output directory in which merfolk keeps its state.
+const cacheDir = ".merfolk"
+
+// basePath returns the path of the last generated version of a file, the common base of the
+// three-way merge of the edits on disk and the newly generated code.
+func basePath(outputDir, file string) string {
+	return filepath.Join(outputDir, cacheDir, "base", filepath.FromSlash(file))
+}
+
+// writeOptions control how generated files are written.
+type writeOptions struct {
+	// force overwrites edits of generated files instead of merging them
+	force bool
+	// keepStale reports files of removed classes instead of removing them
+	keepStale bool
+}
+
+// writeStats counts the changes of writeGeneratedFiles.
+type writeStats struct {
+	written int
+	removed int
+}
+
+// writeGeneratedFiles writes files below outputDir. Files whose content did not change are not
+// touched, so that their modification time is kept. Edits of existing files are merged with
+// the generated code unless options.force is set. Files that were generated by an earlier run
+// but not by this one are removed if they were not edited.
+func writeGeneratedFiles(env *Env, outputDir string, files []project.File, options writeOptions) (writeStats, error) {
+	var stats writeStats
+
+	// Ensure output directory exists
+	if _, err := os.Stat(outputDir); os.IsNotExist(err) {
+		err := os.MkdirAll(outputDir, os.ModePerm)
+		if err != nil {
+			return stats, fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	m, err := loadManifest(outputDir)
+	if err != nil {
+		return stats, err
+	}
+
+	generated := make(map[string]bool, len(files))
+	for _, file := range files {
+		generated[file.Path] = true
+		entry, changed, ok := writeGeneratedFile(env, outputDir, file, m.Files[file.Path], options.force)
+		if !ok {
+			// A file that could not be written keeps its entry
+			continue
+		}
+		m.Files[file.Path] = entry
+		if changed {
+			stats.written++
+		}
+	}
+
+	for _, path := range m.stale(generated) {
+		if removeStaleFile(env, outputDir, path, m, options.keepStale) {
+			stats.removed++
+		}
+	}
+
+	if err := m.save(outputDir); err != nil {
+		return stats, fmt.Errorf("error writing the manifest: %w", err)
+	}
+	return stats, nil
+}
+
+// listGeneratedFiles reports the files that writeGeneratedFiles would write below outputDir
+// and the stale files it would remove, without changing anything.
+func listGeneratedFiles(env *Env, outputDir string, files []project.File) error {
+	m, err := loadManifest(outputDir)
+	if err != nil {
+		return err
+	}
+	generated := make(map[string]bool, len(files))
+	for _, file := range files {
+		generated[file.Path] = true
+		env.Println("Would write:", filepath.Join(outputDir, filepath.FromSlash(file.Path)))
+	}
+	for _, path := range m.stale(generated) {
+		env.Println("Would remove:", filepath.Join(outputDir, filepath.FromSlash(path)))
+	}
+	return nil
+}
+
+// writeGeneratedFile writes a file and returns its manifest entry and whether its content
+// changed. ok is false if the file could not be written.
+func writeGeneratedFile(env *Env, outputDir string, file project.File, previous *manifestEntry, force bool) (entry *manifestEntry, changed, ok bool) {
+	full := filepath.Join(outputDir, filepath.FromSlash(file.Path))
+	content := file.Content
+	entry = &manifestEntry{Generated: project.Hash(file.Content), Sources: file.Sources}
+
+	existing, err := os.ReadFile(full)
+	if err != nil && !os.IsNotExist(err) {
+		env.Errorf("Error reading %s: %v", file.Path, err)
+		return nil, false, false
+	}
+	if err == nil {
+		entry.Hash = project.Hash(existing)
+
+		if !force && merge.HasConflictMarkers(string(existing)) {
+			env.Errorf("%s has unresolved conflict markers, resolve them or use --force to overwrite the file", file.Path)
+			return nil, false, false
+		}
+
+		// Neither the generated code nor the file changed since the last run
+		if previous != nil && previous.Generated == entry.Generated && previous.Hash == entry.Hash && !force {
+			return entry, false, true
+		}
+
+		// Keep the hand-written code of the protected regions, the regions that equal the
+		// last generated version were not edited and take the regenerated code
+		base, err := os.ReadFile(basePath(outputDir, file.Path))
+		if err != nil {
+			base = nil
+		}
+		merged, orphaned, err := generator.MergeRegions(content, existing, base)
+		if err != nil {
+			env.Errorf("Error keeping the protected regions of %s, the file is not written: %v", file.Path, err)
+			return nil, false, false
+		}
+		for _, region := range orphaned {
+			env.Warnf("%s:%d: region %q has no counterpart in the generated code anymore, it is kept commented out", file.Path, region.Line, region.Name)
+		}
+		content = merged
+
+		// Keep the other edits by merging them with the changes of the generated code
+		if base != nil && !force {
+			// The edits of the regions are already part of content
+			current, err := generator.ResetRegions(existing, base)
+			if err != nil {
+				env.Errorf("Error merging the edits of %s, the file is not written: %v", file.Path, err)
+				return nil, false, false
+			}
+			result := merge.Merge(string(base), string(current), string(content), "current", "generated")
+			if result.Conflicts > 0 {
+				env.Errorf("%s: local edits conflict with the generated code in %d places, resolve the conflict markers or use --force to overwrite the file", file.Path, result.Conflicts)
+			}
+			content = []byte(result.Text)
+		}
+	}
+
+	changed = existing == nil || !bytes.Equal(existing, content)
+	if changed {
+		if err := os.MkdirAll(filepath.Dir(full), os.ModePerm); err != nil {
+			env.Errorf("Failed to create output directory: %v", err)
+			return nil, false, false
+		}
+		if err := os.WriteFile(full, content, 0o644); err != nil {
+			env.Errorf("Error writing %s: %v", file.Path, err)
+			return nil, false, false
+		}
+		entry.Hash = project.Hash(content)
+	}
+
+	// The generated code is the base of the next merge
+	base := basePath(outputDir, file.Path)
+	if current, err := os.ReadFile(base); err != nil || !bytes.Equal(current, file.Content) {
+		if err := os.MkdirAll(filepath.Dir(base), os.ModePerm); err != nil {
+			env.Errorf("Error writing the merge base of %s: %v", file.Path, err)
+		} else if err := os.WriteFile(base, file.Content, 0o644); err != nil {
+			env.Errorf("Error writing the merge base of %s: %v", file.Path, err)
+		}
+	}
+	return entry, changed, true
+}
+
+// removeStaleFile removes a generated file whose class is no longer generated and reports
+// whether it was removed. Files that were edited since merfolk wrote them are kept and
+// released from the manifest, with keepStale all files are kept and only reported.
+func removeStaleFile(env *Env, outputDir, path string, m *manifest, keepStale bool) bool {
+	full := filepath.Join(outputDir, filepath.FromSlash(path))
+	release := func() {
+		delete(m.Files, path)
+		removeFile(outputDir, basePath(outputDir, path))
+	}
+
+	content, err := os.ReadFile(full)
+	if os.IsNotExist(err) {
+		release()
+		return false
+	}
+	if err != nil {
+		env.Errorf("Error reading %s: %v", path, err)
+		return false
+	}
+
+	switch {
+	case project.Hash(content) != m.Files[path].Hash:
+		env.Warnf("%s is no longer generated, but it was edited and is kept", path)
+		release()
+		return false
+	case keepStale:
+		env.Warnf("%s is no longer generated", path)
+		return false
+	}
+
+	if err := removeFile(outputDir, full); err != nil {
+		env.Errorf("Error removing %s: %v", path, err)
+		return false
+	}
+	env.Println("Removed:", full)
+	release()
+	return true
+}
+
+// removeFile removes a file and the directories below root that become empty.
+func removeFile(root, file string) error {
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	root = filepath.Clean(root)
+	for dir := filepath.Dir(file); dir != root && len(dir) > len(root); dir = filepath.Dir(dir) {
+		// Remove fails for directories that are not empty
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
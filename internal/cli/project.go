@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	generator "github.com/MarmaidTranspiler/Merfolk/internal/CodeTemplateGenerator"
+	"github.com/MarmaidTranspiler/Merfolk/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// projectFlags are the command line flags that override the project configuration.
+type projectFlags struct {
+	configFile        string
+	include           stringList
+	exclude           stringList
+	imports           stringList
+	mirror            bool
+	basePackage       string
+	language          string
+	typesFile         string
+	classTemplate     string
+	interfaceTemplate string
+	interfacePrefix   string
+	getters           bool
+	setters           bool
+	constructors      bool
+}
+
+func addProjectFlags(flags *flag.FlagSet) *projectFlags {
+	p := &projectFlags{}
+	flags.StringVar(&p.configFile, "config", "", "configuration file (default: merfolk.yaml in the working directory)")
+	flags.Var(&p.include, "include", "only read input files matching this glob (repeatable)")
+	flags.Var(&p.exclude, "exclude", "skip input files and directories matching this glob (repeatable)")
+	flags.BoolVar(&p.mirror, "mirror", false, "mirror the folder layout of the input directory in the output directory")
+	flags.StringVar(&p.basePackage, "base-package", "", "package of classes that are not declared in a namespace")
+	flags.StringVar(&p.language, "language", "java", "target language")
+	flags.Var(&p.imports, "import", "add a type to the known-type table used for imports, e.g. Money=org.joda.money.Money (repeatable)")
+	flags.StringVar(&p.typesFile, "types", "", "YAML file with type mappings that extend or override the configured ones")
+	flags.StringVar(&p.classTemplate, "class-template", "", "template file for classes")
+	flags.StringVar(&p.interfaceTemplate, "interface-template", "", "template file for interfaces")
+	flags.StringVar(&p.interfacePrefix, "interface-prefix", "I", "prefix of generated interface names")
+	flags.BoolVar(&p.getters, "getters", true, "generate getters")
+	flags.BoolVar(&p.setters, "setters", true, "generate setters")
+	flags.BoolVar(&p.constructors, "constructors", true, "generate constructors")
+	return p
+}
+
+// load reads the project configuration and overrides it with the flags that were given on the
+// command line and the positional input and output directories.
+func (p *projectFlags) load(flags *flag.FlagSet, positional []string) (*config.Config, error) {
+	cfg, err := config.Discover(p.configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var flagErr error
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "include":
+			cfg.Include = p.include
+		case "exclude":
+			cfg.Exclude = p.exclude
+		case "mirror":
+			cfg.Mirror = p.mirror
+		case "base-package":
+			cfg.BasePackage = p.basePackage
+		case "language":
+			cfg.Language = p.language
+		case "class-template":
+			cfg.Templates.Class = p.classTemplate
+		case "interface-template":
+			cfg.Templates.Interface = p.interfaceTemplate
+		case "interface-prefix":
+			cfg.Naming.InterfacePrefix = p.interfacePrefix
+		case "getters":
+			cfg.Features.Getters = p.getters
+		case "setters":
+			cfg.Features.Setters = p.setters
+		case "constructors":
+			cfg.Features.Constructors = p.constructors
+		case "import":
+			if cfg.Imports == nil {
+				cfg.Imports = make(map[string]string)
+			}
+			for _, entry := range p.imports {
+				name, fullName, found := strings.Cut(entry, "=")
+				if !found {
+					flagErr = fmt.Errorf("invalid import %q, expected Type=package.Type", entry)
+					return
+				}
+				cfg.Imports[name] = fullName
+			}
+		case "types":
+			types, err := loadTypeMappings(p.typesFile)
+			if err != nil {
+				flagErr = fmt.Errorf("error reading type mappings: %w", err)
+				return
+			}
+			if cfg.Types == nil {
+				cfg.Types = make(map[string]generator.TypeMapping)
+			}
+			for name, mapping := range types {
+				cfg.Types[name] = mapping
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	if len(positional) > 0 {
+		cfg.Input = positional[0]
+	}
+	if len(positional) > 1 {
+		cfg.Output = positional[1]
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// loadTypeMappings reads type mappings from a YAML file of the form
+//
+//	Money:
+//	  target: java.math.BigDecimal
+//	  default: BigDecimal.ZERO
+//	  value: true
+func loadTypeMappings(file string) (map[string]generator.TypeMapping, error) {
+	types := make(map[string]generator.TypeMapping)
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(content, &types); err != nil {
+		return nil, err
+	}
+	return types, nil
+}
@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+	"github.com/MarmaidTranspiler/Merfolk/internal/reverse"
+)
+
+type reverseFlags struct {
+	lang    string
+	output  string
+	exclude stringList
+	entry   string
+	depth   int
+	actor   string
+}
+
+func init() {
+	register(&Command{
+		Name:    "reverse",
+		Args:    "--lang <language> [--entry <Class.method>] <file or dir>...",
+		Summary: "create a class or sequence diagram from existing source code",
+		Description: `
+Reverse parses the source files given as arguments, or all source files below the given
+directories, and prints one Mermaid class diagram of the declared types. Types are declared in
+namespaces named after their packages; inheritance and the associations given by fields are
+drawn as relationships. The diagram can be converted into code again.
+
+With --entry, a sequence diagram of the calls made by the given method is created instead. The
+method body is walked statically: calls on fields, parameters and local variables of the
+declared types are followed into the called methods up to --depth calls deep, if-statements
+become alt blocks and loops loop blocks. Sequence diagrams are supported for Java sources.
+
+The diagram is printed as a Markdown document. With --output it is written to a file, as
+Markdown, AsciiDoc or a plain Mermaid diagram depending on the file extension.
+
+Supported languages: ` + strings.Join(reverse.Languages(), ", "),
+		Flags: func(flags *flag.FlagSet) any {
+			f := &reverseFlags{}
+			flags.StringVar(&f.lang, "lang", "", "language of the sources: "+strings.Join(reverse.Languages(), ", "))
+			flags.StringVar(&f.output, "output", "", "file to write the diagram to (default: standard output)")
+			flags.Var(&f.exclude, "exclude", "glob pattern of files and directories to skip, may be repeated")
+			flags.StringVar(&f.entry, "entry", "", "method to create a sequence diagram for, as Class.method")
+			flags.IntVar(&f.depth, "depth", 10, "how deep calls are followed with --entry")
+			flags.StringVar(&f.actor, "actor", "user", "participant that calls the --entry method")
+			return f
+		},
+		Run: runReverse,
+	})
+}
+
+func runReverse(env *Env, _ *flag.FlagSet, value any, args []string) error {
+	f := value.(*reverseFlags)
+	if f.lang == "" {
+		return usagef("specify the language of the sources with --lang")
+	}
+	language := reverse.Lookup(f.lang)
+	if language == nil {
+		return usagef("unsupported language %q, expected one of %s", f.lang, strings.Join(reverse.Languages(), ", "))
+	}
+	if f.entry != "" && !language.Sequences {
+		return usagef("sequence diagrams are not supported for %s sources", language.Name)
+	}
+	if f.depth < 1 {
+		return usagef("--depth must be at least 1")
+	}
+	if f.output != "" && reader.SyntaxOf(f.output) == reader.PlantUML {
+		return usagef("diagrams are written as Mermaid, not PlantUML: %s", f.output)
+	}
+	if len(args) == 0 {
+		return usagef("specify the source files or directories")
+	}
+
+	var sources []reverse.Source
+	for _, arg := range args {
+		files, err := findSourceFiles(arg, language.Extension, f.exclude)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			sources = append(sources, reverse.Source{Path: file, Content: content})
+		}
+	}
+	if len(sources) == 0 {
+		return errors.New("no " + language.Extension + " files found")
+	}
+
+	types, err := language.Parse(sources)
+	if err != nil {
+		// The types of the other files are still written
+		env.Errorf("%v", err)
+	}
+	report := func(w reverse.Warning) {
+		env.Warnf("%s", w)
+	}
+	var diagram string
+	if f.entry == "" {
+		diagram = reverse.ClassDiagram(types, report)
+	} else {
+		options := reverse.SequenceOptions{Actor: f.actor, MaxDepth: f.depth}
+		if diagram, err = reverse.SequenceDiagram(types, f.entry, options, report); err != nil {
+			return err
+		}
+	}
+
+	if f.output == "" {
+		env.Printf("%s", embedDiagram(diagram, reader.Markdown))
+		return nil
+	}
+	return os.WriteFile(f.output, []byte(embedDiagram(diagram, reader.SyntaxOf(f.output))), 0o644)
+}
+
+// findSourceFiles returns the file named by arg, or the files with the given extension below
+// the directory arg in lexical order. Hidden directories and files matching one of the exclude
+// patterns are skipped.
+func findSourceFiles(arg, extension string, exclude []string) ([]string, error) {
+	info, err := os.Stat(arg)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{arg}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(arg, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(arg, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+
+		if entry.IsDir() {
+			if strings.HasPrefix(entry.Name(), ".") || matchAny(exclude, rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(rel) == extension && !matchAny(exclude, rel) {
+			files = append(files, file)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// embedDiagram returns a document of the given syntax that consists of the diagram.
+func embedDiagram(diagram string, syntax reader.Syntax) string {
+	switch syntax {
+	case reader.Mermaid:
+		return diagram
+	case reader.AsciiDoc:
+		return "[mermaid]\n----\n" + diagram + "----\n"
+	default:
+		return "```mermaid\n" + diagram + "```\n"
+	}
+}
@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+	"github.com/MarmaidTranspiler/Merfolk/internal/reverse"
+	"github.com/MarmaidTranspiler/Merfolk/internal/semantic"
+)
+
+func init() {
+	register(&Command{
+		Name:    "roundtrip",
+		Args:    "[<input dir>]",
+		Summary: "convert the diagrams to code and back and report what was lost",
+		Description: `
+Roundtrip converts all diagrams below the input directory into Java code in memory, reverse
+engineers the code into diagrams again and compares the meaning of both: classes, members and
+their types, relationships, and the calls of every sequence diagram, which is extracted again
+from the generated body of the method its first message calls. Every difference is listed, and
+roundtrip exits with a non-zero code if there is one.
+
+Differences show the constructs that the conversion does not preserve. Members that the
+generator adds, like getters and setters, are differences as well; disable them with
+--getters=false and --setters=false to compare only what the diagrams declare.`,
+		Flags: func(flags *flag.FlagSet) any { return addProjectFlags(flags) },
+		Run:   runRoundtrip,
+	})
+}
+
+func runRoundtrip(env *Env, flags *flag.FlagSet, value any, args []string) error {
+	if len(args) > 1 {
+		return usagef("too many arguments")
+	}
+
+	cfg, err := value.(*projectFlags).load(flags, args)
+	if err != nil {
+		return fmt.Errorf("error reading configuration: %w", err)
+	}
+	if cfg.Input == "" {
+		return usagef("specify the input directory")
+	}
+
+	// Progress messages and the log of the connector are not of interest, only the diagnostics
+	quiet := &Env{Stdout: io.Discard, Stderr: env.Stderr}
+	defer discardConnectorLog()()
+	cache := make(diagramCache)
+	p, err := buildProject(quiet, cfg, cache)
+	if err != nil {
+		return err
+	}
+	files := renderProject(quiet, cfg, p)
+	env.errors += quiet.errors
+
+	original := semantic.NewModel()
+	sources := make([]string, 0, len(cache))
+	for source := range cache {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	for _, source := range sources {
+		for _, diagram := range cache[source].Diagrams {
+			if !diagram.Options.Skip {
+				original.Add(diagram)
+			}
+		}
+	}
+
+	// Reverse engineer the generated code
+	differences := 0
+	var types []*reverse.Type
+	for _, file := range files {
+		if path.Ext(file.Path) != ".java" {
+			continue
+		}
+		parsed, err := reverse.ParseJava(file.Path, file.Content)
+		if err != nil {
+			env.Printf("generated code does not parse: %v\n", err)
+			differences++
+			continue
+		}
+		types = append(types, parsed...)
+	}
+	report := func(w reverse.Warning) {
+		env.Warnf("%s", w)
+	}
+
+	regenerated := semantic.NewModel()
+	diagrams := []string{reverse.ClassDiagram(types, report)}
+	entries := make([]string, 0, len(original.Sequences))
+	for entry := range original.Sequences {
+		entries = append(entries, entry)
+	}
+	sort.Strings(entries)
+	for _, entry := range entries {
+		// Sequences whose entry method was not generated are missing in the comparison
+		if diagram, err := reverse.SequenceDiagram(types, entry, reverse.SequenceOptions{}, report); err == nil {
+			diagrams = append(diagrams, diagram)
+		}
+	}
+	for _, text := range diagrams {
+		diagram, err := reader.ParseDiagram(text)
+		if err != nil {
+			return fmt.Errorf("error reading the reverse engineered diagram: %w", err)
+		}
+		regenerated.Add(*diagram)
+	}
+
+	for _, change := range semantic.Compare(original, regenerated) {
+		env.Println(change)
+		differences++
+	}
+	env.Printf("Round trip of %d diagrams: %d differences\n", p.Diagrams, differences)
+	if differences > 0 {
+		return fmt.Errorf("%d differences", differences)
+	}
+	return nil
+}
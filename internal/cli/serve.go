@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/server"
+)
+
+type serveFlags struct {
+	*projectFlags
+	addr    string
+	maxBody int64
+	timeout time.Duration
+}
+
+func init() {
+	register(&Command{
+		Name:    "serve",
+		Summary: "run an HTTP server that generates code for posted diagrams",
+		Description: `
+Serve starts an HTTP server with a JSON API for the code generation, for wikis and other tools
+that want to generate code without installing merfolk.
+
+  GET  /healthz       reports that the server is running
+  POST /v1/generate   generates the Java files of the posted documents
+
+The generate endpoint accepts a JSON object with the documents and options, or the raw text of a
+single document with the Content-Type text/markdown, text/asciidoc, text/vnd.mermaid or
+text/x-plantuml. It answers with the generated files and the diagnostics as JSON, or as zip
+archive if the request has the query parameter format=zip or accepts application/zip.
+
+The project configuration and flags supply the default options; requests can override the base
+package, the interface prefix, the generated features and the type mappings. The server stops on
+an interrupt after the running requests have finished.`,
+		Flags: func(flags *flag.FlagSet) any {
+			f := &serveFlags{projectFlags: addProjectFlags(flags)}
+			flags.StringVar(&f.addr, "addr", ":8080", "address to listen on")
+			flags.Int64Var(&f.maxBody, "max-body", server.DefaultLimits.MaxBodyBytes, "maximum size of a request body in bytes")
+			flags.DurationVar(&f.timeout, "timeout", server.DefaultLimits.Timeout, "maximum time to handle a request")
+			return f
+		},
+		Run: runServe,
+	})
+}
+
+func runServe(env *Env, flags *flag.FlagSet, value any, args []string) error {
+	f := value.(*serveFlags)
+	if len(args) > 0 {
+		return usagef("too many arguments")
+	}
+
+	cfg, err := f.load(flags, nil)
+	if err != nil {
+		return fmt.Errorf("error reading configuration: %w", err)
+	}
+
+	// Every request runs the connector, its log would mix the progress of concurrent requests
+	defer discardConnectorLog()()
+	listener, err := net.Listen("tcp", f.addr)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return serve(ctx, env, listener, server.New(cfg, server.Limits{MaxBodyBytes: f.maxBody, Timeout: f.timeout}, version()))
+}
+
+// serve handles requests on listener until ctx is cancelled and then waits for the running
+// requests to finish.
+func serve(ctx context.Context, env *Env, listener net.Listener, s *server.Server) error {
+	httpServer := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		done <- httpServer.Shutdown(shutdownCtx)
+	}()
+
+	env.Printf("Listening on http://%s\n", listener.Addr())
+	if err := httpServer.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	if err := <-done; err != nil {
+		return err
+	}
+	env.Println("Server stopped")
+	return nil
+}
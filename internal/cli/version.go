@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"flag"
+	"runtime"
+	"runtime/debug"
+)
+
+// Version is the version of merfolk, set at build time with
+// -ldflags "-X github.com/MarmaidTranspiler/Merfolk/internal/cli.Version=v1.2.3".
+var Version = ""
+
+func init() {
+	register(&Command{
+		Name:    "version",
+		Summary: "print the version of merfolk",
+		Run:     runVersion,
+	})
+}
+
+func runVersion(env *Env, _ *flag.FlagSet, _ any, args []string) error {
+	if len(args) > 0 {
+		return usagef("unexpected arguments")
+	}
+	env.Printf("merfolk %s %s/%s\n", version(), runtime.GOOS, runtime.GOARCH)
+	return nil
+}
+
+// version returns Version or, if it was not set at build time, the module version.
+func version() string {
+	if Version != "" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/config"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watch converts the project and then regenerates the code whenever files below the input
+// directory change, until ctx is done. Changes are collected until no further change happened
+// for the debounce interval. Only the changed files are parsed again and only generated files
+// whose content changed are rewritten. Errors are printed but do not end watch mode.
+func watch(ctx context.Context, env *Env, cfg *config.Config, debounce time.Duration) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := watchTree(watcher, cfg.Input, cfg.Exclude); err != nil {
+		return err
+	}
+
+	cache := make(diagramCache)
+	regenerate := func() {
+		// Every run reports its own errors, they must not end watch mode
+		run := &Env{Stdout: env.Stdout, Stderr: env.Stderr}
+		p, err := buildProject(run, cfg, cache)
+		if err != nil {
+			run.Errorf("%v", err)
+			return
+		}
+		files := renderProject(run, cfg, p)
+		stats, err := writeGeneratedFiles(run, cfg.Output, files, writeOptions{})
+		if err != nil {
+			run.Errorf("%v", err)
+			return
+		}
+
+		status := "ok"
+		if run.Failed() {
+			status = "with errors"
+		}
+		removed := ""
+		if stats.removed > 0 {
+			removed = fmt.Sprintf(", %d removed", stats.removed)
+		}
+		env.Printf("[%s] %d files generated, %d updated%s, %s\n", time.Now().Format("15:04:05"), len(files), stats.written, removed, status)
+	}
+
+	regenerate()
+	env.Println("Watching", cfg.Input, "for changes, press Ctrl+C to stop")
+
+	changed := make(map[string]bool)
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			rel, err := filepath.Rel(cfg.Input, event.Name)
+			if err != nil {
+				continue
+			}
+			rel = filepath.ToSlash(rel)
+
+			// New directories are watched as well, their files are found by the next run
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if strings.HasPrefix(info.Name(), ".") || matchAny(cfg.Exclude, rel) {
+						continue
+					}
+					if err := watchTree(watcher, event.Name, nil); err != nil {
+						env.Errorf("Watch error: %v", err)
+					}
+					changed[rel] = true
+					timer.Reset(debounce)
+					continue
+				}
+			}
+
+			// Removed or renamed directories have no extension either
+			if !inputExtensions[strings.ToLower(path.Ext(rel))] && !event.Has(fsnotify.Remove) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+			changed[rel] = true
+			timer.Reset(debounce)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			env.Errorf("Watch error: %v", err)
+
+		case <-timer.C:
+			for rel := range changed {
+				// A changed directory invalidates all files below it
+				for source := range cache {
+					if source == rel || strings.HasPrefix(source, rel+"/") {
+						delete(cache, source)
+					}
+				}
+			}
+			changed = make(map[string]bool)
+			regenerate()
+		}
+	}
+}
+
+// watchTree adds root and all directories below it to watcher. Hidden and excluded
+// directories are skipped like in findInputFiles.
+func watchTree(watcher *fsnotify.Watcher, root string, exclude []string) error {
+	return filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel != "." && (strings.HasPrefix(entry.Name(), ".") || matchAny(exclude, rel)) {
+			return filepath.SkipDir
+		}
+		return watcher.Add(filePath)
+	})
+}
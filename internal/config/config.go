@@ -0,0 +1,204 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	generator "github.com/MarmaidTranspiler/Merfolk/internal/CodeTemplateGenerator"
+	"gopkg.in/yaml.v3"
+)
+
+// FileNames are the names under which the project configuration is found in the working directory.
+var FileNames = []string{"merfolk.yaml", "merfolk.yml"}
+
+// Config is the project configuration, read from merfolk.yaml:
+//
+//	input: docs
+//	exclude: ["drafts/**"]
+//	output: src/main/java
+//	basePackage: com.acme
+//	types:
+//	  Money:
+//	    target: java.math.BigDecimal
+//	    default: BigDecimal.ZERO
+//	    value: true
+//	features:
+//	  setters: false
+type Config struct {
+	// Input is the directory that is searched for diagrams.
+	Input string `yaml:"input"`
+	// Include and Exclude select the input files by glob, see the convert command.
+	Include []string `yaml:"include,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty"`
+	// Output is the directory the generated code is written to.
+	Output string `yaml:"output"`
+	// Mirror places generated files in the folder layout of their source files.
+	Mirror bool `yaml:"mirror"`
+	// Language is the target language, only "java" is supported.
+	Language string `yaml:"language"`
+	// BasePackage is the package of classes that are not declared in a namespace.
+	BasePackage string `yaml:"basePackage,omitempty"`
+	// Templates overrides the built-in templates.
+	Templates Templates `yaml:"templates,omitempty"`
+	// Types extends or overrides the built-in type mappings.
+	Types map[string]generator.TypeMapping `yaml:"types,omitempty"`
+	// Imports adds simple type names and their fully qualified names to the known-type table.
+	Imports map[string]string `yaml:"imports,omitempty"`
+	// Naming holds naming conventions of the generated code.
+	Naming Naming `yaml:"naming"`
+	// Features turns parts of the generated code on and off.
+	Features Features `yaml:"features"`
+	// Lint configures the rules of the lint command.
+	Lint Lint `yaml:"lint,omitempty"`
+
+	// File is the configuration file the settings were read from, empty if there is none.
+	File string `yaml:"-"`
+}
+
+// Templates are paths of template files that replace the built-in templates.
+type Templates struct {
+	Class     string `yaml:"class,omitempty"`
+	Interface string `yaml:"interface,omitempty"`
+}
+
+// Naming holds naming conventions of the generated code.
+type Naming struct {
+	// InterfacePrefix is prepended to the names of generated interfaces.
+	InterfacePrefix string `yaml:"interfacePrefix"`
+}
+
+// Features turns parts of the generated code on and off.
+type Features struct {
+	Getters      bool `yaml:"getters"`
+	Setters      bool `yaml:"setters"`
+	Constructors bool `yaml:"constructors"`
+}
+
+// Lint configures the rules of the lint command.
+type Lint struct {
+	// Rules maps rule names to their severity: error, warning, info or off.
+	Rules map[string]string `yaml:"rules,omitempty"`
+}
+
+// Default returns the configuration used if no configuration file exists.
+func Default() *Config {
+	return &Config{
+		Language: "java",
+		Naming:   Naming{InterfacePrefix: "I"},
+		Features: Features{Getters: true, Setters: true, Constructors: true},
+	}
+}
+
+// Find returns the path of the configuration file in dir, or an empty string if there is none.
+func Find(dir string) (string, error) {
+	for _, name := range FileNames {
+		file := filepath.Join(dir, name)
+		if _, err := os.Stat(file); err == nil {
+			return file, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// Load reads a configuration file. Settings missing in the file keep their default values and
+// relative paths are resolved against the directory of the file.
+func Load(file string) (*Config, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	cfg.File = file
+
+	dir := filepath.Dir(file)
+	for _, path := range []*string{&cfg.Input, &cfg.Output, &cfg.Templates.Class, &cfg.Templates.Interface} {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+
+	return cfg, nil
+}
+
+// Discover loads the configuration file given by file or, if file is empty, the one found in
+// the working directory. Without a configuration file the default configuration is returned.
+func Discover(file string) (*Config, error) {
+	if file == "" {
+		found, err := Find(".")
+		if err != nil {
+			return nil, err
+		}
+		if found == "" {
+			return Default(), nil
+		}
+		file = found
+	}
+	return Load(file)
+}
+
+// Validate reports settings that cannot be used for generation.
+func (cfg *Config) Validate() error {
+	if cfg.Language != "" && !strings.EqualFold(cfg.Language, "java") {
+		return fmt.Errorf("unsupported target language %q", cfg.Language)
+	}
+	for name, fullName := range cfg.Imports {
+		if name == "" || !strings.Contains(fullName, ".") {
+			return fmt.Errorf("invalid import %s: %s, expected a fully qualified name", name, fullName)
+		}
+	}
+	return nil
+}
+
+// TypeMap returns the built-in type mappings extended by the configured ones.
+func (cfg *Config) TypeMap() generator.TypeMap {
+	return generator.NewTypeMap(cfg.Types)
+}
+
+// KnownTypes returns the table of library types used to generate imports: the built-in
+// table, the imports of the type mappings and the configured imports.
+func (cfg *Config) KnownTypes() map[string]string {
+	knownTypes := make(map[string]string)
+	for name, fullName := range generator.KnownTypes {
+		knownTypes[name] = fullName
+	}
+	for name, fullName := range cfg.TypeMap().Imports() {
+		knownTypes[name] = fullName
+	}
+	for name, fullName := range cfg.Imports {
+		knownTypes[name] = fullName
+	}
+	return knownTypes
+}
+
+// GeneratorOptions returns the options for the code generator.
+func (cfg *Config) GeneratorOptions() generator.Options {
+	return generator.Options{
+		Types:            cfg.TypeMap(),
+		InterfacePrefix:  cfg.Naming.InterfacePrefix,
+		SkipGetters:      !cfg.Features.Getters,
+		SkipSetters:      !cfg.Features.Setters,
+		SkipConstructors: !cfg.Features.Constructors,
+	}
+}
+
+// String returns the configuration in the YAML format of the configuration file.
+func (cfg *Config) String() string {
+	content, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err.Error()
+	}
+	return string(content)
+}
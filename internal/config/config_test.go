@@ -0,0 +1,85 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "merfolk.yaml")
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestLoad(t *testing.T) {
+	file := writeConfig(t, `
+input: docs
+output: /abs/out
+basePackage: com.acme
+types:
+  Money:
+    target: java.math.BigDecimal
+    default: BigDecimal.ZERO
+    value: true
+features:
+  setters: false
+`)
+
+	cfg, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Dir(file)
+	if cfg.Input != filepath.Join(dir, "docs") {
+		t.Errorf("input not resolved against the config file: %s", cfg.Input)
+	}
+	if cfg.Output != "/abs/out" {
+		t.Errorf("absolute output changed: %s", cfg.Output)
+	}
+	if cfg.Language != "java" || cfg.Naming.InterfacePrefix != "I" {
+		t.Errorf("defaults lost: %+v", cfg)
+	}
+	if !cfg.Features.Getters || cfg.Features.Setters || !cfg.Features.Constructors {
+		t.Errorf("unexpected features: %+v", cfg.Features)
+	}
+
+	options := cfg.GeneratorOptions()
+	if !options.SkipSetters || options.SkipGetters {
+		t.Errorf("unexpected generator options: %+v", options)
+	}
+	if got := options.Types.JavaType("Money"); got != "BigDecimal" {
+		t.Errorf("Money mapped to %s", got)
+	}
+	if got := cfg.KnownTypes()["BigDecimal"]; got != "java.math.BigDecimal" {
+		t.Errorf("BigDecimal imported from %q", got)
+	}
+}
+
+func TestLoadUnknownField(t *testing.T) {
+	file := writeConfig(t, "outptu: src\n")
+	_, err := Load(file)
+	if err == nil || !strings.Contains(err.Error(), "outptu") {
+		t.Errorf("expected error for unknown field, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.Language = "kotlin"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for unsupported language")
+	}
+
+	cfg = Default()
+	cfg.Imports = map[string]string{"Money": "Money"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for import without package")
+	}
+}
@@ -5,86 +5,115 @@ import (
 	"fmt"
 	generator "github.com/MarmaidTranspiler/Merfolk/internal/CodeTemplateGenerator"
 	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
-	"path/filepath"
+	"io"
+	"os"
 	"strings"
 )
 
-// TransformClassDiagram transforms a Mermaid class diagram into code structures (classes and interfaces)
-// and then generates Java code for those interfaces.
-func TransformClassDiagram(
-	classDiagram *reader.ClassDiagram,
-	classTemplatePath, interfaceTemplatePath, outputDir string,
-) (map[string]*generator.Class, map[string]*generator.Interface, error) {
+// Log receives the progress messages and warnings of the transformations. Programs that use
+// standard output for other purposes, like the language server, redirect or discard them.
+var Log io.Writer = os.Stdout
+
+// TransformClassDiagram transforms a Mermaid class diagram into code structures (classes and interfaces).
+// Classes declared inside a namespace are placed in the package of the same name.
+// Types are mapped to Java with types, a nil TypeMap uses the built-in mappings.
+func TransformClassDiagram(classDiagram *reader.ClassDiagram, types generator.TypeMap) (*Model, error) {
 
 	if classDiagram == nil {
-		fmt.Println("TransformClassDiagram: class diagram is nil")
-		return nil, nil, errors.New("class diagram is nil")
+		fmt.Fprintln(Log, "TransformClassDiagram: class diagram is nil")
+		return nil, errors.New("class diagram is nil")
 	}
 
-	fmt.Println("TransformClassDiagram: starting transformation of class diagram")
+	fmt.Fprintln(Log, "TransformClassDiagram: starting transformation of class diagram")
 
-	classes := make(map[string]*generator.Class)
-	interfaces := make(map[string]*generator.Interface)
+	model := NewModel()
+	model.Types = types
 
 	for _, instruction := range classDiagram.Instructions {
-		if instruction.Member == nil {
+		if instruction.Namespace != nil {
+			for _, declaration := range instruction.Namespace.Classes {
+				processClassDeclaration(declaration, model, instruction.Namespace.Name)
+			}
 			continue
 		}
 
-		className := instruction.Member.Class
-		isInterface := instruction.Member.Operation == nil
-
-		// Ensure class or interface entry is created
-		if isInterface {
-			ensureInterfaceEntry(interfaces, className)
-		} else {
-			ensureClassEntry(classes, className)
+		if instruction.Class != nil {
+			processClassDeclaration(instruction.Class, model, "")
+			continue
 		}
 
-		if instruction.Member.Attribute != nil {
-			processClassDiagramAttribute(instruction.Member, classes, interfaces, className, isInterface)
-		} else if instruction.Member.Operation != nil {
-			processClassDiagramOperation(instruction.Member, classes, interfaces, className)
+		if instruction.Member == nil {
+			continue
 		}
+
+		processClassMember(instruction.Member, model)
 	}
 
-	// Generate Java code for interfaces
-	fmt.Println("TransformClassDiagram: generating Java code for interfaces")
-	for _, iface := range interfaces {
-		fmt.Printf("TransformClassDiagram: generating interface %s\n", iface.InterfaceName)
-		err := generator.GenerateJavaCode(*iface, filepath.Clean(outputDir)+"/", iface.InterfaceName, interfaceTemplatePath)
-		if err != nil {
-			fmt.Printf("TransformClassDiagram: failed to generate interface %s: %v\n", iface.InterfaceName, err)
-			return nil, nil, fmt.Errorf("failed to generate interface %s: %w", iface.InterfaceName, err)
+	fmt.Fprintln(Log, "TransformClassDiagram: transformation completed successfully")
+	return model, nil
+}
+
+// processClassDeclaration handles "class Name { ... }", optionally inside a namespace.
+func processClassDeclaration(declaration *reader.ClassDeclaration, model *Model, namespace string) {
+	ensureClassEntry(model, declaration.Name)
+
+	for _, body := range declaration.Members {
+		processClassMember(&reader.ClassMember{
+			Class:      declaration.Name,
+			Visibility: body.Visibility,
+			Operation:  body.Operation,
+			Attribute:  body.Attribute,
+		}, model)
+	}
+
+	if namespace != "" {
+		model.Classes[declaration.Name].Package = namespace
+		if iface, exists := model.Interfaces[declaration.Name]; exists {
+			iface.Package = namespace
 		}
 	}
+}
+
+func processClassMember(member *reader.ClassMember, model *Model) {
+	className := member.Class
+	isInterface := member.Operation == nil
 
-	fmt.Println("TransformClassDiagram: transformation completed successfully")
-	return classes, interfaces, nil
+	// Ensure class or interface entry is created
+	if isInterface {
+		ensureInterfaceEntry(model, className)
+	} else {
+		ensureClassEntry(model, className)
+	}
+
+	if member.Attribute != nil {
+		processClassDiagramAttribute(member, model.Classes, model.Interfaces, className, isInterface, model.Types)
+	} else if member.Operation != nil {
+		processClassDiagramOperation(member, model.Classes, model.Interfaces, className, model.Types)
+	}
 }
 
-func ensureInterfaceEntry(interfaces map[string]*generator.Interface, className string) {
-	if _, exists := interfaces[className]; !exists {
-		interfaces[className] = &generator.Interface{
+func ensureInterfaceEntry(model *Model, className string) {
+	if _, exists := model.Interfaces[className]; !exists {
+		model.SetInterface(&generator.Interface{
 			InterfaceName:      className,
 			Inherits:           []string{},
 			AbstractAttributes: []generator.Attribute{},
 			AbstractMethods:    []generator.Method{},
-		}
-		fmt.Printf("TransformClassDiagram: created new interface entry for %s\n", className)
+		})
+		fmt.Fprintf(Log, "TransformClassDiagram: created new interface entry for %s\n", className)
 	}
 }
 
-func ensureClassEntry(classes map[string]*generator.Class, className string) {
-	if _, exists := classes[className]; !exists {
-		classes[className] = &generator.Class{
+func ensureClassEntry(model *Model, className string) {
+	if _, exists := model.Classes[className]; !exists {
+		model.SetClass(&generator.Class{
 			ClassName:   className,
 			Abstraction: []string{},
 			Inherits:    "",
 			Attributes:  []generator.Attribute{},
 			Methods:     []generator.Method{},
-		}
-		fmt.Printf("TransformClassDiagram: created new class entry for %s\n", className)
+		})
+		fmt.Fprintf(Log, "TransformClassDiagram: created new class entry for %s\n", className)
 	}
 }
 
@@ -94,33 +123,34 @@ func processClassDiagramAttribute(
 	interfaces map[string]*generator.Interface,
 	className string,
 	isInterface bool,
+	types generator.TypeMap,
 ) {
-	fmt.Println(isPrimitiveType(member.Attribute.Type), member.Attribute.Name)
+	fmt.Fprintln(Log, types.IsValueType(member.Attribute.Type), member.Attribute.Name)
 
 	attr := generator.Attribute{
 		AccessModifier:  parseVisibility(member.Visibility),
 		Name:            member.Attribute.Name,
-		Type:            member.Attribute.Type,
+		Type:            javaTypeName(member.Attribute.Type, types),
 		IsClassVariable: false,
 		IsConstant:      false,
-		IsObject:        !isPrimitiveType(member.Attribute.Type),
+		IsObject:        !types.IsValueType(member.Attribute.Type),
 		Value: func() string {
-			if isPrimitiveType(member.Attribute.Type) {
-				return defaultZero(member.Attribute.Type) // Replace with appropriate default
+			if types.IsValueType(member.Attribute.Type) {
+				return types.DefaultValue(member.Attribute.Type) // Replace with appropriate default
 			}
 			return fmt.Sprintf("new %s()", member.Attribute.Name)
 		}(),
 	}
-	fmt.Println(attr.Value.(string))
+	fmt.Fprintln(Log, attr.Value.(string))
 
 	if isInterface {
 		interfaces[className].AbstractAttributes = append(interfaces[className].AbstractAttributes, attr)
 		// Also add to class as a normal attribute to maintain logic from original code
 		classes[className].Attributes = append(classes[className].Attributes, attr)
-		fmt.Printf("TransformClassDiagram: added attribute %s to interface %s\n", attr.Name, className)
+		fmt.Fprintf(Log, "TransformClassDiagram: added attribute %s to interface %s\n", attr.Name, className)
 	} else {
 		classes[className].Attributes = append(classes[className].Attributes, attr)
-		fmt.Printf("TransformClassDiagram: added attribute %s to class %s\n", attr.Name, className)
+		fmt.Fprintf(Log, "TransformClassDiagram: added attribute %s to class %s\n", attr.Name, className)
 	}
 }
 
@@ -129,12 +159,13 @@ func processClassDiagramOperation(
 	classes map[string]*generator.Class,
 	interfaces map[string]*generator.Interface,
 	className string,
+	types generator.TypeMap,
 ) {
 	method := generator.Method{
 		AccessModifier: parseVisibility(member.Visibility),
 		Name:           member.Operation.Name,
 		IsStatic:       false,
-		ReturnType:     member.Operation.Return,
+		ReturnType:     javaTypeName(member.Operation.Return, types),
 		Parameters:     []generator.Attribute{},
 		MethodBody:     []generator.Body{},
 		ReturnValue:    fmt.Sprintf("%sResult", member.Operation.Name),
@@ -143,7 +174,7 @@ func processClassDiagramOperation(
 	for _, param := range member.Operation.Parameters {
 		method.Parameters = append(method.Parameters, generator.Attribute{
 			Name: param.Name,
-			Type: param.Type,
+			Type: javaTypeName(param.Type, types),
 		})
 		// If parameter type corresponds to an existing class, add a dependency attribute
 		if _, exists := classes[param.Type]; exists {
@@ -154,16 +185,16 @@ func processClassDiagramOperation(
 				Type:            param.Type,
 				IsClassVariable: false,
 				IsConstant:      false,
-				IsObject:        isPrimitiveType(param.Type),
+				IsObject:        types.IsValueType(param.Type),
 				Value: func() string {
-					if isPrimitiveType(param.Type) {
-						return defaultZero(param.Type) // Replace with appropriate default
+					if types.IsValueType(param.Type) {
+						return types.DefaultValue(param.Type) // Replace with appropriate default
 					}
 					return fmt.Sprintf("new %s()", param.Type)
 				}(),
 			}
 			classes[className].Attributes = append(classes[className].Attributes, classVar)
-			fmt.Printf("TransformClassDiagram: added class dependency attribute %sInstance to class %s\n", param.Type, className)
+			fmt.Fprintf(Log, "TransformClassDiagram: added class dependency attribute %sInstance to class %s\n", param.Type, className)
 		}
 	}
 
@@ -172,26 +203,24 @@ func processClassDiagramOperation(
 	if ifaceExists && method.ReturnType == "" {
 		// In interfaces, methods have no body, just abstract definition
 		iface.AbstractMethods = append(iface.AbstractMethods, method)
-		fmt.Printf("TransformClassDiagram: added abstract method %s to interface %s\n", method.Name, className)
+		fmt.Fprintf(Log, "TransformClassDiagram: added abstract method %s to interface %s\n", method.Name, className)
 	} else if clsExists {
 		cls.Methods = append(cls.Methods, method)
-		fmt.Printf("TransformClassDiagram: added method %s to class %s\n", method.Name, className)
+		fmt.Fprintf(Log, "TransformClassDiagram: added method %s to class %s\n", method.Name, className)
 	}
 }
 
-// TransformSequenceDiagram transforms a Mermaid sequence diagram into code instructions within the classes.
-func TransformSequenceDiagram(
-	sequenceDiagram *reader.SequenceDiagram,
-	classes map[string]*generator.Class,
-	classTemplatePath, outputDir string,
-) error {
+// TransformSequenceDiagram transforms a Mermaid sequence diagram into code instructions within the classes of the model.
+func TransformSequenceDiagram(sequenceDiagram *reader.SequenceDiagram, model *Model) error {
 
 	if sequenceDiagram == nil {
-		fmt.Println("TransformSequenceDiagram: sequence diagram is nil")
+		fmt.Fprintln(Log, "TransformSequenceDiagram: sequence diagram is nil")
 		return errors.New("sequence diagram is nil")
 	}
 
-	fmt.Println("TransformSequenceDiagram: starting transformation of sequence diagram")
+	fmt.Fprintln(Log, "TransformSequenceDiagram: starting transformation of sequence diagram")
+
+	classes := model.Classes
 
 	type methodContext struct {
 		class  *generator.Class
@@ -242,11 +271,11 @@ func TransformSequenceDiagram(
 
 	findOrCreateDummyClass := func(name string) *generator.Class {
 		if classes[name] == nil {
-			classes[name] = &generator.Class{
+			model.SetClass(&generator.Class{
 				ClassName:  name,
 				Attributes: []generator.Attribute{},
 				Methods:    []generator.Method{},
-			}
+			})
 		}
 		return classes[name]
 	}
@@ -329,7 +358,7 @@ func TransformSequenceDiagram(
 	addInstructionToCurrentContext := func(b generator.Body) {
 		_, m := getCurrentContext()
 		if m == nil {
-			fmt.Println("Warning: Instruction outside of method context, skipping line.")
+			fmt.Fprintln(Log, "Warning: Instruction outside of method context, skipping line.")
 			return
 		}
 
@@ -361,7 +390,7 @@ func TransformSequenceDiagram(
 			alt := instruction.Alt
 			currClass, currMethod := getCurrentContext()
 			if currMethod == nil {
-				fmt.Println("Warning: 'alt' encountered outside of any method context. Ignoring.")
+				fmt.Fprintln(Log, "Warning: 'alt' encountered outside of any method context. Ignoring.")
 				continue
 			}
 			if !currentConditional.active {
@@ -375,7 +404,7 @@ func TransformSequenceDiagram(
 
 					startElseBlock(alt.Definition)
 				} else {
-					fmt.Println("Warning: Multiple else blocks not supported. Ignoring extra alt.")
+					fmt.Fprintln(Log, "Warning: Multiple else blocks not supported. Ignoring extra alt.")
 				}
 			}
 			continue
@@ -383,11 +412,11 @@ func TransformSequenceDiagram(
 
 		if instruction.Else != nil {
 			if !currentConditional.active {
-				fmt.Println("Warning: 'else' encountered without an active 'alt' block. Ignoring.")
+				fmt.Fprintln(Log, "Warning: 'else' encountered without an active 'alt' block. Ignoring.")
 				continue
 			}
 			if currentConditional.seenElse {
-				fmt.Println("Warning: Multiple 'else' blocks encountered. Ignoring extra 'else'.")
+				fmt.Fprintln(Log, "Warning: Multiple 'else' blocks encountered. Ignoring extra 'else'.")
 				continue
 			}
 			// Switch to else block
@@ -399,7 +428,7 @@ func TransformSequenceDiagram(
 			if currentConditional.active && currentConditional.origMethod != nil {
 				finalizeConditionalBlock(currentConditional.origMethod)
 			} else {
-				fmt.Println("Warning: 'end' encountered without an active 'alt' block or method context.")
+				fmt.Fprintln(Log, "Warning: 'end' encountered without an active 'alt' block or method context.")
 			}
 			continue
 		}
@@ -541,12 +570,12 @@ func TransformSequenceDiagram(
 				currentClass, currentMethod := getCurrentContext()
 
 				if currentMethod == nil {
-					fmt.Println("No current function. Skipping return assignment.")
+					fmt.Fprintln(Log, "No current function. Skipping return assignment.")
 					continue
 				} else {
 
 					if len(callReturnStack) == 0 {
-						fmt.Printf("No previous method call to assign return value: %s. Could not rename.\n", message.Name)
+						fmt.Fprintf(Log, "No previous method call to assign return value: %s. Could not rename.\n", message.Name)
 					} else {
 						lastCall := callReturnStack[len(callReturnStack)-1]
 						callReturnStack = callReturnStack[:len(callReturnStack)-1]
@@ -577,7 +606,7 @@ func TransformSequenceDiagram(
 							currentMethod.ReturnValue = message.Name
 						} else {
 
-							fmt.Println("Couldn't assign return value name for method. Index out of range.", callerMethod.Name, callerMethod.ReturnValue, currentMethod.Name, currentClass)
+							fmt.Fprintln(Log, "Couldn't assign return value name for method. Index out of range.", callerMethod.Name, callerMethod.ReturnValue, currentMethod.Name, currentClass)
 						}
 					}
 				}
@@ -594,7 +623,7 @@ func TransformSequenceDiagram(
 	}
 
 	// After processing the entire sequence diagram, fix return values for methods without return variables.
-	for _, cls := range classes {
+	for _, cls := range model.ClassList() {
 		for m := range cls.Methods {
 			method := &cls.Methods[m]
 			if method.ReturnType == "" || method.ReturnType == "void" {
@@ -638,14 +667,15 @@ func TransformSequenceDiagram(
 			if conflictingVar {
 				// Create a new variable with a unique name
 				uniqueVarName := returnVar + "Result"
-				if isPrimitiveType(returnType) {
+				if model.Types.IsValueType(returnType) {
 					// For primitives and String, declare and initialize with default value
 					declBody := generator.Body{
 						IsDeclaration: true,
 						Variable: generator.Attribute{
-							Name:  uniqueVarName,
-							Type:  returnType,
-							Value: defaultZero(returnType),
+							Name:                   uniqueVarName,
+							Type:                   returnType,
+							IsAttributeInitialized: true,
+							Value:                  model.Types.DefaultValue(returnType),
 						},
 					}
 					method.MethodBody = append([]generator.Body{declBody}, method.MethodBody...)
@@ -662,14 +692,15 @@ func TransformSequenceDiagram(
 				method.ReturnValue = uniqueVarName
 			} else if existingVar == nil {
 				// No variable exists, create a new one
-				if isPrimitiveType(returnType) {
+				if model.Types.IsValueType(returnType) {
 					// For primitives and String, declare and initialize with default value
 					declBody := generator.Body{
 						IsDeclaration: true,
 						Variable: generator.Attribute{
-							Name:  returnVar,
-							Type:  returnType,
-							Value: defaultZero(returnType),
+							Name:                   returnVar,
+							Type:                   returnType,
+							IsAttributeInitialized: true,
+							Value:                  model.Types.DefaultValue(returnType),
 						},
 					}
 					method.MethodBody = append([]generator.Body{declBody}, method.MethodBody...)
@@ -687,23 +718,23 @@ func TransformSequenceDiagram(
 	}
 
 	// After processing the entire sequence diagram, fix variable declarations
-	finalizeVariableDeclarations(classes)
+	finalizeVariableDeclarations(model)
 
 	if len(callStack) > 0 {
-		fmt.Printf("Warning: Stack not empty after processing. Remaining size: %d\n", len(callStack))
+		fmt.Fprintf(Log, "Warning: Stack not empty after processing. Remaining size: %d\n", len(callStack))
 		for _, ctx := range callStack {
-			fmt.Printf("Unfinished context: class=%s, method=%s\n", ctx.class.ClassName, ctx.method.Name)
+			fmt.Fprintf(Log, "Unfinished context: class=%s, method=%s\n", ctx.class.ClassName, ctx.method.Name)
 		}
 	}
 
-	fmt.Println("TransformSequenceDiagram: completed transformation")
+	fmt.Fprintln(Log, "TransformSequenceDiagram: completed transformation")
 	return nil
 }
 
 // finalizeVariableDeclarations ensures variables are properly declared and assigned
 // without changing the logic. It attempts to tidy up variable usage in method bodies.
-func finalizeVariableDeclarations(classes map[string]*generator.Class) {
-	for _, cls := range classes {
+func finalizeVariableDeclarations(model *Model) {
+	for _, cls := range model.ClassList() {
 		for m := range cls.Methods {
 			method := &cls.Methods[m]
 
@@ -821,29 +852,6 @@ func parseVisibility(vis string) string {
 	}
 }
 
-func isPrimitiveType(typeName string) bool {
-	primitiveTypes := map[string]bool{
-		"int":     true,
-		"boolean": true,
-		"double":  true,
-		"float":   true,
-	}
-	return primitiveTypes[typeName]
-}
-
-func defaultZero(typeName string) string {
-	switch typeName {
-	case "int":
-		return "0"
-	case "boolean":
-		return "false"
-	case "double", "float":
-		return "0.0"
-	default:
-		return "null"
-	}
-}
-
 func capitalize(str string) string {
 	if len(str) == 0 {
 		return str
@@ -0,0 +1,137 @@
+package connector
+
+import (
+	"sort"
+	"strings"
+
+	generator "github.com/MarmaidTranspiler/Merfolk/internal/CodeTemplateGenerator"
+)
+
+// ResolveImports computes the imports of every class and interface of the model. A referenced
+// type is imported if it is declared in another package of the model or listed in knownTypes,
+// which maps simple type names to fully qualified names. Types of the model take precedence.
+// Interfaces are imported by their name with interfacePrefix.
+func ResolveImports(model *Model, knownTypes map[string]string, interfacePrefix string) {
+	qualified := make(map[string]javaType)
+	for name, fullName := range knownTypes {
+		if i := strings.LastIndex(fullName, "."); i >= 0 {
+			qualified[name] = javaType{pkg: fullName[:i], name: fullName[i+1:]}
+		}
+	}
+	for name, target := range qualifiedNames(model, interfacePrefix) {
+		qualified[name] = target
+	}
+
+	for _, class := range model.ClassList() {
+		class.Imports = importsFor(class.Package, classReferences(class), qualified)
+	}
+	for _, iface := range model.InterfaceList() {
+		iface.Imports = importsFor(iface.Package, interfaceReferences(iface), qualified)
+	}
+}
+
+// javaType is the package and generated name of a type.
+type javaType struct {
+	pkg  string
+	name string
+}
+
+// qualifiedNames maps the diagram name of every type in the model to its package and Java name.
+// Interfaces are generated with a prefix and are only used if no class has the same name.
+func qualifiedNames(model *Model, interfacePrefix string) map[string]javaType {
+	qualified := make(map[string]javaType)
+	for _, iface := range model.InterfaceList() {
+		qualified[iface.InterfaceName] = javaType{pkg: iface.Package, name: interfacePrefix + iface.InterfaceName}
+	}
+	for _, class := range model.ClassList() {
+		qualified[class.ClassName] = javaType{pkg: class.Package, name: class.ClassName}
+	}
+	return qualified
+}
+
+// importsFor returns the sorted imports a file in package pkg needs for the given type references.
+func importsFor(pkg string, references []string, qualified map[string]javaType) []string {
+	seen := make(map[string]bool)
+	var imports []string
+	for _, reference := range references {
+		for _, name := range typeNames(reference) {
+			target, exists := qualified[name]
+			if !exists || target.pkg == "" || target.pkg == pkg || target.pkg == "java.lang" {
+				continue
+			}
+			fullName := target.pkg + "." + target.name
+			if !seen[fullName] {
+				seen[fullName] = true
+				imports = append(imports, fullName)
+			}
+		}
+	}
+	sort.Strings(imports)
+	return imports
+}
+
+// typeNames splits a type such as "Map<String, List<Order>>" or "Order[]" into the simple
+// names it consists of. Fully qualified names need no import and are left out.
+func typeNames(typeName string) []string {
+	var names []string
+	for _, name := range strings.FieldsFunc(typeName, func(r rune) bool {
+		return strings.ContainsRune("<>,[]? ", r)
+	}) {
+		if name != "extends" && name != "super" && !strings.Contains(name, ".") {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// classReferences returns the names of all types a class refers to in its declaration,
+// attributes, method signatures and method bodies.
+func classReferences(class *generator.Class) []string {
+	references := []string{class.Inherits}
+	references = append(references, class.Abstraction...)
+	for _, attr := range class.Attributes {
+		references = append(references, attr.Type)
+	}
+	for _, method := range class.Methods {
+		references = append(references, methodReferences(method)...)
+		references = append(references, bodyReferences(method.MethodBody)...)
+	}
+	return references
+}
+
+func interfaceReferences(iface *generator.Interface) []string {
+	references := append([]string{}, iface.Inherits...)
+	for _, attr := range iface.AbstractAttributes {
+		references = append(references, attr.Type)
+	}
+	for _, method := range iface.AbstractMethods {
+		references = append(references, methodReferences(method)...)
+	}
+	return references
+}
+
+func methodReferences(method generator.Method) []string {
+	references := []string{method.ReturnType}
+	for _, param := range method.Parameters {
+		references = append(references, param.Type)
+	}
+	return references
+}
+
+// bodyReferences returns the types of the local variables and created objects of a method body.
+func bodyReferences(body []generator.Body) []string {
+	var references []string
+	for _, line := range body {
+		if line.IsObjectCreation {
+			references = append(references, line.ObjectType)
+		}
+		if line.IsDeclaration || line.IsVariable {
+			references = append(references, line.Variable.Type)
+		}
+		if line.IsCondition {
+			references = append(references, bodyReferences(line.IfBody)...)
+			references = append(references, bodyReferences(line.ElseBody)...)
+		}
+	}
+	return references
+}
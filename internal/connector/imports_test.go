@@ -0,0 +1,78 @@
+package connector
+
+import (
+	"strings"
+	"testing"
+
+	generator "github.com/MarmaidTranspiler/Merfolk/internal/CodeTemplateGenerator"
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+)
+
+func TestJavaTypeName(t *testing.T) {
+	tests := map[string]string{
+		"int":                       "int",
+		"List~Order~":               "List<Order>",
+		"Map~String,Order~":         "Map<String, Order>",
+		"List~List~int~~":           "List<List<Integer>>",
+		"Map~String,List~Order~~":   "Map<String, List<Order>>",
+		"Map~List~Order~,String~":   "Map<List<Order>, String>",
+		"Optional~Map~String,int~~": "Optional<Map<String, Integer>>",
+	}
+	for input, expected := range tests {
+		if got := javaTypeName(input, nil); got != expected {
+			t.Errorf("javaTypeName(%q) = %q, want %q", input, got, expected)
+		}
+	}
+}
+
+func TestResolveImportsWithKnownTypes(t *testing.T) {
+	input := `classDiagram
+class Order {
+    +List~Item~ items
+    +due(LocalDate from) Optional~Instant~
+    +total() Money
+}
+class Item
+Item : price() BigDecimal
+`
+	diagram, err := reader.ClassDiagramParser.ParseString("", input)
+	if err != nil {
+		t.Fatalf("Error parsing diagram: %v", err)
+	}
+	model, err := TransformClassDiagram(diagram, nil)
+	if err != nil {
+		t.Fatalf("Error transforming diagram: %v", err)
+	}
+
+	order := model.Classes["Order"]
+	order.Methods[0].MethodBody = []generator.Body{
+		{IsCondition: true, IfBody: []generator.Body{
+			{IsObjectCreation: true, ObjectName: "ids", ObjectType: "ArrayList<UUID>"},
+		}},
+	}
+
+	AssignPackages(model, "com.acme")
+	knownTypes := map[string]string{
+		"List":       "java.util.List",
+		"Optional":   "java.util.Optional",
+		"ArrayList":  "java.util.ArrayList",
+		"UUID":       "java.util.UUID",
+		"LocalDate":  "java.time.LocalDate",
+		"Instant":    "java.time.Instant",
+		"BigDecimal": "java.math.BigDecimal",
+		"Money":      "org.joda.money.Money",
+		"Item":       "should.not.Win",
+	}
+	ResolveImports(model, knownTypes, "I")
+
+	expected := "java.time.Instant java.time.LocalDate java.util.ArrayList java.util.List java.util.Optional java.util.UUID org.joda.money.Money"
+	if got := strings.Join(order.Imports, " "); got != expected {
+		t.Errorf("Expected imports:\n%s\nGot:\n%s", expected, got)
+	}
+	if got := strings.Join(model.Classes["Item"].Imports, " "); got != "java.math.BigDecimal" {
+		t.Errorf("Expected Item to import java.math.BigDecimal, got %q", got)
+	}
+	if order.Attributes[0].Type != "List<Item>" {
+		t.Errorf("Expected attribute type List<Item>, got %s", order.Attributes[0].Type)
+	}
+}
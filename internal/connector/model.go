@@ -0,0 +1,72 @@
+package connector
+
+import (
+	generator "github.com/MarmaidTranspiler/Merfolk/internal/CodeTemplateGenerator"
+)
+
+// Model holds the classes and interfaces collected from class and sequence diagrams.
+// Entries are kept in the order they were first declared, so that iterating over a
+// model yields the same sequence on every run.
+type Model struct {
+	Classes    map[string]*generator.Class
+	Interfaces map[string]*generator.Interface
+
+	// Types maps diagram types to Java types, nil uses the built-in mappings.
+	Types generator.TypeMap
+
+	classOrder     []string
+	interfaceOrder []string
+}
+
+// NewModel returns an empty model.
+func NewModel() *Model {
+	return &Model{
+		Classes:    make(map[string]*generator.Class),
+		Interfaces: make(map[string]*generator.Interface),
+	}
+}
+
+// SetClass stores class under its name. A class that is already known keeps its position.
+func (m *Model) SetClass(class *generator.Class) {
+	if _, exists := m.Classes[class.ClassName]; !exists {
+		m.classOrder = append(m.classOrder, class.ClassName)
+	}
+	m.Classes[class.ClassName] = class
+}
+
+// SetInterface stores iface under its name. An interface that is already known keeps its position.
+func (m *Model) SetInterface(iface *generator.Interface) {
+	if _, exists := m.Interfaces[iface.InterfaceName]; !exists {
+		m.interfaceOrder = append(m.interfaceOrder, iface.InterfaceName)
+	}
+	m.Interfaces[iface.InterfaceName] = iface
+}
+
+// ClassList returns the classes in declaration order.
+func (m *Model) ClassList() []*generator.Class {
+	list := make([]*generator.Class, 0, len(m.classOrder))
+	for _, name := range m.classOrder {
+		list = append(list, m.Classes[name])
+	}
+	return list
+}
+
+// InterfaceList returns the interfaces in declaration order.
+func (m *Model) InterfaceList() []*generator.Interface {
+	list := make([]*generator.Interface, 0, len(m.interfaceOrder))
+	for _, name := range m.interfaceOrder {
+		list = append(list, m.Interfaces[name])
+	}
+	return list
+}
+
+// Merge copies all entries of other into m. Entries of other replace entries with
+// the same name, new entries are appended after the existing ones.
+func (m *Model) Merge(other *Model) {
+	for _, class := range other.ClassList() {
+		m.SetClass(class)
+	}
+	for _, iface := range other.InterfaceList() {
+		m.SetInterface(iface)
+	}
+}
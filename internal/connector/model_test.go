@@ -0,0 +1,56 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+)
+
+func TestClassListKeepsDeclarationOrder(t *testing.T) {
+	input := `classDiagram
+   Zebra : run() void
+   Apple : eat() void
+   Mango : peel() void
+   Apple : wash() void
+`
+	diagram, err := reader.ClassDiagramParser.ParseString("", input)
+	if err != nil {
+		t.Fatalf("Error parsing diagram: %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		model, err := TransformClassDiagram(diagram, nil)
+		if err != nil {
+			t.Fatalf("Error transforming diagram: %v", err)
+		}
+
+		var names []string
+		for _, class := range model.ClassList() {
+			names = append(names, class.ClassName)
+		}
+		if len(names) != 3 || names[0] != "Zebra" || names[1] != "Apple" || names[2] != "Mango" {
+			t.Fatalf("Expected [Zebra Apple Mango], got %v", names)
+		}
+	}
+}
+
+func TestMergeReplacesButKeepsPosition(t *testing.T) {
+	first := NewModel()
+	ensureClassEntry(first, "A")
+	ensureClassEntry(first, "B")
+
+	second := NewModel()
+	ensureClassEntry(second, "C")
+	ensureClassEntry(second, "A")
+	second.Classes["A"].Inherits = "Base"
+
+	first.Merge(second)
+
+	list := first.ClassList()
+	if len(list) != 3 || list[0].ClassName != "A" || list[1].ClassName != "B" || list[2].ClassName != "C" {
+		t.Fatalf("Unexpected order after merge: %v", list)
+	}
+	if list[0].Inherits != "Base" {
+		t.Errorf("Expected merged class A to replace the original entry")
+	}
+}
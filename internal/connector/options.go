@@ -0,0 +1,42 @@
+package connector
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+)
+
+// CheckDiagramOptions reports settings in the frontmatter of a diagram that cannot be generated.
+func CheckDiagramOptions(options reader.Options) error {
+	if options.Language != "" && !strings.EqualFold(options.Language, "java") {
+		return fmt.Errorf("unsupported target language %q", options.Language)
+	}
+	return nil
+}
+
+// ApplyDiagramOptions applies the generation settings from the frontmatter of a diagram
+// to the classes and interfaces that were created from it.
+func ApplyDiagramOptions(model *Model, options reader.Options) error {
+	if err := CheckDiagramOptions(options); err != nil {
+		return err
+	}
+
+	// Packages from namespaces take precedence over the package of the diagram
+	for _, class := range model.ClassList() {
+		if options.Package != "" && class.Package == "" {
+			class.Package = options.Package
+		}
+		if options.BaseClass != "" && class.Inherits == "" && class.ClassName != options.BaseClass {
+			class.Inherits = options.BaseClass
+		}
+	}
+
+	for _, iface := range model.InterfaceList() {
+		if options.Package != "" && iface.Package == "" {
+			iface.Package = options.Package
+		}
+	}
+
+	return nil
+}
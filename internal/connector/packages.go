@@ -0,0 +1,15 @@
+package connector
+
+// AssignPackages puts every class and interface that has no package yet into basePackage.
+func AssignPackages(model *Model, basePackage string) {
+	for _, class := range model.ClassList() {
+		if class.Package == "" {
+			class.Package = basePackage
+		}
+	}
+	for _, iface := range model.InterfaceList() {
+		if iface.Package == "" {
+			iface.Package = basePackage
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package connector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+)
+
+func TestNamespacesAndImports(t *testing.T) {
+	input := `classDiagram
+namespace com.acme.orders {
+    class Order {
+        +cancel(Customer customer) boolean
+    }
+}
+namespace com.acme.crm {
+    class Customer {
+        +find(Order order) Customer
+    }
+}
+class Util
+Util : help(Order order) void
+`
+	diagram, err := reader.ClassDiagramParser.ParseString("", input)
+	if err != nil {
+		t.Fatalf("Error parsing diagram: %v", err)
+	}
+
+	model, err := TransformClassDiagram(diagram, nil)
+	if err != nil {
+		t.Fatalf("Error transforming diagram: %v", err)
+	}
+	AssignPackages(model, "com.acme")
+	ResolveImports(model, nil, "I")
+
+	tests := []struct {
+		class   string
+		pkg     string
+		imports string
+	}{
+		{"Order", "com.acme.orders", "com.acme.crm.Customer"},
+		{"Customer", "com.acme.crm", "com.acme.orders.Order"},
+		{"Util", "com.acme", "com.acme.orders.Order"},
+	}
+	for _, test := range tests {
+		class := model.Classes[test.class]
+		if class == nil {
+			t.Fatalf("Class %s is missing", test.class)
+		}
+		if class.Package != test.pkg {
+			t.Errorf("Expected package %s for %s, got %s", test.pkg, test.class, class.Package)
+		}
+		if got := strings.Join(class.Imports, " "); got != test.imports {
+			t.Errorf("Expected imports %q for %s, got %q", test.imports, test.class, got)
+		}
+	}
+}
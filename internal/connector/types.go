@@ -0,0 +1,57 @@
+package connector
+
+import (
+	"strings"
+
+	generator "github.com/MarmaidTranspiler/Merfolk/internal/CodeTemplateGenerator"
+)
+
+// javaTypeName converts a Mermaid type to Java syntax. Mermaid writes generic types with
+// tildes, e.g. List~Order~ or Map~String,List~int~~, which become List<Order> and
+// Map<String, List<Integer>>. Type names are mapped with types, primitives inside generic
+// types are replaced by their boxed types.
+func javaTypeName(typeName string, types generator.TypeMap) string {
+	open := strings.IndexByte(typeName, '~')
+	closing := strings.LastIndexByte(typeName, '~')
+	if open < 0 || closing <= open {
+		return types.JavaType(typeName)
+	}
+
+	var arguments []string
+	for _, argument := range splitTypeArguments(typeName[open+1 : closing]) {
+		if strings.ContainsRune(argument, '~') {
+			arguments = append(arguments, javaTypeName(argument, types))
+		} else {
+			arguments = append(arguments, types.BoxedType(argument))
+		}
+	}
+	return types.JavaType(typeName[:open]) + "<" + strings.Join(arguments, ", ") + ">" + typeName[closing+1:]
+}
+
+// splitTypeArguments splits the content of a generic type at the commas that are not part of
+// a nested generic type. A tilde that is followed by a name opens a nested type, any other
+// tilde closes one.
+func splitTypeArguments(arguments string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(arguments); i++ {
+		switch arguments[i] {
+		case '~':
+			if i+1 < len(arguments) && isIdentifierChar(arguments[i+1]) {
+				depth++
+			} else {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(arguments[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(arguments[start:]))
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
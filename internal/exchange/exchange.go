@@ -0,0 +1,122 @@
+// Package exchange reads and writes the model of the connector as JSON or YAML documents, so
+// that other tools can use the transformed diagrams and code can be generated from models that
+// were not created from diagrams. The documents are described by the JSON Schema Schema.
+package exchange
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	generator "github.com/MarmaidTranspiler/Merfolk/internal/CodeTemplateGenerator"
+	"github.com/MarmaidTranspiler/Merfolk/internal/connector"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the version of the document format.
+const Version = 1
+
+// Schema is the JSON Schema of the documents.
+//
+//go:embed model.schema.json
+var Schema []byte
+
+// Format is the syntax of a document.
+type Format string
+
+const (
+	JSON Format = "json"
+	YAML Format = "yaml"
+)
+
+// ParseFormat returns the format of the given name.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "json":
+		return JSON, nil
+	case "yaml", "yml":
+		return YAML, nil
+	}
+	return "", fmt.Errorf("unknown format %q, expected json or yaml", name)
+}
+
+// FormatOf returns the format of a file by its extension, JSON for unknown extensions.
+func FormatOf(file string) Format {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		return YAML
+	}
+	return JSON
+}
+
+// Document is the content of a model file. Classes and interfaces are in declaration order.
+type Document struct {
+	Version    int                    `json:"version" yaml:"version"`
+	Classes    []*generator.Class     `json:"classes" yaml:"classes"`
+	Interfaces []*generator.Interface `json:"interfaces" yaml:"interfaces"`
+}
+
+// Marshal writes the model as a document of the given format.
+func Marshal(model *connector.Model, format Format) ([]byte, error) {
+	document := Document{Version: Version, Classes: model.ClassList(), Interfaces: model.InterfaceList()}
+	if format == YAML {
+		var b bytes.Buffer
+		encoder := yaml.NewEncoder(&b)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(document); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), encoder.Close()
+	}
+	content, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(content, '\n'), nil
+}
+
+// Unmarshal reads a document of the given format. Unknown fields are errors, so that
+// misspelled names are not silently ignored, and every class and interface needs a unique name.
+func Unmarshal(content []byte, format Format) (*connector.Model, error) {
+	var document Document
+	if format == YAML {
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&document); err != nil {
+			return nil, err
+		}
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&document); err != nil {
+			return nil, err
+		}
+	}
+	if document.Version != Version {
+		return nil, fmt.Errorf("unsupported version %d, expected %d", document.Version, Version)
+	}
+
+	model := connector.NewModel()
+	for i, class := range document.Classes {
+		switch {
+		case class == nil || class.ClassName == "":
+			return nil, fmt.Errorf("class %d has no name", i+1)
+		case model.Classes[class.ClassName] != nil:
+			return nil, fmt.Errorf("class %s is declared twice", class.ClassName)
+		}
+		model.SetClass(class)
+	}
+	for i, iface := range document.Interfaces {
+		switch {
+		case iface == nil || iface.InterfaceName == "":
+			return nil, fmt.Errorf("interface %d has no name", i+1)
+		case model.Interfaces[iface.InterfaceName] != nil:
+			return nil, fmt.Errorf("interface %s is declared twice", iface.InterfaceName)
+		}
+		model.SetInterface(iface)
+	}
+	return model, nil
+}
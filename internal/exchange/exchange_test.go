@@ -0,0 +1,182 @@
+package exchange
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	generator "github.com/MarmaidTranspiler/Merfolk/internal/CodeTemplateGenerator"
+	"github.com/MarmaidTranspiler/Merfolk/internal/connector"
+)
+
+func testModel() *connector.Model {
+	model := connector.NewModel()
+	model.SetClass(&generator.Class{
+		Package:     "com.shop",
+		Imports:     []string{"java.util.List"},
+		ClassName:   "Order",
+		Abstraction: []string{"Priced"},
+		Inherits:    "Entity",
+		Attributes: []generator.Attribute{
+			{AccessModifier: "private", Name: "items", Type: "List<Item>", IsAttributeInitialized: true, Value: "new java.util.ArrayList<>()"},
+			{AccessModifier: "public", Name: "LIMIT", Type: "int", IsClassVariable: true, IsConstant: true, IsAttributeInitialized: true, Value: "10"},
+			{AccessModifier: "private", Name: "service", Type: "PriceService", IsObject: true, ObjectConstructorArgs: []generator.ConstructorArg{{Type: "String", Value: "\"eur\""}}},
+		},
+		Methods: []generator.Method{{
+			AccessModifier: "public",
+			Name:           "total",
+			ReturnType:     "int",
+			Parameters:     []generator.Attribute{{Name: "discount", Type: "int"}},
+			MethodBody: []generator.Body{
+				{IsDeclaration: true, Variable: generator.Attribute{Name: "sum", Type: "int", IsAttributeInitialized: true, Value: "0"}},
+				{IsObjectCreation: true, ObjectName: "item", ObjectType: "Item", ObjFuncParameters: []generator.Attribute{{Name: "id", Type: "String"}}},
+				{IsVariable: true, FunctionName: "item.price", Variable: generator.Attribute{Name: "sum", Type: "int"}},
+				{IsCondition: true, Condition: "sum > LIMIT",
+					IfBody:   []generator.Body{{FunctionName: "service.notify"}},
+					ElseBody: []generator.Body{{FunctionName: "service.log", ObjFuncParameters: []generator.Attribute{{Name: "sum"}}}}},
+			},
+			ReturnValue: "sum",
+		}},
+	})
+	model.SetInterface(&generator.Interface{
+		Package:         "com.shop",
+		InterfaceName:   "Priced",
+		Inherits:        []string{"Comparable"},
+		AbstractMethods: []generator.Method{{AccessModifier: "public", Name: "total", ReturnType: "int"}},
+	})
+	return model
+}
+
+// render returns the generated code of all classes and interfaces of model.
+func render(t *testing.T, model *connector.Model) string {
+	t.Helper()
+	var code strings.Builder
+	for _, class := range model.ClassList() {
+		content, err := generator.RenderJavaCode(*class, "", generator.DefaultOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		code.Write(content)
+	}
+	for _, iface := range model.InterfaceList() {
+		content, err := generator.RenderJavaCode(*iface, "", generator.DefaultOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		code.Write(content)
+	}
+	return code.String()
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	model := testModel()
+	expected := render(t, model)
+
+	for _, format := range []Format{JSON, YAML} {
+		content, err := Marshal(model, format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if strings.Contains(string(content), "declared: {}") || strings.Contains(string(content), `"declared": {}`) {
+			t.Errorf("%s: statements without variable declare an empty one\n%s", format, content)
+		}
+		read, err := Unmarshal(content, format)
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", format, err, content)
+		}
+		if code := render(t, read); code != expected {
+			t.Errorf("%s: the read model generates other code\n%s\nexpected\n%s", format, code, expected)
+		}
+		again, err := Marshal(read, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(again) != string(content) {
+			t.Errorf("%s: the read model is written differently\n%s\nexpected\n%s", format, again, content)
+		}
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		format   Format
+		expected string
+	}{
+		{"unknown field", `{"version": 1, "classes": [{"name": "Order", "attribute": []}]}`, JSON, "unknown field"},
+		{"unknown YAML field", "version: 1\nclasses:\n  - name: Order\n    methods:\n      - name: total\n        return: int\n", YAML, "field return not found"},
+		{"version", `{"version": 2, "classes": []}`, JSON, "unsupported version 2"},
+		{"missing version", "classes: []\n", YAML, "unsupported version 0"},
+		{"class name", `{"version": 1, "classes": [{"package": "com.shop"}]}`, JSON, "class 1 has no name"},
+		{"interface name", `{"version": 1, "interfaces": [null]}`, JSON, "interface 1 has no name"},
+		{"duplicate class", `{"version": 1, "classes": [{"name": "Order"}, {"name": "Order"}]}`, JSON, "class Order is declared twice"},
+		{"duplicate interface", "version: 1\ninterfaces:\n  - name: Priced\n  - name: Priced\n", YAML, "interface Priced is declared twice"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Unmarshal([]byte(test.content), test.format)
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("expected error containing %q, got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	if format, err := ParseFormat("YML"); err != nil || format != YAML {
+		t.Errorf("expected YAML, got %q, %v", format, err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	for file, expected := range map[string]Format{"model.yaml": YAML, "model.YML": YAML, "model.json": JSON, "model": JSON} {
+		if format := FormatOf(file); format != expected {
+			t.Errorf("%s: expected %s, got %s", file, expected, format)
+		}
+	}
+}
+
+// TestSchema checks that the schema declares exactly the fields of the documents.
+func TestSchema(t *testing.T) {
+	var schema struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+		Defs       map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(Schema, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	types := map[string]reflect.Type{
+		"":               reflect.TypeOf(Document{}),
+		"class":          reflect.TypeOf(generator.Class{}),
+		"interface":      reflect.TypeOf(generator.Interface{}),
+		"attribute":      reflect.TypeOf(generator.Attribute{}),
+		"constructorArg": reflect.TypeOf(generator.ConstructorArg{}),
+		"method":         reflect.TypeOf(generator.Method{}),
+		"statement":      reflect.TypeOf(generator.Body{}),
+	}
+	for def, typ := range types {
+		properties := schema.Properties
+		if def != "" {
+			properties = schema.Defs[def].Properties
+		}
+		var declared, fields []string
+		for name := range properties {
+			declared = append(declared, name)
+		}
+		for i := 0; i < typ.NumField(); i++ {
+			name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			fields = append(fields, name)
+		}
+		sort.Strings(declared)
+		sort.Strings(fields)
+		if !reflect.DeepEqual(declared, fields) {
+			t.Errorf("%s: schema declares %v, the document has %v", typ.Name(), declared, fields)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package format
+
+import (
+	"strings"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+)
+
+func (p *printer) classDiagram(diagram *reader.ClassDiagram) {
+	p.statement(p.keywordLine, "classDiagram")
+	p.depth = 1
+
+	instructions := diagram.Instructions
+	for i := 0; i < len(instructions); i++ {
+		instruction := instructions[i]
+		switch {
+		case instruction.Namespace != nil:
+			p.namespace(instruction.Pos.Line, instruction.Namespace)
+		case instruction.Class != nil:
+			p.class(instruction.Class)
+		case instruction.Relationship != nil:
+			p.statement(instruction.Pos.Line, relationship(instruction.Relationship))
+		case instruction.Annotation != nil:
+			p.statement(instruction.Pos.Line, "<<"+instruction.Annotation.Name+">> "+instruction.Annotation.Class)
+		case instruction.Member != nil:
+			// Consecutive member lines of the same class are ordered as a group
+			class := instruction.Member.Class
+			var group []member
+			for ; i < len(instructions) && instructions[i].Member != nil && instructions[i].Member.Class == class; i++ {
+				m := classMember(instructions[i].Member.Visibility, instructions[i].Member.Operation, instructions[i].Member.Attribute)
+				m.line = instructions[i].Pos.Line
+				m.text = class + " : " + m.text
+				group = append(group, m)
+			}
+			i--
+			p.members(group)
+		}
+	}
+
+	p.finish()
+}
+
+// namespace prints a namespace and returns the line of its closing brace.
+func (p *printer) namespace(line int, namespace *reader.Namespace) int {
+	p.open(line, "namespace "+namespace.Name+" {")
+
+	end := line
+	for _, class := range namespace.Classes {
+		end = p.class(class) + 1
+	}
+
+	closing := p.closingLine(end)
+	p.close(closing, "}")
+	return closing
+}
+
+// class prints a class declaration and returns its last line.
+func (p *printer) class(class *reader.ClassDeclaration) int {
+	line := class.Pos.Line
+	if len(class.Members) == 0 {
+		p.statement(line, "class "+class.Name)
+		return line
+	}
+
+	p.open(line, "class "+class.Name+" {")
+	group := make([]member, 0, len(class.Members))
+	last := line
+	for _, body := range class.Members {
+		m := classMember(body.Visibility, body.Operation, body.Attribute)
+		m.line = body.Pos.Line
+		group = append(group, m)
+		last = m.line
+	}
+	p.members(group)
+
+	closing := p.closingLine(last)
+	p.close(closing, "}")
+	return closing
+}
+
+func classMember(visibility string, operation *reader.Operation, attribute *reader.Attribute) member {
+	switch {
+	case operation != nil:
+		parameters := make([]string, 0, len(operation.Parameters))
+		for _, parameter := range operation.Parameters {
+			parameters = append(parameters, strings.TrimSpace(parameter.Type+" "+parameter.Name))
+		}
+		text := visibility + operation.Name + "(" + strings.Join(parameters, ", ") + ")"
+		if operation.Return != "" {
+			text += " " + operation.Return
+		}
+		return member{operation: true, name: operation.Name, text: text}
+	case attribute != nil:
+		text := visibility + strings.TrimSpace(attribute.Type+" "+attribute.Name)
+		return member{name: attribute.Name, text: text}
+	}
+	return member{text: visibility}
+}
+
+func relationship(r *reader.Relationship) string {
+	parts := []string{r.LeftClass}
+	if r.LeftCardinality != "" {
+		parts = append(parts, `"`+r.LeftCardinality+`"`)
+	}
+	parts = append(parts, r.Type)
+	if r.RightCardinality != "" {
+		parts = append(parts, `"`+r.RightCardinality+`"`)
+	}
+	parts = append(parts, r.RightClass)
+	text := strings.Join(parts, " ")
+	if r.Label != "" {
+		text += " : " + r.Label
+	}
+	return text
+}
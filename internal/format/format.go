@@ -0,0 +1,334 @@
+// Package format prints Mermaid class and sequence diagrams in a canonical layout.
+//
+// The formatter works on the participle ASTs of the reader package. Lines the ASTs do not
+// keep, comments and sequence diagram notes, are copied from the source text and placed
+// in front of the statement that follows them.
+package format
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+)
+
+// Order selects the order of class members in the formatted output.
+type Order int
+
+const (
+	// SourceOrder keeps the members in the order of the source.
+	SourceOrder Order = iota
+	// KindOrder puts attributes before operations and keeps the source order otherwise.
+	KindOrder
+	// NameOrder puts attributes before operations and sorts both by name.
+	NameOrder
+)
+
+// ParseOrder returns the Order named by s: "source", "kind" or "name".
+func ParseOrder(s string) (Order, error) {
+	switch s {
+	case "", "source":
+		return SourceOrder, nil
+	case "kind":
+		return KindOrder, nil
+	case "name":
+		return NameOrder, nil
+	}
+	return SourceOrder, fmt.Errorf("unknown member order %q, expected source, kind or name", s)
+}
+
+// Options control the layout of the formatted diagrams.
+type Options struct {
+	// Indent is the indentation of one nesting level, four spaces if empty.
+	Indent string
+	// Order is the order of class members.
+	Order Order
+}
+
+// Document formats all Mermaid diagrams embedded in content and returns the resulting
+// document. Text outside of the diagrams and PlantUML diagrams are not changed.
+func Document(content string, syntax reader.Syntax, options Options) (string, error) {
+	blocks, err := reader.ExtractBlocks(content, syntax)
+	if err != nil {
+		return "", err
+	}
+
+	newline := "\n"
+	if strings.Contains(content, "\r\n") {
+		newline = "\r\n"
+	}
+	normalized := strings.ReplaceAll(content, "\r\n", "\n")
+	trailingNewline := strings.HasSuffix(normalized, "\n")
+	lines := strings.Split(strings.TrimSuffix(normalized, "\n"), "\n")
+
+	// Replace the blocks from the last to the first so that the line numbers of the
+	// remaining blocks stay valid
+	for i := len(blocks) - 1; i >= 0; i-- {
+		block := blocks[i]
+		if block.PlantUML {
+			continue
+		}
+		formatted, err := Diagram(block.Content, options)
+		if err != nil {
+			return "", fmt.Errorf("diagram at line %d: %w", block.Line, err)
+		}
+
+		indent := strings.Repeat(" ", block.Indent)
+		var replacement []string
+		for _, line := range strings.Split(strings.TrimSuffix(formatted, "\n"), "\n") {
+			if line != "" {
+				line = indent + line
+			}
+			replacement = append(replacement, line)
+		}
+
+		end := block.EndLine - 1
+		if end > len(lines) {
+			end = len(lines)
+		}
+		lines = append(lines[:block.Line-1], append(replacement, lines[end:]...)...)
+	}
+
+	result := strings.Join(lines, newline)
+	if trailingNewline {
+		result += newline
+	}
+	return result, nil
+}
+
+// Diagram formats the text of a single class or sequence diagram. The frontmatter and the
+// directives in front of the diagram keyword are kept as they are. Like the content of a
+// reader.Block, the result ends with a newline.
+func Diagram(text string, options Options) (string, error) {
+	diagram, err := reader.ParseDiagram(text)
+	if err != nil {
+		return "", err
+	}
+	if options.Indent == "" {
+		options.Indent = "    "
+	}
+
+	p := newPrinter(strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n"), diagram, options)
+
+	if diagram.IsClass {
+		p.classDiagram(diagram.Class)
+	} else {
+		p.sequenceDiagram(diagram.Sequence)
+	}
+
+	return strings.Join(p.out, "\n") + "\n", nil
+}
+
+// comment is a source line that is not part of the AST and copied verbatim.
+type comment struct {
+	line int
+	text string
+}
+
+type printer struct {
+	options Options
+	// lines are the source lines, lines[0] is line 1
+	lines []string
+	// comments are the source lines to copy, ordered by line
+	comments []comment
+	// keywordLine is the line of the diagram keyword
+	keywordLine int
+
+	out   []string
+	depth int
+	// last is the source line of the last printed statement or comment
+	last int
+	// opened is set after a line that opens a block, blank lines are not kept there
+	opened bool
+}
+
+func newPrinter(lines []string, diagram *reader.Diagram, options Options) *printer {
+	p := &printer{options: options, lines: lines, keywordLine: diagram.KeywordLine}
+
+	// The header is kept as it is, without surrounding blank lines
+	header := lines[:diagram.KeywordLine-1]
+	for len(header) > 0 && strings.TrimSpace(header[0]) == "" {
+		header = header[1:]
+	}
+	for len(header) > 0 && strings.TrimSpace(header[len(header)-1]) == "" {
+		header = header[:len(header)-1]
+	}
+	for _, line := range header {
+		p.out = append(p.out, strings.TrimRight(line, " \t\r"))
+	}
+
+	for i := diagram.KeywordLine; i < len(lines); i++ {
+		text := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(text, "%%") || (diagram.IsSequence && isNote(text)) {
+			p.comments = append(p.comments, comment{line: i + 1, text: text})
+		}
+	}
+
+	return p
+}
+
+func isNote(text string) bool {
+	return len(text) >= 4 && strings.EqualFold(text[:4], "note")
+}
+
+// write appends a line at the current depth.
+func (p *printer) write(text string) {
+	p.out = append(p.out, strings.Repeat(p.options.Indent, p.depth)+text)
+	p.opened = false
+}
+
+// blank keeps a single blank line in front of a statement or comment on line if the source has one.
+func (p *printer) blank(line int) {
+	if p.opened || p.last == 0 {
+		return
+	}
+	for i := p.last + 1; i < line && i <= len(p.lines); i++ {
+		if strings.TrimSpace(p.lines[i-1]) == "" {
+			p.out = append(p.out, "")
+			return
+		}
+	}
+}
+
+// take removes and returns the comments in front of line.
+func (p *printer) take(line int) []comment {
+	i := 0
+	for i < len(p.comments) && p.comments[i].line < line {
+		i++
+	}
+	taken := p.comments[:i]
+	p.comments = p.comments[i:]
+	return taken
+}
+
+// flush prints the comments in front of line.
+func (p *printer) flush(line int) {
+	for _, c := range p.take(line) {
+		p.blank(c.line)
+		p.write(c.text)
+		p.last = c.line
+	}
+}
+
+// statement prints a statement that starts on line.
+func (p *printer) statement(line int, text string) {
+	p.flush(line)
+	p.blank(line)
+	p.write(text + p.trailingComment(line))
+	p.last = line
+}
+
+// open prints a statement that starts a nested block.
+func (p *printer) open(line int, text string) {
+	p.statement(line, text)
+	p.depth++
+	p.opened = true
+}
+
+// close prints the statement on line that ends a nested block.
+func (p *printer) close(line int, text string) {
+	p.flush(line)
+	if p.depth > 1 {
+		p.depth--
+	}
+	p.write(text + p.trailingComment(line))
+	p.last = line
+}
+
+// finish prints the comments after the last statement.
+func (p *printer) finish() {
+	p.flush(len(p.lines) + 1)
+}
+
+// trailingComment returns the comment at the end of a source line that also holds code,
+// with a leading space.
+func (p *printer) trailingComment(line int) string {
+	if line < 1 || line > len(p.lines) {
+		return ""
+	}
+	code, comment := splitComment(p.lines[line-1])
+	if comment == "" || strings.TrimSpace(code) == "" {
+		return ""
+	}
+	return " " + comment
+}
+
+// splitComment splits a line at the %% that starts a comment. %% inside of double-quoted
+// strings does not start a comment.
+func splitComment(line string) (string, string) {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '"':
+			quoted = !quoted
+		case !quoted && strings.HasPrefix(line[i:], "%%"):
+			return line[:i], strings.TrimSpace(line[i:])
+		}
+	}
+	return line, ""
+}
+
+// closingLine returns the first line from line on whose code contains a closing brace.
+func (p *printer) closingLine(line int) int {
+	for i := line; i <= len(p.lines); i++ {
+		code, _ := splitComment(p.lines[i-1])
+		if strings.Contains(code, "}") {
+			return i
+		}
+	}
+	return len(p.lines)
+}
+
+// member is a class member prepared for sorting.
+type member struct {
+	line      int
+	operation bool
+	name      string
+	text      string
+}
+
+// members prints a group of members of one class in the configured order.
+func (p *printer) members(group []member) {
+	if p.options.Order == SourceOrder {
+		for _, m := range group {
+			p.statement(m.line, m.text)
+		}
+		return
+	}
+
+	// Comments stay in front of the member that follows them
+	leading := make(map[int][]comment, len(group))
+	for _, m := range group {
+		leading[m.line] = p.take(m.line)
+	}
+
+	sorted := append([]member(nil), group...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].operation != sorted[j].operation {
+			return !sorted[i].operation
+		}
+		if p.options.Order == NameOrder {
+			return strings.ToLower(sorted[i].name) < strings.ToLower(sorted[j].name)
+		}
+		return false
+	})
+
+	first := group[0].line
+	if comments := leading[first]; len(comments) > 0 {
+		first = comments[0].line
+	}
+	p.blank(first)
+
+	for _, m := range sorted {
+		for _, c := range leading[m.line] {
+			p.write(c.text)
+		}
+		p.write(m.text + p.trailingComment(m.line))
+	}
+	for _, m := range group {
+		if m.line > p.last {
+			p.last = m.line
+		}
+	}
+}
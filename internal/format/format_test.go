@@ -0,0 +1,188 @@
+package format
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+)
+
+func TestDiagramClass(t *testing.T) {
+	input := `---
+title: Orders
+---
+classDiagram
+  %% the aggregate root
+  class   Order{
+  +int id
+      +total( )   Money
+    %% status of the order
+  -String status
+  }
+  Order  "1"-->"*"   Item
+  Item:+int   quantity %% never negative
+
+
+  <<interface>>   Item
+`
+	want := `---
+title: Orders
+---
+classDiagram
+    %% the aggregate root
+    class Order {
+        +int id
+        +total() Money
+        %% status of the order
+        -String status
+    }
+    Order "1" --> "*" Item
+    Item : +int quantity %% never negative
+
+    <<interface>> Item
+`
+
+	got, err := Diagram(input, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+
+	again, err := Diagram(got, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != got {
+		t.Errorf("formatting is not idempotent:\n%s", again)
+	}
+}
+
+func TestDiagramMemberOrder(t *testing.T) {
+	input := `classDiagram
+class Order {
++total() Money
++int id
+%% status of the order
+-String status
++cancel()
+}
+`
+	tests := []struct {
+		order Order
+		want  []string
+	}{
+		{SourceOrder, []string{"+total() Money", "+int id", "%% status of the order", "-String status", "+cancel()"}},
+		{KindOrder, []string{"+int id", "%% status of the order", "-String status", "+total() Money", "+cancel()"}},
+		{NameOrder, []string{"+int id", "%% status of the order", "-String status", "+cancel()", "+total() Money"}},
+	}
+
+	for _, test := range tests {
+		got, err := Diagram(input, Options{Order: test.order})
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(got, "\n")
+		var members []string
+		for _, line := range lines[2 : len(lines)-2] {
+			members = append(members, strings.TrimSpace(line))
+		}
+		if strings.Join(members, "|") != strings.Join(test.want, "|") {
+			t.Errorf("order %d: got %q, want %q", test.order, members, test.want)
+		}
+	}
+}
+
+func TestDiagramSequence(t *testing.T) {
+	input := `sequenceDiagram
+participant A as Alice
+A ->> B : hello()
+loop every minute
+alt ok
+%% thinking
+B -->> A : answer
+else
+B -->> A : error(<null>)
+end
+end
+`
+	want := `sequenceDiagram
+    participant A as Alice
+    A->>B: hello()
+    loop every minute
+        alt ok
+            %% thinking
+            B-->>A: answer
+        else
+            B-->>A: error(<null>)
+        end
+    end
+`
+
+	got, err := Diagram(input, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDocument(t *testing.T) {
+	input := "# Orders\r\n\r\n- item\r\n\r\n  ```mermaid\r\n  classDiagram\r\n  Order : +int id\r\n  ```\r\nText\r\n"
+	want := "# Orders\r\n\r\n- item\r\n\r\n  ```mermaid\r\n  classDiagram\r\n      Order : +int id\r\n  ```\r\nText\r\n"
+
+	got, err := Document(input, reader.Markdown, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("unexpected output:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestDocumentKeepsPlantUML(t *testing.T) {
+	input := "```plantuml\n@startuml\nclass   Order\n@enduml\n```\n\n```mermaid\nclassDiagram\nclass   Order\n```\n"
+	want := "```plantuml\n@startuml\nclass   Order\n@enduml\n```\n\n```mermaid\nclassDiagram\n    class Order\n```\n"
+
+	got, err := Document(input, reader.Markdown, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("unexpected output:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestDocumentExample(t *testing.T) {
+	content, err := os.ReadFile("../../example/example.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatted, err := Document(string(content), reader.Markdown, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := Document(formatted, reader.Markdown, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != formatted {
+		t.Errorf("formatting is not idempotent:\n%s", again)
+	}
+
+	// The formatted diagrams describe the same model
+	before, _ := reader.ExtractBlocks(string(content), reader.Markdown)
+	after, _ := reader.ExtractBlocks(formatted, reader.Markdown)
+	if len(before) != len(after) {
+		t.Fatalf("got %d diagrams, want %d", len(after), len(before))
+	}
+	for i := range before {
+		if _, err := reader.ParseDiagram(after[i].Content); err != nil {
+			t.Errorf("formatted diagram %d does not parse: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package format
+
+import (
+	"strings"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+)
+
+func (p *printer) sequenceDiagram(diagram *reader.SequenceDiagram) {
+	p.statement(p.keywordLine, "sequenceDiagram")
+	p.depth = 1
+
+	for _, instruction := range diagram.Instructions {
+		line := instruction.Pos.Line
+		switch {
+		case instruction.Message != nil:
+			p.statement(line, message(instruction.Message))
+		case instruction.Loop != nil:
+			p.open(line, "loop "+strings.Join(instruction.Loop.Definition, " "))
+		case instruction.Alt != nil:
+			p.open(line, "alt "+strings.Join(instruction.Alt.Definition, " "))
+		case instruction.Else != nil:
+			p.close(line, "else")
+			p.depth++
+			p.opened = true
+		case instruction.End != nil:
+			p.close(line, "end")
+		case instruction.Member != nil:
+			text := strings.ToLower(instruction.Member.Type) + " " + instruction.Member.Name
+			if instruction.Member.Alias != "" {
+				text += " as " + instruction.Member.Alias
+			}
+			p.statement(line, text)
+		case instruction.Life != nil:
+			text := strings.ToLower(instruction.Life.Type) + " "
+			if instruction.Life.On != "" {
+				text += strings.ToLower(instruction.Life.On) + " "
+			}
+			p.statement(line, text+instruction.Life.Name)
+		case instruction.Switch != nil:
+			p.statement(line, strings.ToLower(instruction.Switch.Type)+" "+instruction.Switch.Name)
+		}
+	}
+
+	p.finish()
+}
+
+func message(m *reader.Message) string {
+	text := m.Left + m.Type + m.Right + ": " + m.Name
+	if len(m.Parameters) > 0 {
+		text += "(" + strings.Join(m.Parameters, ", ") + ")"
+	} else if m.DefaultCall {
+		text += "()"
+	}
+	return text
+}
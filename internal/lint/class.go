@@ -0,0 +1,181 @@
+package lint
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+)
+
+func init() {
+	Register(&Rule{
+		Name:        "missing-visibility",
+		Description: "class members without a visibility marker (+, -, #, ~)",
+		Severity:    Warning,
+		Class: func(diagram *reader.ClassDiagram, report Reporter) {
+			for _, m := range classMembers(diagram) {
+				if m.visibility == "" {
+					report(m.line, "member %s of class %s has no visibility", m.name(), m.class)
+				}
+			}
+		},
+	})
+
+	Register(&Rule{
+		Name:        "untyped-attribute",
+		Description: "attributes without a type",
+		Severity:    Warning,
+		Class: func(diagram *reader.ClassDiagram, report Reporter) {
+			for _, m := range classMembers(diagram) {
+				if m.attribute != nil && m.attribute.Type == "" {
+					report(m.line, "attribute %s of class %s has no type", m.attribute.Name, m.class)
+				}
+			}
+		},
+	})
+
+	Register(&Rule{
+		Name:        "method-case",
+		Description: "method names that are not in camelCase, constructors excepted",
+		Severity:    Warning,
+		Class: func(diagram *reader.ClassDiagram, report Reporter) {
+			for _, m := range classMembers(diagram) {
+				if m.operation != nil && m.operation.Name != m.class && !camelCase.MatchString(m.operation.Name) {
+					report(m.line, "method %s of class %s is not in camelCase", m.operation.Name, m.class)
+				}
+			}
+		},
+	})
+
+	Register(&Rule{
+		Name:        "duplicate-member",
+		Description: "attributes declared twice and methods declared twice with the same parameter types",
+		Severity:    Error,
+		Class: func(diagram *reader.ClassDiagram, report Reporter) {
+			seen := make(map[string]int)
+			for _, m := range classMembers(diagram) {
+				key := m.class + "." + m.signature()
+				if first, exists := seen[key]; exists {
+					report(m.line, "member %s of class %s is already declared on line %d", m.name(), m.class, first)
+					continue
+				}
+				seen[key] = m.line
+			}
+		},
+	})
+
+	Register(&Rule{
+		Name:        "unused-relationship",
+		Description: "relationships to classes that are neither declared nor given members",
+		Severity:    Warning,
+		Class: func(diagram *reader.ClassDiagram, report Reporter) {
+			declared := declaredClasses(diagram)
+			for _, instruction := range diagram.Instructions {
+				relationship := instruction.Relationship
+				if relationship == nil {
+					continue
+				}
+				for _, class := range []string{relationship.LeftClass, relationship.RightClass} {
+					if !declared[class] {
+						report(instruction.Pos.Line, "relationship %s %s %s refers to class %s, which is not declared",
+							relationship.LeftClass, relationship.Type, relationship.RightClass, class)
+						break
+					}
+				}
+			}
+		},
+	})
+}
+
+var camelCase = regexp.MustCompile(`^[a-z][a-zA-Z0-9]*$`)
+
+// classMember is an attribute or operation declared in a class body or in a member line.
+type classMember struct {
+	line       int
+	class      string
+	visibility string
+	operation  *reader.Operation
+	attribute  *reader.Attribute
+}
+
+func (m classMember) name() string {
+	if m.operation != nil {
+		return m.operation.Name + "()"
+	}
+	if m.attribute != nil {
+		return m.attribute.Name
+	}
+	return ""
+}
+
+// signature identifies a member within its class: the name of an attribute, or the name
+// and parameter types of an operation.
+func (m classMember) signature() string {
+	if m.operation == nil {
+		return m.name()
+	}
+	types := make([]string, 0, len(m.operation.Parameters))
+	for _, parameter := range m.operation.Parameters {
+		types = append(types, parameter.Type)
+	}
+	return m.operation.Name + "(" + strings.Join(types, ",") + ")"
+}
+
+// classMembers returns all members of a diagram in source order.
+func classMembers(diagram *reader.ClassDiagram) []classMember {
+	var members []classMember
+	addDeclaration := func(class *reader.ClassDeclaration) {
+		for _, body := range class.Members {
+			members = append(members, classMember{
+				line:       body.Pos.Line,
+				class:      class.Name,
+				visibility: body.Visibility,
+				operation:  body.Operation,
+				attribute:  body.Attribute,
+			})
+		}
+	}
+
+	for _, instruction := range diagram.Instructions {
+		switch {
+		case instruction.Namespace != nil:
+			for _, class := range instruction.Namespace.Classes {
+				addDeclaration(class)
+			}
+		case instruction.Class != nil:
+			addDeclaration(instruction.Class)
+		case instruction.Member != nil:
+			if instruction.Member.Operation == nil && instruction.Member.Attribute == nil {
+				continue
+			}
+			members = append(members, classMember{
+				line:       instruction.Pos.Line,
+				class:      instruction.Member.Class,
+				visibility: instruction.Member.Visibility,
+				operation:  instruction.Member.Operation,
+				attribute:  instruction.Member.Attribute,
+			})
+		}
+	}
+	return members
+}
+
+// declaredClasses returns the classes that are declared, annotated or given members.
+func declaredClasses(diagram *reader.ClassDiagram) map[string]bool {
+	declared := make(map[string]bool)
+	for _, instruction := range diagram.Instructions {
+		switch {
+		case instruction.Namespace != nil:
+			for _, class := range instruction.Namespace.Classes {
+				declared[class.Name] = true
+			}
+		case instruction.Class != nil:
+			declared[instruction.Class.Name] = true
+		case instruction.Member != nil:
+			declared[instruction.Member.Class] = true
+		case instruction.Annotation != nil:
+			declared[instruction.Annotation.Class] = true
+		}
+	}
+	return declared
+}
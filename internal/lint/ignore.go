@@ -0,0 +1,109 @@
+package lint
+
+import (
+	"strings"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// ignoreDirective starts a comment that suppresses findings: %% merfolk-ignore rule-a rule-b.
+// Without rule names all rules are suppressed.
+const ignoreDirective = "merfolk-ignore"
+
+// ignores holds the suppressed rules per line, an empty list suppresses every rule.
+type ignores map[int][]string
+
+func (i ignores) has(line int, rule string) bool {
+	names, exists := i[line]
+	if !exists {
+		return false
+	}
+	if len(names) == 0 {
+		return true
+	}
+	for _, name := range names {
+		if name == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoredRules collects the merfolk-ignore comments of a diagram. The comments are taken from
+// the Comment tokens of the diagram lexers: the lexers emit them, unlike the lowercase
+// whitespace tokens, and only the parsers elide them. A comment that follows a statement
+// applies to the line of the statement, a comment on its own line applies to the next line
+// with a statement.
+func ignoredRules(text string, diagram *reader.Diagram) ignores {
+	definition := reader.ClassDiagramLexer
+	if diagram.IsSequence {
+		definition = reader.SequenceDiagramLexer
+	}
+
+	// The header is not Mermaid syntax, its lines are blanked to keep the line numbers
+	lines := strings.Split(text, "\n")
+	for i := 0; i < diagram.KeywordLine-1 && i < len(lines); i++ {
+		lines[i] = ""
+	}
+
+	lex, err := definition.LexString("", strings.Join(lines, "\n"))
+	if err != nil {
+		return nil
+	}
+	tokens, err := lexer.ConsumeAll(lex)
+	if err != nil {
+		return nil
+	}
+
+	symbols := definition.Symbols()
+	comment, lineBreak := symbols["Comment"], symbols["Break"]
+
+	result := ignores{}
+	codeLine := 0
+	var pending [][]string
+	for _, token := range tokens {
+		switch token.Type {
+		case lexer.EOF, lineBreak:
+			continue
+		case comment:
+			names, ok := parseIgnore(token.Value)
+			if !ok {
+				continue
+			}
+			if codeLine == token.Pos.Line {
+				result.add(token.Pos.Line, names)
+			} else {
+				pending = append(pending, names)
+			}
+		default:
+			codeLine = token.Pos.Line
+			for _, names := range pending {
+				result.add(codeLine, names)
+			}
+			pending = nil
+		}
+	}
+	return result
+}
+
+func (i ignores) add(line int, names []string) {
+	existing, exists := i[line]
+	if len(names) == 0 || (exists && len(existing) == 0) {
+		// One of the comments suppresses all rules
+		i[line] = []string{}
+		return
+	}
+	i[line] = append(existing, names...)
+}
+
+// parseIgnore returns the rule names of a merfolk-ignore comment.
+func parseIgnore(value string) ([]string, bool) {
+	fields := strings.FieldsFunc(strings.TrimPrefix(value, "%%"), func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ','
+	})
+	if len(fields) == 0 || fields[0] != ignoreDirective {
+		return nil, false
+	}
+	return fields[1:], true
+}
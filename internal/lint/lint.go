@@ -0,0 +1,227 @@
+// Package lint checks Mermaid class and sequence diagrams for style and correctness problems
+// that do not prevent parsing.
+//
+// Rules register themselves in the rule registry. Every rule has a default severity that can be
+// changed in the project configuration, and single findings can be suppressed with a
+// %% merfolk-ignore <rule> comment on the same line or on the line in front of the statement.
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+	"github.com/alecthomas/participle/v2"
+)
+
+// Severity is the importance of a finding.
+type Severity int
+
+const (
+	// Off disables a rule.
+	Off Severity = iota
+	Info
+	Warning
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Off:
+		return "off"
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ParseSeverity returns the Severity named by s.
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(s) {
+	case "off", "none":
+		return Off, nil
+	case "info":
+		return Info, nil
+	case "warning", "warn":
+		return Warning, nil
+	case "error":
+		return Error, nil
+	}
+	return Off, fmt.Errorf("unknown severity %q, expected off, info, warning or error", s)
+}
+
+// Reporter records a finding of a rule on a line of the diagram text.
+type Reporter func(line int, format string, args ...any)
+
+// Rule is a check of class or sequence diagrams.
+type Rule struct {
+	// Name identifies the rule in the configuration and in merfolk-ignore comments.
+	Name string
+	// Description is a one-line description of what the rule reports.
+	Description string
+	// Severity is the default severity of the findings.
+	Severity Severity
+	// Class checks a class diagram, nil if the rule does not apply to class diagrams.
+	Class func(diagram *reader.ClassDiagram, report Reporter)
+	// Sequence checks a sequence diagram, nil if the rule does not apply to sequence diagrams.
+	Sequence func(diagram *reader.SequenceDiagram, report Reporter)
+}
+
+// SyntaxRule is the name under which parse errors are reported. It cannot be disabled.
+const SyntaxRule = "syntax"
+
+var rules = map[string]*Rule{}
+
+// Register adds a rule to the registry. Rules register themselves in init functions.
+func Register(rule *Rule) {
+	if _, exists := rules[rule.Name]; exists || rule.Name == SyntaxRule {
+		panic("lint rule registered twice: " + rule.Name)
+	}
+	rules[rule.Name] = rule
+}
+
+// Rules returns all registered rules ordered by name.
+func Rules() []*Rule {
+	list := make([]*Rule, 0, len(rules))
+	for _, rule := range rules {
+		list = append(list, rule)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Config holds the severity of every rule.
+type Config struct {
+	severities map[string]Severity
+}
+
+// NewConfig returns the default severities, changed by overrides that map rule names to
+// severity names.
+func NewConfig(overrides map[string]string) (Config, error) {
+	config := Config{severities: make(map[string]Severity, len(rules))}
+	for name, rule := range rules {
+		config.severities[name] = rule.Severity
+	}
+	for name, value := range overrides {
+		if err := config.Set(name, value); err != nil {
+			return Config{}, err
+		}
+	}
+	return config, nil
+}
+
+// Set changes the severity of a rule.
+func (config Config) Set(name, value string) error {
+	if _, exists := rules[name]; !exists {
+		return fmt.Errorf("unknown lint rule %q", name)
+	}
+	severity, err := ParseSeverity(value)
+	if err != nil {
+		return fmt.Errorf("lint rule %s: %w", name, err)
+	}
+	config.severities[name] = severity
+	return nil
+}
+
+// Severity returns the configured severity of a rule.
+func (config Config) Severity(name string) Severity {
+	if name == SyntaxRule {
+		return Error
+	}
+	if severity, exists := config.severities[name]; exists {
+		return severity
+	}
+	if rule, exists := rules[name]; exists {
+		return rule.Severity
+	}
+	return Off
+}
+
+// Diagnostic is a finding of a rule.
+type Diagnostic struct {
+	Rule     string
+	Severity Severity
+	// Line is the 1-based line in the checked document.
+	Line    int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d: %s: %s [%s]", d.Line, d.Severity, d.Message, d.Rule)
+}
+
+// Document checks all diagrams embedded in content. Diagrams that cannot be parsed are
+// reported with the syntax rule.
+func Document(content string, syntax reader.Syntax, config Config) ([]Diagnostic, error) {
+	blocks, err := reader.ExtractBlocks(content, syntax)
+	if err != nil {
+		return nil, err
+	}
+
+	var diagnostics []Diagnostic
+	for _, block := range blocks {
+		for _, d := range Diagram(block.Content, config) {
+			d.Line += block.Line - 1
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	return diagnostics, nil
+}
+
+// Diagram checks the text of a single diagram. The lines of the diagnostics are relative to text.
+func Diagram(text string, config Config) []Diagnostic {
+	diagram, err := reader.ParseDiagram(text)
+	if err != nil {
+		return []Diagnostic{{Rule: SyntaxRule, Severity: Error, Line: errorLine(err), Message: err.Error()}}
+	}
+
+	ignored := ignoredRules(text, diagram)
+
+	var diagnostics []Diagnostic
+	for _, rule := range Rules() {
+		severity := config.Severity(rule.Name)
+		if severity == Off {
+			continue
+		}
+
+		name := rule.Name
+		report := func(line int, format string, args ...any) {
+			if ignored.has(line, name) {
+				return
+			}
+			diagnostics = append(diagnostics, Diagnostic{
+				Rule:     name,
+				Severity: severity,
+				Line:     line,
+				Message:  fmt.Sprintf(format, args...),
+			})
+		}
+
+		if diagram.IsClass && rule.Class != nil {
+			rule.Class(diagram.Class, report)
+		}
+		if diagram.IsSequence && rule.Sequence != nil {
+			rule.Sequence(diagram.Sequence, report)
+		}
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].Line < diagnostics[j].Line
+	})
+	return diagnostics
+}
+
+// errorLine returns the line of a parse error, 1 if it has none.
+func errorLine(err error) int {
+	var parseErr participle.Error
+	if errors.As(err, &parseErr) && parseErr.Position().Line > 0 {
+		return parseErr.Position().Line
+	}
+	return 1
+}
@@ -0,0 +1,130 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+)
+
+// findings returns rule@line for every diagnostic.
+func findings(diagnostics []Diagnostic) string {
+	var names []string
+	for _, d := range diagnostics {
+		names = append(names, fmt.Sprintf("%s@%d", d.Rule, d.Line))
+	}
+	return strings.Join(names, " ")
+}
+
+func TestClassRules(t *testing.T) {
+	input := `classDiagram
+class Order {
+    +int id
+    total() Money
+    +status
+    +int id
+    +Cancel()
+    +Order(int id)
+}
+Order --> Item
+Order --> Customer
+Customer : +String name
+`
+	config, err := NewConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := findings(Diagram(input, config))
+	want := "missing-visibility@4 untyped-attribute@5 duplicate-member@6 method-case@7 unused-relationship@10"
+	if got != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+}
+
+func TestSequenceRules(t *testing.T) {
+	input := `sequenceDiagram
+participant A
+participant B
+participant C
+A->>B: start()
+B->>A: query()
+A-->>B: answer
+alt ok
+    B-->>A: done
+else
+    B-->>C: wrong
+end
+A->>B: again()
+`
+	config, err := NewConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := findings(Diagram(input, config))
+	want := "unmatched-call@11 unmatched-call@13"
+	if got != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+}
+
+func TestIgnoreAndSeverity(t *testing.T) {
+	input := `classDiagram
+%% merfolk-ignore missing-visibility
+Order : int id
+Order : String name %% merfolk-ignore
+Order : Money total
+`
+	config, err := NewConfig(map[string]string{"untyped-attribute": "off", "missing-visibility": "error"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diagnostics := Diagram(input, config)
+	if got := findings(diagnostics); got != "missing-visibility@5" {
+		t.Errorf("got %s", got)
+	}
+	if len(diagnostics) == 1 && diagnostics[0].Severity != Error {
+		t.Errorf("severity not configured: %v", diagnostics[0])
+	}
+
+	if _, err := NewConfig(map[string]string{"no-such-rule": "error"}); err == nil {
+		t.Error("expected error for unknown rule")
+	}
+	if _, err := NewConfig(map[string]string{"method-case": "fatal"}); err == nil {
+		t.Error("expected error for unknown severity")
+	}
+}
+
+func TestDocument(t *testing.T) {
+	content, err := os.ReadFile("../../example/example.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := NewConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diagnostics, err := Document(string(content), reader.Markdown, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range diagnostics {
+		if d.Severity == Error {
+			t.Errorf("unexpected error in the example: %v", d)
+		}
+	}
+
+	broken := "Text\n\n```mermaid\nclassDiagram\nclass\n```\n"
+	diagnostics, err = Document(broken, reader.Markdown, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := findings(diagnostics); got != "syntax@5" {
+		t.Errorf("got %s", got)
+	}
+}
@@ -0,0 +1,147 @@
+package lint
+
+import (
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+)
+
+const (
+	callArrow  = "->>"
+	replyArrow = "-->>"
+)
+
+func init() {
+	Register(&Rule{
+		Name:        "unmatched-call",
+		Description: "->> calls without a -->> reply and replies without a call",
+		Severity:    Warning,
+		Sequence:    unmatchedCalls,
+	})
+
+	Register(&Rule{
+		Name:        "unused-participant",
+		Description: "participants and actors that are declared but never send or receive a message",
+		Severity:    Warning,
+		Sequence: func(diagram *reader.SequenceDiagram, report Reporter) {
+			used := make(map[string]bool)
+			for _, instruction := range diagram.Instructions {
+				if instruction.Message != nil {
+					used[instruction.Message.Left] = true
+					used[instruction.Message.Right] = true
+				}
+			}
+
+			for _, instruction := range diagram.Instructions {
+				var kind, name string
+				switch {
+				case instruction.Member != nil:
+					kind, name = instruction.Member.Type, instruction.Member.Name
+				case instruction.Life != nil && instruction.Life.Type == "create":
+					kind, name = "participant", instruction.Life.Name
+					if instruction.Life.On != "" {
+						kind = instruction.Life.On
+					}
+				default:
+					continue
+				}
+				if !used[name] {
+					report(instruction.Pos.Line, "%s %s is never used in a message", kind, name)
+				}
+			}
+		},
+	})
+}
+
+// call is a ->> message that waits for its reply.
+type call struct {
+	caller, callee string
+	line           int
+}
+
+// block is an open alt or loop block.
+type block struct {
+	line int
+	alt  bool
+	// start is the call stack in front of the block
+	start []call
+	// after is the call stack at the end of the first branch, the stack behind the block
+	after []call
+	// branches is the number of completed branches
+	branches int
+}
+
+// unmatchedCalls follows the call stack of a sequence diagram: every A->>B call must be
+// answered by a B-->>A reply before the caller returns itself. Calls made in a branch of an
+// alt block must be answered in the same branch. Every branch starts with the stack in front
+// of the block, the stack behind the block is the one left by the first branch.
+func unmatchedCalls(diagram *reader.SequenceDiagram, report Reporter) {
+	var stack []call
+	var blocks []*block
+
+	unanswered := func(calls []call) {
+		for _, open := range calls {
+			report(open.line, "call %s->>%s is never answered with %s-->>%s",
+				open.caller, open.callee, open.callee, open.caller)
+		}
+	}
+
+	// endBranch reports the calls left open in the current alt branch
+	endBranch := func() {
+		if len(blocks) == 0 || !blocks[len(blocks)-1].alt {
+			return
+		}
+		b := blocks[len(blocks)-1]
+		var remaining []call
+		for _, open := range stack {
+			if open.line > b.line {
+				unanswered([]call{open})
+			} else {
+				remaining = append(remaining, open)
+			}
+		}
+		if b.branches == 0 {
+			b.after = remaining
+		}
+		b.branches++
+		stack = append([]call{}, b.start...)
+	}
+
+	for _, instruction := range diagram.Instructions {
+		line := instruction.Pos.Line
+		switch {
+		case instruction.Alt != nil:
+			blocks = append(blocks, &block{line: line, alt: true, start: append([]call{}, stack...)})
+		case instruction.Loop != nil:
+			blocks = append(blocks, &block{line: line})
+		case instruction.Else != nil:
+			endBranch()
+		case instruction.End != nil:
+			endBranch()
+			if len(blocks) > 0 {
+				if b := blocks[len(blocks)-1]; b.alt {
+					stack = b.after
+				}
+				blocks = blocks[:len(blocks)-1]
+			}
+		case instruction.Message != nil:
+			message := instruction.Message
+			switch message.Type {
+			case callArrow:
+				stack = append(stack, call{caller: message.Left, callee: message.Right, line: line})
+			case replyArrow:
+				if len(stack) == 0 {
+					report(line, "reply %s-->>%s has no matching call", message.Left, message.Right)
+					continue
+				}
+				top := stack[len(stack)-1]
+				if top.callee != message.Left || top.caller != message.Right {
+					report(line, "reply %s-->>%s does not answer the open call %s->>%s on line %d",
+						message.Left, message.Right, top.caller, top.callee, top.line)
+					continue
+				}
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+
+	unanswered(stack)
+}
@@ -0,0 +1,139 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// JSON-RPC error codes used by the server.
+const (
+	codeParseError     = -32700
+	codeInvalidParams  = -32602
+	codeMethodNotFound = -32601
+	codeInternalError  = -32603
+	// codeServerNotInitialized is returned for requests before initialize.
+	codeServerNotInitialized = -32002
+)
+
+// rpcError is the error of a JSON-RPC response.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// message is an incoming request or notification. Notifications have no ID.
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type notification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+// conn reads and writes JSON-RPC messages with the base protocol framing of LSP: every message
+// is preceded by a Content-Length header and an empty line.
+type conn struct {
+	in *bufio.Reader
+
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func newConn(in io.Reader, out io.Writer) *conn {
+	return &conn{in: bufio.NewReader(in), out: out}
+}
+
+// read returns the next message. It returns io.EOF when the input is closed.
+func (c *conn) read() (*message, error) {
+	body, err := c.readBody()
+	if err != nil {
+		return nil, err
+	}
+
+	var m message
+	if err := json.Unmarshal(body, &m); err != nil {
+		return &m, &rpcError{Code: codeParseError, Message: err.Error()}
+	}
+	return &m, nil
+}
+
+// readBody returns the content of the next message.
+func (c *conn) readBody() ([]byte, error) {
+	header, err := textproto.NewReader(c.in).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF && len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("invalid message header: %w", err)
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.in, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// reply answers the request with the given id, with result if err is nil.
+func (c *conn) reply(id json.RawMessage, result any, err error) error {
+	r := response{JSONRPC: "2.0", ID: id}
+	if err != nil {
+		rpcErr, ok := err.(*rpcError)
+		if !ok {
+			rpcErr = &rpcError{Code: codeInternalError, Message: err.Error()}
+		}
+		r.Error = rpcErr
+	} else {
+		content, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		r.Result = content
+	}
+	return c.write(r)
+}
+
+// notify sends a notification to the client.
+func (c *conn) notify(method string, params any) error {
+	return c.write(notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (c *conn) write(value any) error {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := fmt.Fprintf(c.out, "Content-Length: %d\r\n\r\n", len(content)); err != nil {
+		return err
+	}
+	_, err = c.out.Write(content)
+	return err
+}
@@ -0,0 +1,161 @@
+package lsp
+
+import (
+	"strings"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+)
+
+// document is the text of an open editor buffer or of a file in the input directory.
+type document struct {
+	uri    string
+	syntax reader.Syntax
+	text   string
+	lines  []string
+	// version is the version of an open document, nil for files read from disk
+	version *int
+
+	blocks []block
+	// blocksErr is set if the diagrams could not be extracted, e.g. because of an unclosed fence
+	blocksErr error
+}
+
+// block is a diagram of a document with its parse result.
+type block struct {
+	reader.Block
+	diagram *reader.Diagram
+	err     error
+}
+
+func newDocument(uri, text string, version *int) *document {
+	d := &document{
+		uri:     uri,
+		syntax:  reader.SyntaxOf(uriToPath(uri)),
+		text:    text,
+		lines:   strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n"),
+		version: version,
+	}
+
+	blocks, err := reader.ExtractBlocks(text, d.syntax)
+	if err != nil {
+		d.blocksErr = err
+		return d
+	}
+	for _, b := range blocks {
+		diagram, err := reader.ParseDiagram(b.Content)
+		d.blocks = append(d.blocks, block{Block: b, diagram: diagram, err: err})
+	}
+	return d
+}
+
+// blockAt returns the diagram that contains the zero-based line, or nil.
+func (d *document) blockAt(line int) *block {
+	for i := range d.blocks {
+		b := &d.blocks[i]
+		if line+1 >= b.Line && line+1 < b.EndLine {
+			return b
+		}
+	}
+	return nil
+}
+
+// line returns the zero-based line, or an empty string if it does not exist.
+func (d *document) line(line int) string {
+	if line < 0 || line >= len(d.lines) {
+		return ""
+	}
+	return d.lines[line]
+}
+
+// lineRange returns the range of the text on a zero-based line without the indentation.
+func (d *document) lineRange(line int) Range {
+	text := d.line(line)
+	start := len(text) - len(strings.TrimLeft(text, " \t"))
+	return Range{
+		Start: Position{Line: line, Character: utf16Len(text[:start])},
+		End:   Position{Line: line, Character: utf16Len(text)},
+	}
+}
+
+// offset converts the character of a position to a byte offset in its line.
+func (d *document) offset(position Position) int {
+	text := d.line(position.Line)
+	units := 0
+	for i, r := range text {
+		if units >= position.Character {
+			return i
+		}
+		units += runeLen(r)
+	}
+	return len(text)
+}
+
+// wordAt returns the identifier at a position and its range.
+func (d *document) wordAt(position Position) (string, Range, bool) {
+	text := d.line(position.Line)
+	offset := d.offset(position)
+	start, end := offset, offset
+	for start > 0 && isWordByte(text[start-1]) {
+		start--
+	}
+	for end < len(text) && isWordByte(text[end]) {
+		end++
+	}
+	if start == end {
+		return "", Range{}, false
+	}
+	return text[start:end], d.byteRange(position.Line, start, end), true
+}
+
+// byteRange returns the range of the bytes start to end of a zero-based line.
+func (d *document) byteRange(line, start, end int) Range {
+	text := d.line(line)
+	return Range{
+		Start: Position{Line: line, Character: utf16Len(text[:start])},
+		End:   Position{Line: line, Character: utf16Len(text[:end])},
+	}
+}
+
+// findWord returns the range of a whole-word occurrence of word on a zero-based line, starting
+// the search at the byte offset from. With last the last occurrence is returned, otherwise the
+// first. If word does not occur, the range of the line is returned.
+func (d *document) findWord(line, from int, word string, last bool) Range {
+	text := d.line(line)
+	found := -1
+	for i := from; i >= 0 && i+len(word) <= len(text); i++ {
+		if text[i:i+len(word)] != word {
+			continue
+		}
+		if (i > 0 && isWordByte(text[i-1])) || (i+len(word) < len(text) && isWordByte(text[i+len(word)])) {
+			continue
+		}
+		found = i
+		if !last {
+			break
+		}
+	}
+	if found < 0 {
+		return d.lineRange(line)
+	}
+	return d.byteRange(line, found, found+len(word))
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeLen(r)
+	}
+	return n
+}
+
+// runeLen returns the number of UTF-16 code units of r.
+func runeLen(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
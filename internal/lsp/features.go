@@ -0,0 +1,218 @@
+package lsp
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/lint"
+)
+
+// diagnostics returns the parse errors and lint findings of a document and the errors of the
+// transformation of its diagrams.
+func (s *Server) diagnostics(d *document, idx *index) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	if d.blocksErr != nil {
+		return append(diagnostics, Diagnostic{Range: d.lineRange(0), Severity: SeverityError, Source: "merfolk", Message: d.blocksErr.Error()})
+	}
+
+	findings, _ := lint.Document(d.text, d.syntax, s.lint)
+	for _, finding := range findings {
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    d.lineRange(finding.Line - 1),
+			Severity: severity(finding.Severity),
+			Code:     finding.Rule,
+			Source:   "merfolk",
+			Message:  finding.Message,
+		})
+	}
+
+	for _, problem := range idx.diagnostics[d.uri] {
+		line := problem.Line - 1
+		if line < 0 {
+			line = 0
+		}
+		diagnostics = append(diagnostics, Diagnostic{Range: d.lineRange(line), Severity: SeverityError, Source: "merfolk", Message: problem.Message})
+	}
+	return diagnostics
+}
+
+func severity(s lint.Severity) DiagnosticSeverity {
+	switch s {
+	case lint.Error:
+		return SeverityError
+	case lint.Warning:
+		return SeverityWarning
+	}
+	return SeverityInformation
+}
+
+const arrowPattern = `(?:(?:<<)?--?>>|--?[>x)])`
+
+var (
+	// messageNamePrefix matches a message line up to the method name: "A->>B: na"
+	messageNamePrefix = regexp.MustCompile(`^\s*\w+\s*` + arrowPattern + `\s*(\w+)\s*:\s*\w*$`)
+	// messageTargetPrefix matches a message line up to the receiver: "A->>B"
+	messageTargetPrefix = regexp.MustCompile(`^\s*\w+\s*` + arrowPattern + `\s*\w*$`)
+	// participantPrefix matches the lines that name a participant: "participant A", "activate A"
+	participantPrefix = regexp.MustCompile(`(?i)^\s*(?:(?:create\s+)?(?:participant|actor)|destroy|activate|deactivate)\s+\w*$`)
+	// statementPrefix matches the beginning of a line, where a message starts with the sender
+	statementPrefix = regexp.MustCompile(`^\s*\w*$`)
+
+	participantLine = regexp.MustCompile(`(?i)^\s*(?:create\s+)?(?:participant|actor)\s+(\w+)`)
+	messageLine     = regexp.MustCompile(`^\s*(\w+)\s*` + arrowPattern + `\s*(\w+)\s*:`)
+	sequenceKeyword = regexp.MustCompile(`(?m)^\s*sequenceDiagram\b`)
+)
+
+// completion completes methods and participants in sequence diagrams. The text of the diagram
+// is read line by line, so completion also works while the diagram does not parse.
+func (s *Server) completion(params *TextDocumentPositionParams) (any, error) {
+	list := CompletionList{Items: []CompletionItem{}}
+
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	b := d.blockAt(params.Position.Line)
+	if b == nil || !sequenceKeyword.MatchString(b.Content) {
+		return list, nil
+	}
+	idx := s.currentIndex()
+	prefix := d.line(params.Position.Line)[:d.offset(params.Position)]
+
+	if match := messageNamePrefix.FindStringSubmatch(prefix); match != nil {
+		list.Items = s.methodItems(idx, match[1])
+		return list, nil
+	}
+	if messageTargetPrefix.MatchString(prefix) || participantPrefix.MatchString(prefix) || statementPrefix.MatchString(prefix) {
+		list.Items = s.participantItems(idx, b)
+	}
+	return list, nil
+}
+
+// methodItems returns the methods of a class: the operations of the class diagrams and the
+// methods added by sequence diagrams.
+func (s *Server) methodItems(idx *index, className string) []CompletionItem {
+	items := []CompletionItem{}
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		detail, _ := idx.javaMember(className, name)
+		items = append(items, CompletionItem{Label: name, Kind: CompletionKindMethod, Detail: detail})
+	}
+
+	if c, exists := idx.classes[className]; exists {
+		for _, m := range c.members {
+			if m.operation {
+				add(m.name)
+			}
+		}
+	}
+	if class, exists := idx.model.Classes[className]; exists {
+		for _, method := range class.Methods {
+			add(method.Name)
+		}
+	}
+	return items
+}
+
+// participantItems returns the participants of the sequence diagram b and the known classes.
+func (s *Server) participantItems(idx *index, b *block) []CompletionItem {
+	items := []CompletionItem{}
+	seen := make(map[string]bool)
+	for _, name := range idx.classNames() {
+		seen[name] = true
+		detail, _ := idx.javaType(name, s.config.Naming.InterfacePrefix)
+		items = append(items, CompletionItem{Label: name, Kind: CompletionKindClass, Detail: detail})
+	}
+
+	for _, line := range strings.Split(b.Content, "\n") {
+		var names []string
+		if match := participantLine.FindStringSubmatch(line); match != nil {
+			names = match[1:2]
+		} else if match := messageLine.FindStringSubmatch(line); match != nil {
+			names = match[1:3]
+		}
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				items = append(items, CompletionItem{Label: name, Kind: CompletionKindVariable, Detail: "participant"})
+			}
+		}
+	}
+	return items
+}
+
+// symbolAt returns the class and, if the word at the position is a member, the member name.
+// The word must be inside of a diagram.
+func (s *Server) symbolAt(params *TextDocumentPositionParams) (*index, string, string, Range, error) {
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, "", "", Range{}, err
+	}
+	if d.blockAt(params.Position.Line) == nil {
+		return nil, "", "", Range{}, nil
+	}
+	word, wordRange, ok := d.wordAt(params.Position)
+	if !ok {
+		return nil, "", "", Range{}, nil
+	}
+
+	idx := s.currentIndex()
+	if r := idx.reference(d.uri, params.Position.Line); r != nil && r.member == word {
+		return idx, r.class, r.member, wordRange, nil
+	}
+	if _, exists := idx.classes[word]; exists {
+		return idx, word, "", wordRange, nil
+	}
+	if _, exists := idx.model.Classes[word]; exists {
+		return idx, word, "", wordRange, nil
+	}
+	return nil, "", "", Range{}, nil
+}
+
+// hover shows the Java declaration generated for the class or member at the position.
+func (s *Server) hover(params *TextDocumentPositionParams) (any, error) {
+	idx, className, memberName, wordRange, err := s.symbolAt(params)
+	if err != nil || idx == nil {
+		return nil, err
+	}
+
+	var declaration string
+	var ok bool
+	if memberName != "" {
+		declaration, ok = idx.javaMember(className, memberName)
+	} else {
+		declaration, ok = idx.javaType(className, s.config.Naming.InterfacePrefix)
+	}
+	if !ok {
+		return nil, nil
+	}
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: "```java\n" + declaration + "\n```"},
+		Range:    &wordRange,
+	}, nil
+}
+
+// definition returns the declaration of the class or member at the position in the class
+// diagrams.
+func (s *Server) definition(params *TextDocumentPositionParams) (any, error) {
+	idx, className, memberName, _, err := s.symbolAt(params)
+	if err != nil || idx == nil {
+		return nil, err
+	}
+
+	c, exists := idx.classes[className]
+	if !exists {
+		return nil, nil
+	}
+	if memberName == "" {
+		return []Location{c.location}, nil
+	}
+	if m := c.member(memberName); m != nil {
+		return []Location{m.location}, nil
+	}
+	return nil, nil
+}
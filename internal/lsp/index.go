@@ -0,0 +1,200 @@
+package lsp
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/config"
+	"github.com/MarmaidTranspiler/Merfolk/internal/connector"
+	"github.com/MarmaidTranspiler/Merfolk/internal/project"
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+)
+
+// class is a class declared in the class diagrams of the workspace.
+type class struct {
+	name     string
+	location Location
+	// declared is set if location is a class declaration and not the first member line
+	declared bool
+	members  []*member
+}
+
+// member is an attribute or operation of a class.
+type member struct {
+	name      string
+	operation bool
+	location  Location
+}
+
+func (c *class) member(name string) *member {
+	for _, m := range c.members {
+		if m.name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// reference is a line of a diagram that names a member of a class: a member line of a class
+// diagram or a message of a sequence diagram.
+type reference struct {
+	// line is the zero-based line in the document
+	line   int
+	class  string
+	member string
+}
+
+// index holds the classes of all documents of the workspace and the model generated from them.
+type index struct {
+	classes    map[string]*class
+	references map[string][]reference
+	model      *connector.Model
+	// diagnostics are the problems found while building the model, by document URI
+	diagnostics map[string][]project.Diagnostic
+}
+
+// buildIndex collects the classes of the documents and transforms their diagrams like convert.
+func buildIndex(cfg *config.Config, documents []*document) *index {
+	idx := &index{
+		classes:     make(map[string]*class),
+		references:  make(map[string][]reference),
+		diagnostics: make(map[string][]project.Diagnostic),
+	}
+
+	sorted := append([]*document(nil), documents...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].uri < sorted[j].uri })
+
+	var parsed []project.Document
+	for _, d := range sorted {
+		var diagrams []reader.Diagram
+		for i := range d.blocks {
+			b := &d.blocks[i]
+			if b.diagram == nil {
+				continue
+			}
+			diagram := *b.diagram
+			diagram.Line = b.Line
+			diagrams = append(diagrams, diagram)
+
+			if diagram.IsClass {
+				idx.addClassDiagram(d, b)
+			} else if diagram.IsSequence {
+				idx.addSequenceDiagram(d, b)
+			}
+		}
+		parsed = append(parsed, project.Document{Path: d.uri, Diagrams: diagrams})
+	}
+
+	p := project.Build(cfg, parsed, func(diagnostic project.Diagnostic) {
+		idx.diagnostics[diagnostic.Path] = append(idx.diagnostics[diagnostic.Path], diagnostic)
+	})
+	idx.model = p.Model
+
+	return idx
+}
+
+// documentLine returns the zero-based document line and the byte offset of an AST position
+// in the diagram text of b.
+func documentLine(b *block, line, column int) (int, int) {
+	return b.Line + line - 2, b.Indent + column - 1
+}
+
+func (idx *index) addClassDiagram(d *document, b *block) {
+	for _, instruction := range b.diagram.Class.Instructions {
+		switch {
+		case instruction.Namespace != nil:
+			for _, declaration := range instruction.Namespace.Classes {
+				idx.addClassDeclaration(d, b, declaration)
+			}
+
+		case instruction.Class != nil:
+			idx.addClassDeclaration(d, b, instruction.Class)
+
+		case instruction.Member != nil:
+			line, offset := documentLine(b, instruction.Pos.Line, instruction.Pos.Column)
+			c := idx.class(instruction.Member.Class, Location{URI: d.uri, Range: d.findWord(line, offset, instruction.Member.Class, false)}, false)
+
+			// The member follows the colon
+			colon := strings.Index(d.line(line)[offset:], ":")
+			if colon < 0 {
+				continue
+			}
+			idx.addMember(d, c, line, offset+colon, instruction.Member.Operation, instruction.Member.Attribute)
+		}
+	}
+}
+
+func (idx *index) addClassDeclaration(d *document, b *block, declaration *reader.ClassDeclaration) {
+	line, offset := documentLine(b, declaration.Pos.Line, declaration.Pos.Column)
+	// Skip the class keyword
+	c := idx.class(declaration.Name, Location{URI: d.uri, Range: d.findWord(line, offset+len("class"), declaration.Name, false)}, true)
+
+	for _, body := range declaration.Members {
+		line, offset := documentLine(b, body.Pos.Line, body.Pos.Column)
+		idx.addMember(d, c, line, offset, body.Operation, body.Attribute)
+	}
+}
+
+// class returns the class with the given name and adds it if it is not known yet. A class
+// declaration replaces the location of a class that was first seen on a member line.
+func (idx *index) class(name string, location Location, declared bool) *class {
+	c, exists := idx.classes[name]
+	if !exists {
+		c = &class{name: name, location: location, declared: declared}
+		idx.classes[name] = c
+	} else if declared && !c.declared {
+		c.location, c.declared = location, true
+	}
+	return c
+}
+
+// addMember adds the member on a line, the name is searched from the byte offset on.
+func (idx *index) addMember(d *document, c *class, line, offset int, operation *reader.Operation, attribute *reader.Attribute) {
+	m := &member{}
+	switch {
+	case operation != nil:
+		// The name of an operation comes first, the return type last
+		m.name, m.operation = operation.Name, true
+		m.location = Location{URI: d.uri, Range: d.findWord(line, offset, operation.Name, false)}
+	case attribute != nil:
+		// The name of an attribute follows its type
+		m.name = attribute.Name
+		m.location = Location{URI: d.uri, Range: d.findWord(line, offset, attribute.Name, true)}
+	default:
+		return
+	}
+
+	if c.member(m.name) == nil {
+		c.members = append(c.members, m)
+	}
+	idx.references[d.uri] = append(idx.references[d.uri], reference{line: line, class: c.name, member: m.name})
+}
+
+func (idx *index) addSequenceDiagram(d *document, b *block) {
+	for _, instruction := range b.diagram.Sequence.Instructions {
+		if message := instruction.Message; message != nil {
+			line, _ := documentLine(b, instruction.Pos.Line, instruction.Pos.Column)
+			idx.references[d.uri] = append(idx.references[d.uri], reference{line: line, class: message.Right, member: message.Name})
+		}
+	}
+}
+
+// reference returns the reference on a zero-based line of a document.
+func (idx *index) reference(uri string, line int) *reference {
+	for i, r := range idx.references[uri] {
+		if r.line == line {
+			return &idx.references[uri][i]
+		}
+	}
+	return nil
+}
+
+// classNames returns the names of all classes ordered by name.
+func (idx *index) classNames() []string {
+	names := make([]string, 0, len(idx.classes))
+	for name := range idx.classes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,112 @@
+package lsp
+
+import (
+	"strings"
+
+	generator "github.com/MarmaidTranspiler/Merfolk/internal/CodeTemplateGenerator"
+)
+
+// The functions below print the declarations of the generated Java code for hover texts.
+// They follow the class and interface templates.
+
+func javaClass(class *generator.Class) string {
+	var b strings.Builder
+	if class.Package != "" {
+		b.WriteString("package " + class.Package + ";\n\n")
+	}
+	b.WriteString("public class " + class.ClassName)
+	if class.Inherits != "" {
+		b.WriteString(" extends " + class.Inherits)
+	}
+	if len(class.Abstraction) > 0 {
+		b.WriteString(" implements " + strings.Join(class.Abstraction, ", "))
+	}
+	return b.String()
+}
+
+func javaInterface(iface *generator.Interface, prefix string) string {
+	var b strings.Builder
+	if iface.Package != "" {
+		b.WriteString("package " + iface.Package + ";\n\n")
+	}
+	b.WriteString("public interface " + prefix + iface.InterfaceName)
+	if len(iface.Inherits) > 0 {
+		b.WriteString(" extends " + strings.Join(iface.Inherits, ", "))
+	}
+	return b.String()
+}
+
+func javaMethod(method generator.Method) string {
+	var b strings.Builder
+	if method.AccessModifier != "" {
+		b.WriteString(method.AccessModifier + " ")
+	}
+	if method.IsStatic {
+		b.WriteString("static ")
+	}
+	b.WriteString(method.ReturnType + " " + method.Name + "(")
+	for i, parameter := range method.Parameters {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(parameter.Type + " " + parameter.Name)
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+func javaAttribute(attribute generator.Attribute) string {
+	var b strings.Builder
+	if attribute.AccessModifier != "" {
+		b.WriteString(attribute.AccessModifier + " ")
+	}
+	if attribute.IsClassVariable {
+		b.WriteString("static ")
+	}
+	if attribute.IsConstant {
+		b.WriteString("final ")
+	}
+	b.WriteString(attribute.Type + " " + attribute.Name)
+	return b.String()
+}
+
+// javaMember returns the declaration of a member of the class or, if there is no such class,
+// of the interface of the given name.
+func (idx *index) javaMember(className, name string) (string, bool) {
+	if class, exists := idx.model.Classes[className]; exists {
+		for _, method := range class.Methods {
+			if method.Name == name {
+				return javaMethod(method), true
+			}
+		}
+		for _, attribute := range class.Attributes {
+			if attribute.Name == name {
+				return javaAttribute(attribute), true
+			}
+		}
+	}
+	if iface, exists := idx.model.Interfaces[className]; exists {
+		for _, method := range iface.AbstractMethods {
+			if method.Name == name {
+				return javaMethod(method), true
+			}
+		}
+		for _, attribute := range iface.AbstractAttributes {
+			if attribute.Name == name {
+				return javaAttribute(attribute), true
+			}
+		}
+	}
+	return "", false
+}
+
+// javaType returns the declaration of the class or interface of the given name.
+func (idx *index) javaType(name, interfacePrefix string) (string, bool) {
+	if class, exists := idx.model.Classes[name]; exists {
+		return javaClass(class), true
+	}
+	if iface, exists := idx.model.Interfaces[name]; exists {
+		return javaInterface(iface, interfacePrefix), true
+	}
+	return "", false
+}
@@ -0,0 +1,263 @@
+package lsp
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// client drives a server over pipes like an editor.
+type client struct {
+	t    *testing.T
+	conn *conn
+	id   int
+	done chan error
+	// diagnostics are the last published diagnostics by URI
+	diagnostics map[string][]Diagnostic
+}
+
+func newClient(t *testing.T, options Options) *client {
+	t.Helper()
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	c := &client{t: t, conn: newConn(clientIn, clientOut), done: make(chan error, 1), diagnostics: make(map[string][]Diagnostic)}
+	go func() {
+		err := NewServer(options).Serve(serverIn, serverOut)
+		serverOut.Close()
+		c.done <- err
+	}()
+	t.Cleanup(func() { clientOut.Close() })
+	return c
+}
+
+// call sends a request and returns its result. Notifications received in between are recorded.
+func (c *client) call(method string, params, result any) {
+	c.t.Helper()
+	c.id++
+	id, _ := json.Marshal(c.id)
+	if err := c.conn.write(map[string]any{"jsonrpc": "2.0", "id": json.RawMessage(id), "method": method, "params": params}); err != nil {
+		c.t.Fatal(err)
+	}
+
+	for {
+		var r struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+			Result json.RawMessage `json:"result"`
+			Error  *rpcError       `json:"error"`
+		}
+		c.receive(&r)
+		if r.Method != "" {
+			c.record(r.Method, r.Params)
+			continue
+		}
+		if string(r.ID) != string(id) {
+			c.t.Fatalf("response to request %s, expected %s", r.ID, id)
+		}
+		if r.Error != nil {
+			c.t.Fatalf("%s: %v", method, r.Error)
+		}
+		if result != nil {
+			if err := json.Unmarshal(r.Result, result); err != nil {
+				c.t.Fatal(err)
+			}
+		}
+		return
+	}
+}
+
+// notify sends a notification and records the diagnostics published for open documents.
+func (c *client) notify(method string, params any, diagnostics int) {
+	c.t.Helper()
+	if err := c.conn.write(notification{JSONRPC: "2.0", Method: method, Params: params}); err != nil {
+		c.t.Fatal(err)
+	}
+	for i := 0; i < diagnostics; i++ {
+		var raw struct {
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		c.receive(&raw)
+		c.record(raw.Method, raw.Params)
+	}
+}
+
+func (c *client) receive(value any) {
+	c.t.Helper()
+	body, err := c.conn.readBody()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if err := json.Unmarshal(body, value); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *client) record(method string, params json.RawMessage) {
+	if method != "textDocument/publishDiagnostics" {
+		return
+	}
+	var published PublishDiagnosticsParams
+	if err := json.Unmarshal(params, &published); err != nil {
+		c.t.Fatal(err)
+	}
+	c.diagnostics[published.URI] = published.Diagnostics
+}
+
+const orders = "# Orders\n" +
+	"\n" +
+	"```mermaid\n" +
+	"classDiagram\n" +
+	"class Order\n" +
+	"Order : +int id\n" +
+	"Order : +total() int\n" +
+	"```\n"
+
+const checkout = "# Checkout\n" +
+	"\n" +
+	"```mermaid\n" +
+	"sequenceDiagram\n" +
+	"participant Shop\n" +
+	"Shop->>Order: total()\n" +
+	"Order-->>Shop: sum\n" +
+	"```\n"
+
+func position(uri string, line, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{Line: line, Character: character}}
+}
+
+func TestServer(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "orders.md"), []byte(orders), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	findFiles := func(dir string, include, exclude []string) ([]string, error) {
+		return []string{"orders.md"}, nil
+	}
+	ordersURI := pathToURI(filepath.Join(root, "orders.md"))
+	uri := pathToURI(filepath.Join(root, "checkout.md"))
+
+	c := newClient(t, Options{FindFiles: findFiles})
+
+	var initialized InitializeResult
+	c.call("initialize", InitializeParams{RootURI: pathToURI(root)}, &initialized)
+	if !initialized.Capabilities.HoverProvider || initialized.Capabilities.TextDocumentSync.Change != TextDocumentSyncKindFull {
+		t.Errorf("unexpected capabilities %+v", initialized.Capabilities)
+	}
+	c.notify("initialized", struct{}{}, 0)
+
+	// The class diagram is read from the workspace, the sequence diagram is opened
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uri, Version: 1, Text: checkout}}, 1)
+	if diagnostics := c.diagnostics[uri]; len(diagnostics) != 0 {
+		t.Errorf("unexpected diagnostics %+v", diagnostics)
+	}
+
+	t.Run("completion", func(t *testing.T) {
+		var list CompletionList
+		c.call("textDocument/completion", position(uri, 5, len("Shop->>Order: ")), &list)
+		if len(list.Items) != 1 || list.Items[0].Label != "total" || list.Items[0].Detail != "public int total()" {
+			t.Errorf("unexpected method completion %+v", list.Items)
+		}
+
+		c.call("textDocument/completion", position(uri, 5, len("Shop->>")), &list)
+		var labels []string
+		for _, item := range list.Items {
+			labels = append(labels, item.Label)
+		}
+		if strings.Join(labels, " ") != "Order Shop" {
+			t.Errorf("unexpected participant completion %v", labels)
+		}
+
+		c.call("textDocument/completion", position(uri, 0, 2), &list)
+		if len(list.Items) != 0 {
+			t.Errorf("unexpected completion outside of a diagram %+v", list.Items)
+		}
+	})
+
+	t.Run("hover", func(t *testing.T) {
+		var hover Hover
+		c.call("textDocument/hover", position(uri, 5, len("Shop->>Order: to")), &hover)
+		if !strings.Contains(hover.Contents.Value, "public int total()") || hover.Range.Start.Character != len("Shop->>Order: ") {
+			t.Errorf("unexpected method hover %+v", hover)
+		}
+
+		c.call("textDocument/hover", position(uri, 5, len("Shop->>Or")), &hover)
+		if !strings.Contains(hover.Contents.Value, "public class Order") {
+			t.Errorf("unexpected class hover %+v", hover)
+		}
+
+		var none *Hover
+		c.call("textDocument/hover", position(uri, 0, 4), &none)
+		if none != nil {
+			t.Errorf("unexpected hover outside of a diagram %+v", none)
+		}
+	})
+
+	t.Run("definition", func(t *testing.T) {
+		var locations []Location
+		c.call("textDocument/definition", position(uri, 5, len("Shop->>Order: t")), &locations)
+		want := Location{URI: ordersURI, Range: Range{Start: Position{6, 9}, End: Position{6, 14}}}
+		if len(locations) != 1 || locations[0] != want {
+			t.Errorf("expected %+v, got %+v", want, locations)
+		}
+
+		c.call("textDocument/definition", position(uri, 5, len("Shop->>O")), &locations)
+		want = Location{URI: ordersURI, Range: Range{Start: Position{4, 6}, End: Position{4, 11}}}
+		if len(locations) != 1 || locations[0] != want {
+			t.Errorf("expected %+v, got %+v", want, locations)
+		}
+	})
+
+	t.Run("diagnostics", func(t *testing.T) {
+		broken := strings.Replace(checkout, "participant Shop\n", "participant Shop\nShop->>\n", 1)
+		c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+			TextDocument:   VersionedTextDocumentIdentifier{URI: uri, Version: 2},
+			ContentChanges: []TextDocumentContentChangeEvent{{Text: broken}},
+		}, 1)
+		diagnostics := c.diagnostics[uri]
+		if len(diagnostics) != 1 || diagnostics[0].Code != "syntax" || diagnostics[0].Range.Start.Line != 5 {
+			t.Errorf("unexpected diagnostics %+v", diagnostics)
+		}
+
+		// Lint findings are reported with their severity
+		unused := strings.Replace(checkout, "participant Shop\n", "participant Shop\nparticipant Bank\n", 1)
+		c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+			TextDocument:   VersionedTextDocumentIdentifier{URI: uri, Version: 3},
+			ContentChanges: []TextDocumentContentChangeEvent{{Text: unused}},
+		}, 1)
+		diagnostics = c.diagnostics[uri]
+		if len(diagnostics) != 1 || diagnostics[0].Code != "unused-participant" || diagnostics[0].Severity != SeverityWarning {
+			t.Errorf("unexpected diagnostics %+v", diagnostics)
+		}
+	})
+
+	c.call("shutdown", nil, nil)
+	c.notify("exit", nil, 0)
+	if err := <-c.done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServerNotInitialized(t *testing.T) {
+	c := newClient(t, Options{})
+	c.id++
+	if err := c.conn.write(map[string]any{"jsonrpc": "2.0", "id": c.id, "method": "textDocument/hover", "params": position("file:///a.md", 0, 0)}); err != nil {
+		t.Fatal(err)
+	}
+	var r response
+	c.receive(&r)
+	if r.Error == nil || r.Error.Code != codeServerNotInitialized {
+		t.Errorf("expected error %d, got %+v", codeServerNotInitialized, r.Error)
+	}
+
+	// Exit without shutdown is an error
+	c.notify("exit", nil, 0)
+	if err := <-c.done; err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,331 @@
+// Package lsp implements a language server for Mermaid diagrams embedded in Markdown and
+// AsciiDoc documents and in raw Mermaid files.
+//
+// The server speaks the Language Server Protocol over a byte stream, usually standard input and
+// output. It publishes the parse errors, the lint findings and the errors of the transformation
+// as diagnostics, completes class names, methods and participants in sequence diagrams, shows
+// the generated Java declaration on hover and jumps from sequence messages to the members of
+// the class diagrams.
+//
+// Besides the open documents the server reads the input files of the project configuration
+// found in the workspace root, so that classes declared in other documents are known.
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/config"
+	"github.com/MarmaidTranspiler/Merfolk/internal/lint"
+)
+
+// Options configure a Server.
+type Options struct {
+	// Version is reported to the client.
+	Version string
+	// FindFiles returns the input files below dir, relative to dir. If nil, only the open
+	// documents are analyzed.
+	FindFiles func(dir string, include, exclude []string) ([]string, error)
+	// Log receives messages about problems that cannot be reported to the client. It may be nil.
+	Log io.Writer
+}
+
+// Server is a language server. It handles one client connection.
+type Server struct {
+	options Options
+	conn    *conn
+
+	config *config.Config
+	lint   lint.Config
+	// open are the documents opened in the editor, by URI
+	open map[string]*document
+	// files are the input files of the workspace as stored on disk, by URI
+	files map[string]*document
+	// index is built from files and open on demand, nil if a document changed
+	index *index
+
+	initialized bool
+	shutdown    bool
+}
+
+// NewServer returns a language server.
+func NewServer(options Options) *Server {
+	rules, _ := lint.NewConfig(nil)
+	return &Server{
+		options: options,
+		config:  config.Default(),
+		lint:    rules,
+		open:    make(map[string]*document),
+		files:   make(map[string]*document),
+	}
+}
+
+// errExit is returned by the exit handler to end the message loop.
+var errExit = errors.New("exit")
+
+// Serve handles the messages read from in until the client sends exit or closes in. It returns
+// an error if the connection failed or the client exited without shutting down the server first.
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	s.conn = newConn(in, out)
+	for {
+		m, err := s.conn.read()
+		if err == io.EOF {
+			return errors.New("connection closed without exit")
+		}
+		var rpcErr *rpcError
+		if errors.As(err, &rpcErr) {
+			// The message is malformed, but the stream is still intact
+			if err := s.conn.reply(json.RawMessage("null"), nil, rpcErr); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		result, err := s.handle(m)
+		if err == errExit {
+			if !s.shutdown {
+				return errors.New("exit without shutdown")
+			}
+			return nil
+		}
+		if m.ID == nil {
+			// Notifications have no response, errors can only be logged
+			if err != nil {
+				s.logf("%s: %v", m.Method, err)
+			}
+			continue
+		}
+		if err := s.conn.reply(*m.ID, result, err); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) logf(format string, args ...any) {
+	if s.options.Log != nil {
+		fmt.Fprintf(s.options.Log, format+"\n", args...)
+	}
+}
+
+// handle dispatches a request or notification to its handler.
+func (s *Server) handle(m *message) (any, error) {
+	switch m.Method {
+	case "initialize":
+		return decode(m, s.initialize)
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "exit":
+		return nil, errExit
+	}
+
+	if !s.initialized {
+		return nil, &rpcError{Code: codeServerNotInitialized, Message: "server not initialized"}
+	}
+
+	switch m.Method {
+	case "textDocument/didOpen":
+		return decode(m, s.didOpen)
+	case "textDocument/didChange":
+		return decode(m, s.didChange)
+	case "textDocument/didSave":
+		return decode(m, s.didSave)
+	case "textDocument/didClose":
+		return decode(m, s.didClose)
+	case "textDocument/completion":
+		return decode(m, s.completion)
+	case "textDocument/hover":
+		return decode(m, s.hover)
+	case "textDocument/definition":
+		return decode(m, s.definition)
+	}
+
+	if m.ID == nil {
+		// Unknown notifications such as $/cancelRequest are ignored
+		return nil, nil
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: "method not supported: " + m.Method}
+}
+
+// decode unmarshals the parameters of a message and calls handler.
+func decode[P any](m *message, handler func(params *P) (any, error)) (any, error) {
+	var params P
+	if len(m.Params) > 0 {
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+	}
+	return handler(&params)
+}
+
+func (s *Server) initialize(params *InitializeParams) (any, error) {
+	root := uriToPath(params.RootURI)
+	if root == "" && len(params.WorkspaceFolders) > 0 {
+		root = uriToPath(params.WorkspaceFolders[0].URI)
+	}
+	if root == "" {
+		root = params.RootPath
+	}
+	if root != "" {
+		s.loadWorkspace(root)
+	}
+
+	s.initialized = true
+	return InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync: TextDocumentSyncOptions{
+				OpenClose: true,
+				Change:    TextDocumentSyncKindFull,
+				Save:      SaveOptions{IncludeText: true},
+			},
+			CompletionProvider: CompletionOptions{TriggerCharacters: []string{">", ":", " "}},
+			HoverProvider:      true,
+			DefinitionProvider: true,
+		},
+		ServerInfo: ServerInfo{Name: "merfolk", Version: s.options.Version},
+	}, nil
+}
+
+// loadWorkspace reads the project configuration and the input files below root. Problems are
+// logged, the server then works with the open documents only.
+func (s *Server) loadWorkspace(root string) {
+	file, err := config.Find(root)
+	if err != nil {
+		s.logf("error reading configuration: %v", err)
+		return
+	}
+	if file != "" {
+		cfg, err := config.Load(file)
+		if err != nil {
+			s.logf("error reading configuration: %v", err)
+			return
+		}
+		s.config = cfg
+	}
+
+	if rules, err := lint.NewConfig(s.config.Lint.Rules); err != nil {
+		s.logf("error reading configuration: %v", err)
+	} else {
+		s.lint = rules
+	}
+
+	if s.options.FindFiles == nil {
+		return
+	}
+	inputDir := s.config.Input
+	if inputDir == "" {
+		inputDir = root
+	}
+	names, err := s.options.FindFiles(inputDir, s.config.Include, s.config.Exclude)
+	if err != nil {
+		s.logf("error reading input files: %v", err)
+		return
+	}
+	for _, name := range names {
+		s.readFile(filepath.Join(inputDir, filepath.FromSlash(name)))
+	}
+}
+
+// readFile reads an input file into files.
+func (s *Server) readFile(path string) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		s.logf("%v", err)
+		return
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		s.logf("%v", err)
+		return
+	}
+	uri := pathToURI(path)
+	s.files[uri] = newDocument(uri, string(content), nil)
+}
+
+func (s *Server) didOpen(params *DidOpenTextDocumentParams) (any, error) {
+	item := params.TextDocument
+	s.open[item.URI] = newDocument(item.URI, item.Text, &item.Version)
+	return nil, s.changed()
+}
+
+func (s *Server) didChange(params *DidChangeTextDocumentParams) (any, error) {
+	if len(params.ContentChanges) == 0 {
+		return nil, nil
+	}
+	version := params.TextDocument.Version
+	text := params.ContentChanges[len(params.ContentChanges)-1].Text
+	s.open[params.TextDocument.URI] = newDocument(params.TextDocument.URI, text, &version)
+	return nil, s.changed()
+}
+
+func (s *Server) didSave(params *DidSaveTextDocumentParams) (any, error) {
+	uri := params.TextDocument.URI
+	if _, exists := s.files[uri]; exists && params.Text != nil {
+		s.files[uri] = newDocument(uri, *params.Text, nil)
+	}
+	return nil, nil
+}
+
+func (s *Server) didClose(params *DidCloseTextDocumentParams) (any, error) {
+	uri := params.TextDocument.URI
+	delete(s.open, uri)
+	// Unsaved changes are dropped, the file is analyzed as stored on disk again
+	if _, exists := s.files[uri]; exists {
+		s.readFile(uriToPath(uri))
+	}
+	if err := s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: []Diagnostic{}}); err != nil {
+		return nil, err
+	}
+	return nil, s.changed()
+}
+
+// changed rebuilds the index and publishes the diagnostics of all open documents, since a
+// change in one document can affect the consistency of the others.
+func (s *Server) changed() error {
+	s.index = nil
+	idx := s.currentIndex()
+	for _, d := range s.open {
+		params := PublishDiagnosticsParams{URI: d.uri, Version: d.version, Diagnostics: s.diagnostics(d, idx)}
+		if err := s.conn.notify("textDocument/publishDiagnostics", params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// currentIndex returns the index of the workspace, open documents replace the files on disk.
+func (s *Server) currentIndex() *index {
+	if s.index == nil {
+		var documents []*document
+		for uri, d := range s.files {
+			if _, open := s.open[uri]; !open {
+				documents = append(documents, d)
+			}
+		}
+		for _, d := range s.open {
+			documents = append(documents, d)
+		}
+		s.index = buildIndex(s.config, documents)
+	}
+	return s.index
+}
+
+// document returns an open document or an input file.
+func (s *Server) document(uri string) (*document, error) {
+	if d, exists := s.open[uri]; exists {
+		return d, nil
+	}
+	if d, exists := s.files[uri]; exists {
+		return d, nil
+	}
+	return nil, &rpcError{Code: codeInvalidParams, Message: "unknown document " + uri}
+}
@@ -0,0 +1,183 @@
+// Package merge combines the edits of two versions of a text that derive from a common base,
+// like the merge of version control systems.
+//
+// The texts are compared line by line. A region changed in only one version takes that
+// change, a region changed in both versions in the same way takes it once and a region changed
+// in both versions differently is a conflict. Conflicts are written with the markers known
+// from git:
+//
+//	<<<<<<< ours
+//	lines of ours
+//	=======
+//	lines of theirs
+//	>>>>>>> theirs
+package merge
+
+import (
+	"strings"
+)
+
+// Conflict markers. The labels of the versions follow the opening and closing marker.
+const (
+	MarkerOurs   = "<<<<<<<"
+	MarkerSep    = "======="
+	MarkerTheirs = ">>>>>>>"
+)
+
+// Result is the outcome of a merge.
+type Result struct {
+	// Text is the merged text, with conflict markers if there were conflicts.
+	Text string
+	// Conflicts is the number of conflicting regions.
+	Conflicts int
+}
+
+// Merge combines the changes from base to ours and from base to theirs. The labels name ours
+// and theirs in the conflict markers.
+func Merge(base, ours, theirs, oursLabel, theirsLabel string) Result {
+	o, a, b := splitLines(base), splitLines(ours), splitLines(theirs)
+	matchA := match(o, a)
+	matchB := match(o, b)
+
+	var out []string
+	conflicts := 0
+	chunk := func(oc, ac, bc []string) {
+		switch {
+		case equal(ac, oc):
+			out = append(out, bc...)
+		case equal(bc, oc), equal(ac, bc):
+			out = append(out, ac...)
+		default:
+			conflicts++
+			out = append(out, MarkerOurs+" "+oursLabel)
+			out = append(out, ac...)
+			out = append(out, MarkerSep)
+			out = append(out, bc...)
+			out = append(out, MarkerTheirs+" "+theirsLabel)
+		}
+	}
+
+	lo, la, lb := 0, 0, 0
+	for lo < len(o) || la < len(a) || lb < len(b) {
+		// Lines that are unchanged in both versions
+		i := 0
+		for lo+i < len(o) && matchA[lo+i] == la+i && matchB[lo+i] == lb+i {
+			i++
+		}
+		if i > 0 {
+			out = append(out, o[lo:lo+i]...)
+			lo, la, lb = lo+i, la+i, lb+i
+			continue
+		}
+
+		// The next base line that is kept in both versions ends the changed region
+		next := lo
+		for next < len(o) && (matchA[next] < 0 || matchB[next] < 0) {
+			next++
+		}
+		if next == len(o) {
+			chunk(o[lo:], a[la:], b[lb:])
+			break
+		}
+		chunk(o[lo:next], a[la:matchA[next]], b[lb:matchB[next]])
+		lo, la, lb = next, matchA[next], matchB[next]
+	}
+
+	text := strings.Join(out, "\n")
+	if len(out) > 0 && hasFinalNewline(ours, theirs) {
+		text += "\n"
+	}
+	return Result{Text: text, Conflicts: conflicts}
+}
+
+// HasConflictMarkers reports whether text contains a line that starts a conflict.
+func HasConflictMarkers(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, MarkerOurs+" ") || line == MarkerOurs {
+			return true
+		}
+	}
+	return false
+}
+
+func hasFinalNewline(ours, theirs string) bool {
+	return strings.HasSuffix(ours, "\n") || (ours == "" && strings.HasSuffix(theirs, "\n"))
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n"), "\n")
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// match returns for every line of a the index of the matching line of b in a longest common
+// subsequence, or -1 if the line was removed. It uses the greedy algorithm of Myers.
+func match(a, b []string) []int {
+	n, m := len(a), len(b)
+	result := make([]int, n)
+	for i := range result {
+		result[i] = -1
+	}
+
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+search:
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk back through the trace and record the diagonals, which are the matching lines
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0 && (x > 0 || y > 0); d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			result[x] = y
+		}
+		if d > 0 {
+			x, y = prevX, prevY
+		}
+	}
+	return result
+}
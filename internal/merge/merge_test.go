@@ -0,0 +1,133 @@
+package merge
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func lines(s ...string) string {
+	return strings.Join(s, "\n") + "\n"
+}
+
+func TestMerge(t *testing.T) {
+	base := lines("class A {", "    int x;", "    void f() {", "    }", "}")
+
+	tests := []struct {
+		name      string
+		ours      string
+		theirs    string
+		want      string
+		conflicts int
+	}{
+		{
+			name:   "unchanged",
+			ours:   base,
+			theirs: base,
+			want:   base,
+		},
+		{
+			name:   "only ours",
+			ours:   lines("class A {", "    int x;", "    // moved", "    void f() {", "    }", "}"),
+			theirs: base,
+			want:   lines("class A {", "    int x;", "    // moved", "    void f() {", "    }", "}"),
+		},
+		{
+			name:   "only theirs",
+			ours:   base,
+			theirs: lines("class A {", "    int x;", "    int y;", "    void f() {", "    }", "}"),
+			want:   lines("class A {", "    int x;", "    int y;", "    void f() {", "    }", "}"),
+		},
+		{
+			name:   "both in different places",
+			ours:   lines("// header", "class A {", "    int x;", "    void f() {", "    }", "}"),
+			theirs: lines("class A {", "    int x;", "    void f() {", "    }", "    void g() {", "    }", "}"),
+			want:   lines("// header", "class A {", "    int x;", "    void f() {", "    }", "    void g() {", "    }", "}"),
+		},
+		{
+			name:   "both the same",
+			ours:   lines("class A {", "    long x;", "    void f() {", "    }", "}"),
+			theirs: lines("class A {", "    long x;", "    void f() {", "    }", "}"),
+			want:   lines("class A {", "    long x;", "    void f() {", "    }", "}"),
+		},
+		{
+			name:   "removed in theirs",
+			ours:   lines("// header", "class A {", "    int x;", "    void f() {", "    }", "}"),
+			theirs: lines("class A {", "    int x;", "}"),
+			want:   lines("// header", "class A {", "    int x;", "}"),
+		},
+		{
+			name:      "conflict",
+			ours:      lines("class A {", "    long x;", "    void f() {", "    }", "}"),
+			theirs:    lines("class A {", "    String x;", "    void f() {", "    }", "}"),
+			want:      lines("class A {", "<<<<<<< current", "    long x;", "=======", "    String x;", ">>>>>>> generated", "    void f() {", "    }", "}"),
+			conflicts: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := Merge(base, test.ours, test.theirs, "current", "generated")
+			if result.Text != test.want {
+				t.Errorf("Mismatch!\nExpected:\n%s\nGot:\n%s", test.want, result.Text)
+			}
+			if result.Conflicts != test.conflicts {
+				t.Errorf("expected %d conflicts, got %d", test.conflicts, result.Conflicts)
+			}
+			if HasConflictMarkers(result.Text) != (test.conflicts > 0) {
+				t.Errorf("HasConflictMarkers does not match the number of conflicts")
+			}
+		})
+	}
+}
+
+func TestMergeEmpty(t *testing.T) {
+	if result := Merge("", "", "", "ours", "theirs"); result.Text != "" || result.Conflicts != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if result := Merge("", "", "a\n", "ours", "theirs"); result.Text != "a\n" {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	a := []string{"a", "b", "c", "a", "b", "b", "a"}
+	b := []string{"c", "b", "a", "b", "a", "c"}
+
+	result := match(a, b)
+	common, last := 0, -1
+	for i, j := range result {
+		if j < 0 {
+			continue
+		}
+		if j <= last || a[i] != b[j] {
+			t.Fatalf("invalid match %v", result)
+		}
+		last = j
+		common++
+	}
+	if common != 4 {
+		t.Errorf("expected a common subsequence of 4 lines, got %d: %v", common, result)
+	}
+}
+
+func TestMergeOneSided(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	text := func() string {
+		var b strings.Builder
+		for i := random.Intn(20); i > 0; i-- {
+			b.WriteString(string(rune('a'+random.Intn(4))) + "\n")
+		}
+		return b.String()
+	}
+
+	for i := 0; i < 500; i++ {
+		base, changed := text(), text()
+		if result := Merge(base, changed, base, "ours", "theirs"); result.Text != changed || result.Conflicts != 0 {
+			t.Fatalf("Merge(%q, %q, base) = %+v", base, changed, result)
+		}
+		if result := Merge(base, base, changed, "ours", "theirs"); result.Text != changed || result.Conflicts != 0 {
+			t.Fatalf("Merge(%q, base, %q) = %+v", base, changed, result)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+// Package project turns the diagrams of a set of documents into generated Java files.
+//
+// It holds the steps shared by the convert command, watch mode and the HTTP server: the
+// class and sequence diagrams of all documents are merged into one model, which is then
+// rendered with the templates. Reading the documents is left to the callers.
+package project
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+
+	generator "github.com/MarmaidTranspiler/Merfolk/internal/CodeTemplateGenerator"
+	"github.com/MarmaidTranspiler/Merfolk/internal/config"
+	"github.com/MarmaidTranspiler/Merfolk/internal/connector"
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+)
+
+// Document is a parsed input document.
+type Document struct {
+	// Path is the path of the document relative to the input directory, with forward slashes.
+	Path     string
+	Diagrams []reader.Diagram
+	// Fingerprint identifies the content of the document, see Hash. It is passed on to the
+	// generated files and may be empty.
+	Fingerprint string
+}
+
+// Diagnostic is a problem found while building or rendering a project.
+type Diagnostic struct {
+	// Path is the path of the document, empty if the problem is not bound to a document.
+	Path string
+	// Line is the 1-based line in the document, 0 if unknown.
+	Line    int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	switch {
+	case d.Path == "":
+		return d.Message
+	case d.Line == 0:
+		return d.Path + ": " + d.Message
+	}
+	return fmt.Sprintf("%s:%d: %s", d.Path, d.Line, d.Message)
+}
+
+// Reporter receives the diagnostics of Build and Render.
+type Reporter func(Diagnostic)
+
+// Project is the model built from the diagrams of all documents.
+type Project struct {
+	Model *connector.Model
+	// TargetDirs is the output directory of every class and interface, relative to the output directory
+	TargetDirs map[string]string
+	// Diagrams is the number of diagrams that were transformed
+	Diagrams int
+	// Sources are the paths of the documents that declare or extend a class or interface, by name
+	Sources map[string][]string
+
+	// fingerprints are the fingerprints of the documents by path
+	fingerprints map[string]string
+}
+
+// File is the rendered code of a class or interface.
+type File struct {
+	// Path is the path of the file relative to the output directory, with forward slashes.
+	Path    string
+	Content []byte
+	// Sources maps the paths of the documents the file was generated from to their fingerprints.
+	Sources map[string]string
+}
+
+// Hash returns the hex encoded SHA-256 hash of content.
+func Hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Build transforms the diagrams of documents into one model. Class diagrams are merged in the
+// order of documents, then the sequence diagrams add the method bodies. Diagrams that cannot
+// be transformed are reported and skipped.
+func Build(cfg *config.Config, documents []Document, report Reporter) *Project {
+	types := cfg.TypeMap()
+
+	p := &Project{
+		Model:      connector.NewModel(),
+		TargetDirs: make(map[string]string),
+		Sources:    make(map[string][]string),
+
+		fingerprints: make(map[string]string),
+	}
+	targetDir := func(name, source string) {
+		if _, exists := p.TargetDirs[name]; exists {
+			return
+		}
+		dir := ""
+		if cfg.Mirror {
+			dir = path.Dir(source)
+		}
+		p.TargetDirs[name] = dir
+	}
+
+	addSource := func(name string, document *Document) {
+		for _, source := range p.Sources[name] {
+			if source == document.Path {
+				return
+			}
+		}
+		p.Sources[name] = append(p.Sources[name], document.Path)
+	}
+
+	type sequence struct {
+		diagram  *reader.SequenceDiagram
+		document *Document
+		line     int
+	}
+
+	// Collect classes and interfaces in the order they appear in the sources
+	model := p.Model
+	model.Types = types
+	var sequenceDiagrams []sequence
+
+	for i := range documents {
+		document := &documents[i]
+		p.fingerprints[document.Path] = document.Fingerprint
+
+		// Separate class and sequence diagrams
+		for _, diagram := range document.Diagrams {
+			if diagram.Options.Skip {
+				continue
+			}
+			p.Diagrams++
+
+			fail := func(kind string, err error) {
+				report(Diagnostic{Path: document.Path, Line: diagram.Line, Message: fmt.Sprintf("error processing %s diagram: %v", kind, err)})
+			}
+
+			if diagram.IsClass && diagram.Class != nil {
+				// Process class diagrams
+				diagramModel, err := transformClassDiagram(diagram.Class, types)
+				if err != nil {
+					fail("class", err)
+					continue
+				}
+
+				if err := connector.ApplyDiagramOptions(diagramModel, diagram.Options); err != nil {
+					fail("class", err)
+					continue
+				}
+
+				// Merge classes and interfaces into the project model
+				model.Merge(diagramModel)
+				for _, class := range diagramModel.ClassList() {
+					targetDir(class.ClassName, document.Path)
+					addSource(class.ClassName, document)
+				}
+				for _, iface := range diagramModel.InterfaceList() {
+					targetDir(iface.InterfaceName, document.Path)
+					addSource(iface.InterfaceName, document)
+				}
+			} else if diagram.IsSequence && diagram.Sequence != nil {
+				if err := connector.CheckDiagramOptions(diagram.Options); err != nil {
+					fail("sequence", err)
+					continue
+				}
+
+				// Store sequence diagrams for later processing
+				sequenceDiagrams = append(sequenceDiagrams, sequence{diagram.Sequence, document, diagram.Line})
+			} else {
+				report(Diagnostic{Path: document.Path, Line: diagram.Line, Message: "unknown or unsupported diagram type"})
+			}
+		}
+	}
+
+	// Process sequence diagrams and integrate with classes
+	for _, s := range sequenceDiagrams {
+		// Modify existing class definitions
+		if err := transformSequenceDiagram(s.diagram, model); err != nil {
+			report(Diagnostic{Path: s.document.Path, Line: s.line, Message: fmt.Sprintf("error processing sequence diagram: %v", err)})
+		}
+
+		// Classes that only appear in sequence diagrams are placed next to the diagram
+		for _, class := range model.ClassList() {
+			targetDir(class.ClassName, s.document.Path)
+		}
+		for _, instruction := range s.diagram.Instructions {
+			if message := instruction.Message; message != nil {
+				for _, name := range []string{message.Left, message.Right} {
+					if _, exists := model.Classes[name]; exists {
+						addSource(name, s.document)
+					}
+				}
+			}
+		}
+	}
+
+	// Place classes without namespace in the base package and resolve the referenced types
+	connector.AssignPackages(model, cfg.BasePackage)
+	connector.ResolveImports(model, cfg.KnownTypes(), cfg.Naming.InterfacePrefix)
+
+	return p
+}
+
+// FromModel returns the project of a model that was not built from diagrams, like an imported
+// one. All classes and interfaces are attributed to the document source. Like in Build, classes
+// without namespace are placed in the base package; the imports of the types that list none
+// are resolved, the listed ones are kept.
+func FromModel(cfg *config.Config, model *connector.Model, source, fingerprint string) *Project {
+	p := &Project{
+		Model:      model,
+		TargetDirs: make(map[string]string),
+		Sources:    make(map[string][]string),
+
+		fingerprints: map[string]string{source: fingerprint},
+	}
+	if model.Types == nil {
+		model.Types = cfg.TypeMap()
+	}
+
+	classImports, interfaceImports := make(map[string][]string), make(map[string][]string)
+	for _, class := range model.ClassList() {
+		p.Sources[class.ClassName] = []string{source}
+		if len(class.Imports) > 0 {
+			classImports[class.ClassName] = class.Imports
+		}
+	}
+	for _, iface := range model.InterfaceList() {
+		p.Sources[iface.InterfaceName] = []string{source}
+		if len(iface.Imports) > 0 {
+			interfaceImports[iface.InterfaceName] = iface.Imports
+		}
+	}
+
+	connector.AssignPackages(model, cfg.BasePackage)
+	connector.ResolveImports(model, cfg.KnownTypes(), cfg.Naming.InterfacePrefix)
+	for _, class := range model.ClassList() {
+		if listed, ok := classImports[class.ClassName]; ok {
+			class.Imports = listed
+		}
+	}
+	for _, iface := range model.InterfaceList() {
+		if listed, ok := interfaceImports[iface.InterfaceName]; ok {
+			iface.Imports = listed
+		}
+	}
+	return p
+}
+
+// transformClassDiagram calls the connector and turns a panic on unexpected input into an error.
+func transformClassDiagram(diagram *reader.ClassDiagram, types generator.TypeMap) (model *connector.Model, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			model, err = nil, fmt.Errorf("internal error: %v", r)
+		}
+	}()
+	return connector.TransformClassDiagram(diagram, types)
+}
+
+// transformSequenceDiagram calls the connector and turns a panic on unexpected input into an error.
+func transformSequenceDiagram(diagram *reader.SequenceDiagram, model *connector.Model) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("internal error: %v", r)
+		}
+	}()
+	return connector.TransformSequenceDiagram(diagram, model)
+}
+
+// Render generates the Java code of all classes and interfaces of p in memory.
+func Render(cfg *config.Config, p *Project, report Reporter) []File {
+	options := cfg.GeneratorOptions()
+	var files []File
+
+	for _, class := range p.Model.ClassList() {
+		code, err := generator.RenderJavaCode(*class, cfg.Templates.Class, options)
+		if err != nil {
+			report(Diagnostic{Message: fmt.Sprintf("error generating Java class %s: %v", class.ClassName, err)})
+			continue
+		}
+		dir := PackageDir(class.Package, p.TargetDirs[class.ClassName])
+		files = append(files, File{
+			Path:    path.Join(dir, generator.JavaFileName(*class, options)+".java"),
+			Content: code,
+			Sources: p.sources(class.ClassName),
+		})
+	}
+
+	for _, iface := range p.Model.InterfaceList() {
+		code, err := generator.RenderJavaCode(*iface, cfg.Templates.Interface, options)
+		if err != nil {
+			report(Diagnostic{Message: fmt.Sprintf("error generating Java interface %s: %v", iface.InterfaceName, err)})
+			continue
+		}
+		dir := PackageDir(iface.Package, p.TargetDirs[iface.InterfaceName])
+		files = append(files, File{
+			Path:    path.Join(dir, generator.JavaFileName(*iface, options)+".java"),
+			Content: code,
+			Sources: p.sources(iface.InterfaceName),
+		})
+	}
+
+	return files
+}
+
+// sources returns the fingerprints of the documents of a class or interface by path.
+func (p *Project) sources(name string) map[string]string {
+	sources := make(map[string]string, len(p.Sources[name]))
+	for _, source := range p.Sources[name] {
+		sources[source] = p.fingerprints[source]
+	}
+	return sources
+}
+
+// PackageDir returns the directory of a Java package relative to the output directory.
+// Types without a package are written to fallback.
+func PackageDir(pkg, fallback string) string {
+	if pkg == "" {
+		return fallback
+	}
+	return strings.ReplaceAll(pkg, ".", "/")
+}
@@ -0,0 +1,342 @@
+package reader
+
+import (
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Block is the text of a single diagram embedded in a document.
+type Block struct {
+	// Content is the diagram text with the indentation of the fence removed.
+	Content string
+	// Line is the 1-based line number of the first line of Content in the document.
+	Line int
+	// EndLine is the 1-based line number of the closing fence. For raw Mermaid files
+	// it is the line after the last line of the file, for PlantUML files the line after
+	// @enduml.
+	EndLine int
+	// Indent is the number of columns of indentation removed from each line.
+	Indent int
+	// PlantUML is set for PlantUML diagrams, whose Content runs from @startuml to @enduml.
+	PlantUML bool
+}
+
+// Syntax is the markup language of a document containing diagrams.
+type Syntax int
+
+const (
+	Markdown Syntax = iota
+	AsciiDoc
+	Mermaid
+	PlantUML
+)
+
+// SyntaxOf determines the markup language of a file by its extension. Unknown
+// extensions are treated as Markdown.
+func SyntaxOf(name string) Syntax {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".mmd", ".mermaid":
+		return Mermaid
+	case ".adoc", ".asciidoc", ".asc":
+		return AsciiDoc
+	case ".puml", ".plantuml", ".pu":
+		return PlantUML
+	default:
+		return Markdown
+	}
+}
+
+// ExtractBlocks returns all Mermaid and PlantUML diagrams contained in input.
+func ExtractBlocks(input string, syntax Syntax) ([]Block, error) {
+	lines := splitLines(input)
+
+	switch syntax {
+	case Mermaid:
+		return []Block{{Content: joinLines(lines), Line: 1, EndLine: len(lines) + 1}}, nil
+	case PlantUML:
+		return extractPlantUMLBlocks(lines)
+	case AsciiDoc:
+		return extractAsciiDocBlocks(lines)
+	default:
+		return extractMarkdownBlocks(lines)
+	}
+}
+
+// splitLines splits input into lines, accepting both LF and CRLF line endings.
+func splitLines(input string) []string {
+	input = strings.ReplaceAll(input, "\r\n", "\n")
+	input = strings.TrimSuffix(input, "\n")
+	if input == "" {
+		return nil
+	}
+	return strings.Split(input, "\n")
+}
+
+func joinLines(lines []string) string {
+	var builder strings.Builder
+	for _, line := range lines {
+		builder.WriteString(line + "\n")
+	}
+	return builder.String()
+}
+
+// fence is an open fenced code block as defined by CommonMark.
+type fence struct {
+	char   byte
+	length int
+	indent int
+	// diagram is set for Mermaid and PlantUML blocks
+	diagram  bool
+	plantUML bool
+	// unlabelled is set for blocks without language, which hold a diagram if they start with @startuml
+	unlabelled bool
+	line       int
+}
+
+// extractMarkdownBlocks follows the CommonMark rules for fenced code blocks: a fence
+// consists of at least three backticks or tildes, the first word of the info string
+// names the language and the block is closed by a fence of the same character that is
+// at least as long as the opening one. Fences nested in list items may be indented by
+// any amount; that indentation is removed from the content lines. MDX uses the same rules.
+// PlantUML diagrams are written in blocks of the language plantuml or puml, or in blocks
+// without language whose content starts with @startuml. @startuml outside of code blocks is
+// text.
+func extractMarkdownBlocks(lines []string) ([]Block, error) {
+	var blocks []Block
+	var open *fence
+	var content []string
+
+	for i, line := range lines {
+		if open == nil {
+			if f, ok := parseOpeningFence(line); ok {
+				f.line = i + 1
+				open = &f
+				content = nil
+			}
+			continue
+		}
+
+		if isClosingFence(line, open) {
+			if open.unlabelled && isPlantUML(joinLines(content)) {
+				open.diagram, open.plantUML = true, true
+			}
+			if open.diagram {
+				blocks = append(blocks, Block{
+					Content:  joinLines(content),
+					Line:     open.line + 1,
+					EndLine:  i + 1,
+					Indent:   open.indent,
+					PlantUML: open.plantUML,
+				})
+			}
+			open = nil
+			continue
+		}
+
+		if open.diagram || open.unlabelled {
+			content = append(content, stripIndent(line, open.indent))
+		}
+	}
+
+	if open != nil && open.diagram {
+		return nil, errors.New("unclosed diagram")
+	}
+
+	return blocks, nil
+}
+
+func parseOpeningFence(line string) (fence, bool) {
+	indent, rest := splitIndent(line)
+
+	if len(rest) < 3 || (rest[0] != '`' && rest[0] != '~') {
+		return fence{}, false
+	}
+	char := rest[0]
+	length := 0
+	for length < len(rest) && rest[length] == char {
+		length++
+	}
+	if length < 3 {
+		return fence{}, false
+	}
+
+	info := strings.TrimSpace(rest[length:])
+	if char == '`' && strings.Contains(info, "`") {
+		return fence{}, false
+	}
+
+	language := strings.ToLower(fenceLanguage(info))
+	plantUML := language == "plantuml" || language == "puml"
+	return fence{
+		char:       char,
+		length:     length,
+		indent:     indent,
+		diagram:    language == "mermaid" || plantUML,
+		plantUML:   plantUML,
+		unlabelled: language == "",
+	}, true
+}
+
+// fenceLanguage returns the language of an info string such as "mermaid {title=x}" or "{mermaid}".
+func fenceLanguage(info string) string {
+	fields := strings.Fields(info)
+	if len(fields) == 0 {
+		return ""
+	}
+	language := fields[0]
+	if strings.HasPrefix(language, "{") {
+		return strings.Trim(language, "{}")
+	}
+	if i := strings.IndexByte(language, '{'); i >= 0 {
+		language = language[:i]
+	}
+	return language
+}
+
+func isClosingFence(line string, open *fence) bool {
+	_, rest := splitIndent(line)
+	length := 0
+	for length < len(rest) && rest[length] == open.char {
+		length++
+	}
+	return length >= open.length && strings.TrimSpace(rest[length:]) == ""
+}
+
+// splitIndent returns the width of the leading whitespace of line, with tabs expanded
+// to the next multiple of four columns, and the remainder of the line.
+func splitIndent(line string) (int, string) {
+	width := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ':
+			width++
+		case '\t':
+			width += 4 - width%4
+		default:
+			return width, line[i:]
+		}
+	}
+	return width, ""
+}
+
+// stripIndent removes up to width columns of leading whitespace from line.
+func stripIndent(line string, width int) string {
+	column := 0
+	for i := 0; i < len(line); i++ {
+		if column >= width {
+			return line[i:]
+		}
+		switch line[i] {
+		case ' ':
+			column++
+		case '\t':
+			next := column + 4 - column%4
+			if next > width {
+				// A tab crossing the boundary leaves the remaining columns as spaces
+				return strings.Repeat(" ", next-width) + line[i+1:]
+			}
+			column = next
+		default:
+			return line[i:]
+		}
+	}
+	return ""
+}
+
+var (
+	asciiDocDiagramAttribute = regexp.MustCompile(`^\[\s*(source\s*,\s*)?(mermaid|plantuml)\s*(,[^\]]*)?\]$`)
+	asciiDocDelimiter        = regexp.MustCompile(`^(-{4,}|\.{4,}|` + "`{3,}" + `)$`)
+)
+
+// extractAsciiDocBlocks finds listing and literal blocks that are marked with a
+// [mermaid] or [source,mermaid] attribute line, or with [plantuml] for PlantUML diagrams:
+//
+//	[mermaid]
+//	----
+//	classDiagram
+//	----
+//
+// Block titles (.Title) between the attribute line and the delimiter are allowed.
+func extractAsciiDocBlocks(lines []string) ([]Block, error) {
+	var blocks []Block
+	var content []string
+	pending := ""
+	delimiter := ""
+	language := ""
+	start := 0
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		if delimiter != "" {
+			if trimmed == delimiter {
+				if language != "" {
+					blocks = append(blocks, Block{
+						Content:  joinLines(content),
+						Line:     start + 1,
+						EndLine:  i + 1,
+						PlantUML: language == "plantuml",
+					})
+				}
+				delimiter = ""
+				continue
+			}
+			if language != "" {
+				content = append(content, line)
+			}
+			continue
+		}
+
+		switch {
+		case asciiDocDiagramAttribute.MatchString(trimmed):
+			pending = asciiDocDiagramAttribute.FindStringSubmatch(trimmed)[2]
+		case asciiDocDelimiter.MatchString(trimmed):
+			delimiter = trimmed
+			language = pending
+			pending = ""
+			content = nil
+			start = i + 1
+		case strings.HasPrefix(trimmed, ".") && len(trimmed) > 1 && trimmed[1] != '.':
+			// a block title keeps a pending attribute line alive
+		default:
+			pending = ""
+		}
+	}
+
+	if delimiter != "" && language != "" {
+		return nil, errors.New("unclosed diagram")
+	}
+
+	return blocks, nil
+}
+
+// extractPlantUMLBlocks returns the diagrams of a PlantUML file, which may hold several
+// @startuml ... @enduml blocks. Text between the blocks is ignored, like PlantUML does.
+func extractPlantUMLBlocks(lines []string) ([]Block, error) {
+	var blocks []Block
+	start := -1
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case start < 0 && plantUMLStart.MatchString(trimmed):
+			start = i
+		case start >= 0 && plantUMLEnd.MatchString(trimmed):
+			blocks = append(blocks, Block{
+				Content:  joinLines(lines[start : i+1]),
+				Line:     start + 1,
+				EndLine:  i + 2,
+				PlantUML: true,
+			})
+			start = -1
+		}
+	}
+
+	if start >= 0 {
+		return nil, errors.New("unclosed diagram")
+	}
+
+	return blocks, nil
+}
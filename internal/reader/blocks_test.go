@@ -0,0 +1,212 @@
+package reader
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractMarkdownBlocks(t *testing.T) {
+	input := strings.Join([]string{
+		"# Title",
+		"```mermaid",
+		"classDiagram",
+		"```",
+		"- item",
+		"  ```mermaid",
+		"  sequenceDiagram",
+		"    A ->> B : call()",
+		"  ```",
+		"~~~mermaid",
+		"classDiagram",
+		"~~~",
+		"``` mermaid",
+		"classDiagram",
+		"```",
+		"```mermaid {title=\"Orders\"}",
+		"classDiagram",
+		"```",
+		"````markdown",
+		"```mermaid",
+		"classDiagram",
+		"```",
+		"````",
+		"````mermaid",
+		"classDiagram",
+		"```",
+		"````",
+		"```go",
+		"func main() {}",
+		"```",
+	}, "\r\n")
+
+	blocks, err := ExtractBlocks(input, Markdown)
+	if err != nil {
+		t.Fatalf("Error extracting blocks: %v", err)
+	}
+
+	expected := []Block{
+		{Content: "classDiagram\n", Line: 3, EndLine: 4},
+		{Content: "sequenceDiagram\n  A ->> B : call()\n", Line: 7, EndLine: 9, Indent: 2},
+		{Content: "classDiagram\n", Line: 11, EndLine: 12},
+		{Content: "classDiagram\n", Line: 14, EndLine: 15},
+		{Content: "classDiagram\n", Line: 17, EndLine: 18},
+		{Content: "classDiagram\n```\n", Line: 25, EndLine: 27},
+	}
+	if len(blocks) != len(expected) {
+		t.Fatalf("Expected %d blocks, got %d: %#v", len(expected), len(blocks), blocks)
+	}
+	for i := range expected {
+		if blocks[i] != expected[i] {
+			t.Errorf("Block %d:\nExpected: %#v\nGot:      %#v", i, expected[i], blocks[i])
+		}
+	}
+}
+
+func TestExtractMarkdownBlocksUnclosed(t *testing.T) {
+	if _, err := ExtractBlocks("```mermaid\nclassDiagram\n", Markdown); err == nil {
+		t.Error("Expected an error for an unclosed diagram")
+	}
+	if _, err := ExtractBlocks("```go\nfunc main() {}\n", Markdown); err != nil {
+		t.Errorf("Unclosed non-mermaid fences should be ignored, got %v", err)
+	}
+}
+
+func TestExtractAsciiDocBlocks(t *testing.T) {
+	input := strings.Join([]string{
+		"= Design",
+		"[mermaid]",
+		".Orders",
+		"----",
+		"classDiagram",
+		"----",
+		"[source,java]",
+		"----",
+		"[mermaid]",
+		"----",
+		"[mermaid, format=svg]",
+		"....",
+		"sequenceDiagram",
+		"....",
+	}, "\n")
+
+	blocks, err := ExtractBlocks(input, AsciiDoc)
+	if err != nil {
+		t.Fatalf("Error extracting blocks: %v", err)
+	}
+
+	expected := []Block{
+		{Content: "classDiagram\n", Line: 5, EndLine: 6},
+		{Content: "sequenceDiagram\n", Line: 13, EndLine: 14},
+	}
+	if len(blocks) != len(expected) {
+		t.Fatalf("Expected %d blocks, got %d: %#v", len(expected), len(blocks), blocks)
+	}
+	for i := range expected {
+		if blocks[i] != expected[i] {
+			t.Errorf("Block %d:\nExpected: %#v\nGot:      %#v", i, expected[i], blocks[i])
+		}
+	}
+}
+
+func TestExtractPlantUMLBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []string
+		syntax   Syntax
+		expected []Block
+	}{
+		{"PlantUML file", []string{
+			"' shared settings",
+			"@startuml classes",
+			"class Order",
+			"@enduml",
+			"",
+			"@startuml",
+			"A -> B : call()",
+			"@enduml",
+		}, PlantUML, []Block{
+			{Content: "@startuml classes\nclass Order\n@enduml\n", Line: 2, EndLine: 5, PlantUML: true},
+			{Content: "@startuml\nA -> B : call()\n@enduml\n", Line: 6, EndLine: 9, PlantUML: true},
+		}},
+		{"Markdown", []string{
+			"```plantuml",
+			"@startuml",
+			"@enduml",
+			"```",
+			"```mermaid",
+			"classDiagram",
+			"```",
+			"~~~puml",
+			"@startuml",
+			"@enduml",
+			"~~~",
+			"```",
+			"",
+			"@startuml",
+			"@enduml",
+			"```",
+			"```",
+			"plain text",
+			"```",
+			"@startuml",
+			"@enduml",
+		}, Markdown, []Block{
+			{Content: "@startuml\n@enduml\n", Line: 2, EndLine: 4, PlantUML: true},
+			{Content: "classDiagram\n", Line: 6, EndLine: 7},
+			{Content: "@startuml\n@enduml\n", Line: 9, EndLine: 11, PlantUML: true},
+			// Unlabelled blocks starting with @startuml, but not @startuml outside of blocks
+			{Content: "\n@startuml\n@enduml\n", Line: 13, EndLine: 16, PlantUML: true},
+		}},
+		{"AsciiDoc", []string{
+			"[plantuml, target=orders, format=svg]",
+			"----",
+			"@startuml",
+			"@enduml",
+			"----",
+		}, AsciiDoc, []Block{
+			{Content: "@startuml\n@enduml\n", Line: 3, EndLine: 5, PlantUML: true},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			blocks, err := ExtractBlocks(strings.Join(test.input, "\n"), test.syntax)
+			if err != nil {
+				t.Fatalf("Error extracting blocks: %v", err)
+			}
+			if len(blocks) != len(test.expected) {
+				t.Fatalf("Expected %d blocks, got %d: %#v", len(test.expected), len(blocks), blocks)
+			}
+			for i := range test.expected {
+				if blocks[i] != test.expected[i] {
+					t.Errorf("Block %d:\nExpected: %#v\nGot:      %#v", i, test.expected[i], blocks[i])
+				}
+			}
+		})
+	}
+
+	if _, err := ExtractBlocks("@startuml\nclass Order\n", PlantUML); err == nil {
+		t.Error("Expected an error for an unclosed diagram")
+	}
+	if syntax := SyntaxOf("docs/orders.puml"); syntax != PlantUML {
+		t.Errorf("Expected .puml files to be PlantUML, got %v", syntax)
+	}
+}
+
+func TestStripIndent(t *testing.T) {
+	tests := []struct {
+		line  string
+		width int
+		want  string
+	}{
+		{"    foo", 2, "  foo"},
+		{" foo", 2, "foo"},
+		{"\tfoo", 4, "foo"},
+		{"\tfoo", 2, "  foo"},
+		{"", 2, ""},
+	}
+	for _, test := range tests {
+		if got := stripIndent(test.line, test.width); got != test.want {
+			t.Errorf("stripIndent(%q, %d) = %q, want %q", test.line, test.width, got, test.want)
+		}
+	}
+}
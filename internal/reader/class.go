@@ -10,9 +10,36 @@ type ClassDiagram struct {
 }
 
 type ClassInstruction struct {
-	Relationship *Relationship `  @@`
-	Member       *ClassMember  `| @@`
-	Annotation   *Annotation   `| @@`
+	Pos lexer.Position
+
+	Namespace    *Namespace        `  @@`
+	Class        *ClassDeclaration `| @@`
+	Relationship *Relationship     `| @@`
+	Member       *ClassMember      `| @@`
+	Annotation   *Annotation       `| @@`
+}
+
+// Namespace groups classes, the dotted name is used as package of its classes.
+type Namespace struct {
+	Name    string              `"namespace" @Word ( @"." @Word )* "{" Break*`
+	Classes []*ClassDeclaration `@@* "}" Break*`
+}
+
+// ClassDeclaration declares a class, optionally with its members in braces.
+type ClassDeclaration struct {
+	Pos lexer.Position
+
+	Name    string       `"class" @Word`
+	Members []*ClassBody `( "{" Break* @@* "}" )? Break*`
+}
+
+// ClassBody is a member declared inside the braces of a class declaration.
+type ClassBody struct {
+	Pos lexer.Position
+
+	Visibility string     `@Visibility?`
+	Operation  *Operation `( @@`
+	Attribute  *Attribute `| @@ )`
 }
 
 type Relationship struct {
@@ -27,8 +54,8 @@ type Relationship struct {
 type ClassMember struct {
 	Class      string     `@Word ":"`
 	Visibility string     `@Visibility?`
-	Operation  *Operation `@@?`
-	Attribute  *Attribute `@@?`
+	Operation  *Operation `( @@`
+	Attribute  *Attribute `| @@ )`
 }
 
 type Operation struct {
@@ -55,19 +82,21 @@ type Annotation struct {
 var (
 	ClassDiagramLexer = lexer.MustSimple([]lexer.SimpleRule{
 		{"diagramType", `classDiagram`},
-		{"Word", `[a-zA-Z]\w*`},
+		{"Word", `[a-zA-Z]\w*(~[\w,~]*~)?`}, // generic types are written as List~String~
 		{"Break", `\n`},
 		{"Claw", `(<<)|(>>)`},
-		{"Special", `[,:\(\)]`},
+		{"Special", `[,:\(\)\{\}]`},
 		{"Relationship", `(<\||\*|o|<)?(--|\.\.)(\|>|\*|o|>)?`},
+		{"Dot", `\.`},
 		{"Visibility", `[+\-#~]`},
 		{"Cardinality", `\"(1|(0\.\.1)|\*|(1\.\.\*))\"`},
-		{"comment", `%%[^\n]*`},
+		{"Comment", `%%[^\n]*`},
 		{"whitespace", `[ \t\r]+`},
 	})
 
 	ClassDiagramParser = participle.MustBuild[ClassDiagram](
 		participle.Lexer(ClassDiagramLexer),
 		participle.Unquote("Cardinality"),
+		participle.Elide("Comment"),
 	)
 )
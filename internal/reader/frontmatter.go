@@ -0,0 +1,106 @@
+package reader
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Frontmatter is the YAML header that Mermaid allows in front of the diagram keyword:
+//
+//	---
+//	title: Orders
+//	merfolk:
+//	  package: com.acme.orders
+//	---
+//	classDiagram
+type Frontmatter struct {
+	Title   string         `yaml:"title"`
+	Config  map[string]any `yaml:"config"`
+	Merfolk Options        `yaml:"merfolk"`
+}
+
+// Options are the generation settings of a single diagram, taken from the
+// merfolk section of its frontmatter.
+type Options struct {
+	// Package is the target package of the generated classes.
+	Package string `yaml:"package"`
+	// Language is the target language, only "java" is supported.
+	Language string `yaml:"language"`
+	// BaseClass is inherited by every class of the diagram that has no other superclass.
+	BaseClass string `yaml:"baseClass"`
+	// Skip excludes the diagram from code generation.
+	Skip bool `yaml:"skip"`
+}
+
+// splitHeader separates the frontmatter and the %%{init: ...}%% directives from the
+// diagram body. The header lines are replaced by empty lines in the returned body so
+// that line numbers reported by the parsers still match the input.
+func splitHeader(input string) (*Frontmatter, []string, string, error) {
+	lines := strings.Split(input, "\n")
+	var frontmatter *Frontmatter
+	var directives []string
+
+	i := skipBlankLines(lines, 0)
+
+	if i < len(lines) && strings.TrimSpace(lines[i]) == "---" {
+		end := i + 1
+		for end < len(lines) && strings.TrimSpace(lines[end]) != "---" {
+			end++
+		}
+		if end == len(lines) {
+			return nil, nil, "", errors.New("unclosed frontmatter")
+		}
+
+		frontmatter = &Frontmatter{}
+		if err := yaml.Unmarshal([]byte(strings.Join(lines[i+1:end], "\n")), frontmatter); err != nil {
+			return nil, nil, "", fmt.Errorf("invalid frontmatter: %w", err)
+		}
+		blankLines(lines, i, end+1)
+		i = end + 1
+	}
+
+	for {
+		i = skipBlankLines(lines, i)
+		if i == len(lines) || !strings.HasPrefix(strings.TrimSpace(lines[i]), "%%") {
+			break
+		}
+
+		// Directives may span several lines until the closing }%%
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), "%%{") {
+			end := i
+			for end < len(lines) && !strings.HasSuffix(strings.TrimSpace(lines[end]), "}%%") {
+				end++
+			}
+			if end == len(lines) {
+				return nil, nil, "", errors.New("unclosed directive")
+			}
+			directive := strings.TrimSpace(strings.Join(lines[i:end+1], "\n"))
+			directives = append(directives, strings.TrimSuffix(strings.TrimPrefix(directive, "%%{"), "}%%"))
+			blankLines(lines, i, end+1)
+			i = end + 1
+			continue
+		}
+
+		// Plain comments in front of the keyword
+		blankLines(lines, i, i+1)
+		i++
+	}
+
+	return frontmatter, directives, strings.Join(lines, "\n"), nil
+}
+
+func skipBlankLines(lines []string, i int) int {
+	for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+		i++
+	}
+	return i
+}
+
+func blankLines(lines []string, from, to int) {
+	for i := from; i < to; i++ {
+		lines[i] = ""
+	}
+}
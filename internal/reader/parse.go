@@ -1,8 +1,8 @@
 package reader
 
 import (
-	"bufio"
 	"errors"
+	"fmt"
 	"os"
 	"strings"
 )
@@ -13,67 +13,79 @@ type Diagram struct {
 
 	IsClass bool
 	Class   *ClassDiagram
+
+	// Line is the line of the source file on which the diagram text starts.
+	Line int
+	// KeywordLine is the 1-based line of the diagram keyword within the diagram text,
+	// the lines before it hold the frontmatter and directives.
+	KeywordLine int
+
+	// Title is the title given in the frontmatter.
+	Title string
+	// Options are the generation settings given in the frontmatter.
+	Options Options
+	// Directives holds the bodies of the %%{...}%% directives in front of the diagram.
+	Directives []string
 }
 
+// ParseFile reads all Mermaid and PlantUML diagrams from a file. Markdown, MDX and AsciiDoc
+// files may contain any number of embedded diagrams, raw Mermaid files (.mmd, .mermaid) contain
+// exactly one and PlantUML files (.puml, .plantuml, .pu) any number of @startuml blocks.
 func ParseFile(dir string) ([]Diagram, error) {
-	file, err := os.Open(dir)
+	content, err := os.ReadFile(dir)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	scanner.Split(bufio.ScanLines)
 
-	var builder strings.Builder
-	reading := false
-	var diagrams []Diagram
-
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		if line == "```mermaid" {
-			if reading {
-				return nil, errors.New("nested diagram")
-			}
-			reading = true
-			continue
-		} else if line == "```" {
-			if reading {
-				reading = false
-				diagram, err := ParseDiagram(builder.String())
-				if err != nil {
-					return nil, err
-				}
-				builder.Reset()
-				diagrams = append(diagrams, *diagram)
-			}
-			continue
-		}
+	return ParseDocument(string(content), SyntaxOf(dir))
+}
 
-		if reading {
-			builder.WriteString(line + "\n")
-		}
+// ParseDocument parses all diagrams embedded in a document of the given syntax.
+func ParseDocument(content string, syntax Syntax) ([]Diagram, error) {
+	blocks, err := ExtractBlocks(content, syntax)
+	if err != nil {
+		return nil, err
 	}
 
-	if reading {
-		return nil, errors.New("unclosed diagram")
+	var diagrams []Diagram
+	for _, block := range blocks {
+		diagram, err := ParseDiagram(block.Content)
+		if err != nil {
+			return nil, fmt.Errorf("diagram at line %d: %w", block.Line, err)
+		}
+		diagram.Line = block.Line
+		diagrams = append(diagrams, *diagram)
 	}
 
 	return diagrams, nil
 }
 
+// ParseDiagram parses the text of a single class or sequence diagram. The diagram keyword
+// may be preceded by a YAML frontmatter and %%{init: ...}%% directives. Diagrams starting
+// with @startuml are PlantUML diagrams, which are parsed into the same AST.
 func ParseDiagram(input string) (*Diagram, error) {
+	if isPlantUML(input) {
+		return parsePlantUML(input)
+	}
+
 	var diagram Diagram
 
-	if strings.HasPrefix(input, "classDiagram") {
-		parsed, err := ClassDiagramParser.ParseString("", input)
+	frontmatter, directives, body, err := splitHeader(input)
+	if err != nil {
+		return nil, err
+	}
+
+	// Blank lines before the diagram keyword are allowed
+	header := strings.TrimLeft(body, " \t\r\n")
+
+	if strings.HasPrefix(header, "classDiagram") {
+		parsed, err := ClassDiagramParser.ParseString("", body)
 		if err != nil {
 			return nil, err
 		}
 		diagram = Diagram{IsClass: true, Class: parsed}
-	} else if strings.HasPrefix(input, "sequenceDiagram") {
-		parsed, err := SequenceDiagramParser.ParseString("", input)
+	} else if strings.HasPrefix(header, "sequenceDiagram") {
+		parsed, err := SequenceDiagramParser.ParseString("", body)
 		if err != nil {
 			return nil, err
 		}
@@ -82,5 +94,12 @@ func ParseDiagram(input string) (*Diagram, error) {
 		return nil, errors.New("unknown diagram type")
 	}
 
+	if frontmatter != nil {
+		diagram.Title = frontmatter.Title
+		diagram.Options = frontmatter.Merfolk
+	}
+	diagram.Directives = directives
+	diagram.KeywordLine = strings.Count(body[:len(body)-len(header)], "\n") + 1
+
 	return &diagram, nil
 }
@@ -0,0 +1,130 @@
+package reader
+
+import (
+	"testing"
+)
+
+func TestParseDiagramWithFrontmatter(t *testing.T) {
+	input := `---
+title: Orders
+config:
+  theme: forest
+merfolk:
+  package: com.acme.orders
+  language: java
+  baseClass: Entity
+  skip: true
+---
+%%{init: {"theme": "forest"}}%%
+%% a comment
+classDiagram
+   Order : cancel() void
+`
+
+	diagram, err := ParseDiagram(input)
+	if err != nil {
+		t.Fatalf("Error parsing diagram: %v", err)
+	}
+
+	if !diagram.IsClass || len(diagram.Class.Instructions) != 1 {
+		t.Fatalf("Expected a class diagram with one instruction, got %#v", diagram)
+	}
+	if diagram.Title != "Orders" {
+		t.Errorf("Expected title Orders, got %q", diagram.Title)
+	}
+	expected := Options{Package: "com.acme.orders", Language: "java", BaseClass: "Entity", Skip: true}
+	if diagram.Options != expected {
+		t.Errorf("Expected options %#v, got %#v", expected, diagram.Options)
+	}
+	if len(diagram.Directives) != 1 || diagram.Directives[0] != `init: {"theme": "forest"}` {
+		t.Errorf("Unexpected directives %q", diagram.Directives)
+	}
+}
+
+func TestParseDiagramWithMultilineDirective(t *testing.T) {
+	input := `%%{
+  init: {
+    "theme": "dark"
+  }
+}%%
+sequenceDiagram
+   A ->> B : call()
+   B -->> A : result
+`
+
+	diagram, err := ParseDiagram(input)
+	if err != nil {
+		t.Fatalf("Error parsing diagram: %v", err)
+	}
+	if !diagram.IsSequence || len(diagram.Sequence.Instructions) != 2 {
+		t.Fatalf("Expected a sequence diagram with two instructions, got %#v", diagram)
+	}
+	if len(diagram.Directives) != 1 {
+		t.Errorf("Expected one directive, got %q", diagram.Directives)
+	}
+}
+
+func TestParseSequenceDiagramKeywordPrefixes(t *testing.T) {
+	input := `sequenceDiagram
+   Service ->> Repository : createOrder(endDate, notifyAll)
+   Repository -->> Service : assigned
+   alt activeUser
+   Service ->> Repository : notes()
+   end
+`
+
+	diagram, err := ParseDiagram(input)
+	if err != nil {
+		t.Fatalf("Error parsing diagram: %v", err)
+	}
+	instructions := diagram.Sequence.Instructions
+	if len(instructions) != 5 {
+		t.Fatalf("Expected five instructions, got %d", len(instructions))
+	}
+	message := instructions[0].Message
+	if message == nil || message.Name != "createOrder" || len(message.Parameters) != 2 || message.Parameters[1] != "notifyAll" {
+		t.Errorf("Unexpected message %#v", message)
+	}
+	if alt := instructions[2].Alt; alt == nil || alt.Definition[0] != "activeUser" {
+		t.Errorf("Unexpected alt %#v", alt)
+	}
+}
+
+func TestParseClassMemberBeforeClass(t *testing.T) {
+	input := `classDiagram
+   Order : +total() int
+   class Item
+`
+
+	diagram, err := ParseDiagram(input)
+	if err != nil {
+		t.Fatalf("Error parsing diagram: %v", err)
+	}
+	instructions := diagram.Class.Instructions
+	if len(instructions) != 2 || instructions[1].Class == nil || instructions[1].Class.Name != "Item" {
+		t.Errorf("Expected the member of Order and the class Item, got %#v", instructions)
+	}
+}
+
+func TestParseDiagramErrorKeepsLineNumbers(t *testing.T) {
+	input := `---
+title: Broken
+---
+classDiagram
+   Order : cancel(
+`
+
+	_, err := ParseDiagram(input)
+	if err == nil {
+		t.Fatal("Expected a parse error")
+	}
+	if got := err.Error(); len(got) < 2 || got[:2] != "5:" {
+		t.Errorf("Expected the error to point to line 5, got %q", got)
+	}
+}
+
+func TestParseDiagramUnclosedFrontmatter(t *testing.T) {
+	if _, err := ParseDiagram("---\ntitle: x\nclassDiagram\n"); err == nil {
+		t.Error("Expected an error for an unclosed frontmatter")
+	}
+}
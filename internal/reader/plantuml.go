@@ -0,0 +1,549 @@
+package reader
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/participle/v2"
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// PlantUML class and sequence diagrams are translated into Mermaid line by line and parsed
+// with the Mermaid grammars, so that both produce the same AST. Every PlantUML line becomes
+// the Mermaid statement of the same meaning, or a blank line for comments, notes and the
+// layout and styling statements, so the positions in the AST and in parse errors are the
+// lines of the PlantUML source. Statements that Mermaid writes separately, like the
+// stereotypes and super types of a class declaration, are appended after the last line.
+// Statements outside of the subset that Mermaid supports are passed on unchanged and
+// reported by the Mermaid parser.
+
+var (
+	plantUMLStart = regexp.MustCompile(`^@startuml\b`)
+	plantUMLEnd   = regexp.MustCompile(`^@enduml\b`)
+
+	plantUMLIgnored   = regexp.MustCompile(`^((skinparam|hide|show|scale|caption|header|footer|mainframe|newpage|autonumber|allowmixing|allow_mixing)\b|(left to right|top to bottom) direction$|set\s+namespaceSeparator\b|!)`)
+	plantUMLTitle     = regexp.MustCompile(`^title\s+(.+)$`)
+	plantUMLNote      = regexp.MustCompile(`^[rh]?note\b`)
+	plantUMLNoteAlias = regexp.MustCompile(`\bas\s+(\w+)$`)
+
+	plantUMLClassKeyword    = regexp.MustCompile(`^(abstract|class|interface|enum|annotation|package|namespace)\b`)
+	plantUMLSequenceKeyword = regexp.MustCompile(`^(participant|actor|boundary|control|database|collections|queue|activate|deactivate|loop|alt|opt|return|create|destroy)\b`)
+
+	plantUMLClass       = regexp.MustCompile(`^(?:(abstract)(?:\s+class)?|(class|interface|enum|annotation|entity))\s+(?:"[^"]*"\s+as\s+(\w+)|(\w+)(<[^>]*>)?(?:\s+as\s+"[^"]*")?)\s*(.*)$`)
+	plantUMLNamespace   = regexp.MustCompile(`^(?:package|namespace)\s+"?([\w.]+)"?[^{]*(\{\s*\}?)$`)
+	plantUMLStereotype  = regexp.MustCompile(`<<\s*([^>]*?)\s*>>`)
+	plantUMLColor       = regexp.MustCompile(`#\w+`)
+	plantUMLDirection   = regexp.MustCompile(`([-.])(?:\[[^\]]*\]|up|down|left|right|u|d|l|r)([-.])`)
+	plantUMLRelation    = regexp.MustCompile(`^(\w+)\s*("[^"]*")?\s*(<\||[*o<])?(-+|\.+)(\|>|[*o>])?\s*("[^"]*")?\s*(\w+)\s*(?::\s*(.*))?$`)
+	plantUMLMember      = regexp.MustCompile(`^(\w+)\s*:\s*(.+)$`)
+	plantUMLModifier    = regexp.MustCompile(`\{(static|abstract|classifier|field|method)\}`)
+	plantUMLSeparator   = regexp.MustCompile(`^(--|\.\.|==|__)`)
+	plantUMLParticipant = regexp.MustCompile(`^(participant|actor|boundary|control|entity|database|collections|queue)\s+(?:"[^"]*"\s+as\s+(\w+)|(\w+)(?:\s+as\s+(?:"[^"]*"|(\w+)))?)`)
+	plantUMLCreate      = regexp.MustCompile(`^create\s+(?:(participant|actor|boundary|control|entity|database|collections|queue)\s+)?(\w+)`)
+	plantUMLActivation  = regexp.MustCompile(`^(activate|deactivate|destroy)\s+(\w+)`)
+	plantUMLArrowStyle  = regexp.MustCompile(`(-+)\[[^\]]*\]`)
+	plantUMLMessage     = regexp.MustCompile(`^(\w+)\s*(<<?)?(-{1,2})(>>?|\\\\?|//?)?(?:(x|o)\s)?\s*(\w+)\s*((?:(?:\+\+|--|\*\*|!!)\s*)*)(?::\s*(.*?))?\s*$`)
+	plantUMLFragment    = regexp.MustCompile(`^(loop|alt|opt|else|end)\b\s*(.*)$`)
+	plantUMLSpacing     = regexp.MustCompile(`^(==|\.\.\.|\|\|)`)
+	plantUMLBox         = regexp.MustCompile(`^(box\b|end\s*box$)`)
+	mermaidWord         = regexp.MustCompile(`^[a-zA-Z]\w*$`)
+)
+
+// isPlantUML reports whether the diagram text starts with @startuml.
+func isPlantUML(text string) bool {
+	return plantUMLStart.MatchString(strings.TrimLeft(text, " \t\r\n"))
+}
+
+// parsePlantUML parses a PlantUML class or sequence diagram. The diagram type is that of
+// the first statement that only one of them has.
+func parsePlantUML(input string) (*Diagram, error) {
+	lines := strings.Split(strings.ReplaceAll(input, "\r\n", "\n"), "\n")
+	start, end := -1, -1
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if start < 0 && plantUMLStart.MatchString(trimmed) {
+			start = i
+		} else if start >= 0 && plantUMLEnd.MatchString(trimmed) {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return nil, participle.Errorf(lexer.Position{Line: start + 1, Column: 1}, "missing @enduml")
+	}
+
+	// Line i of the body is line start+i+2 of the diagram text
+	statements, title, notes, err := plantUMLStatements(lines[start+1:end], start+2)
+	if err != nil {
+		return nil, err
+	}
+
+	var diagram Diagram
+	var translated, tail []string
+	switch plantUMLKind(statements) {
+	case "class":
+		translated, tail = translateClassDiagram(statements, notes)
+		diagram.IsClass = true
+	case "sequence":
+		if translated, err = translateSequenceDiagram(statements, start+2); err != nil {
+			return nil, err
+		}
+		diagram.IsSequence = true
+	default:
+		return nil, errors.New("unknown diagram type")
+	}
+
+	keyword := "classDiagram"
+	if diagram.IsSequence {
+		keyword = "sequenceDiagram"
+	}
+	text := make([]string, 0, end+1+len(tail))
+	text = append(text, make([]string, start)...)
+	text = append(text, keyword)
+	text = append(text, translated...)
+	text = append(text, "")
+	text = append(text, tail...)
+
+	if diagram.IsClass {
+		diagram.Class, err = ClassDiagramParser.ParseString("", joinLines(text))
+	} else {
+		diagram.Sequence, err = SequenceDiagramParser.ParseString("", joinLines(text))
+	}
+	if err != nil {
+		return nil, err
+	}
+	diagram.Title = title
+	diagram.KeywordLine = start + 1
+	return &diagram, nil
+}
+
+// plantUMLStatements returns the trimmed lines of the body of a diagram, with blank lines for
+// comments, notes and the styling and layout statements, and the title of the diagram. notes
+// holds the aliases of the notes, which class diagrams attach to classes with relationships.
+// first is the line of the first line of body in the diagram text.
+func plantUMLStatements(body []string, first int) (statements []string, title string, notes map[string]bool, err error) {
+	statements = make([]string, len(body))
+	notes = make(map[string]bool)
+
+	// end matches the line that closes a multi-line comment, note or other text block
+	var end func(string) bool
+	open, opened := "", 0
+	closedBy := func(pattern string) func(string) bool {
+		re := regexp.MustCompile(pattern)
+		return re.MatchString
+	}
+
+	for i, line := range body {
+		s := strings.TrimSpace(line)
+		if end != nil {
+			if end(s) {
+				end = nil
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(s, "/'"):
+			if !strings.Contains(s[2:], "'/") {
+				end = func(s string) bool { return strings.Contains(s, "'/") }
+				open, opened = "comment", i
+			}
+		case strings.HasPrefix(s, "'"):
+		case plantUMLNote.MatchString(s):
+			alias := plantUMLNoteAlias.FindStringSubmatch(s)
+			if alias != nil {
+				notes[alias[1]] = true
+			}
+			// A note without text on its line ends with "end note"
+			if !strings.Contains(s, ":") && !strings.Contains(s, `"`) {
+				end = closedBy(`^end\s*[rh]?note$`)
+				open, opened = "note", i
+			}
+		case strings.HasPrefix(s, "ref ") && !strings.Contains(s, ":"):
+			end = closedBy(`^end\s*ref$`)
+			open, opened = "ref", i
+		case s == "legend" || strings.HasPrefix(s, "legend "):
+			end = closedBy(`^end\s*legend$`)
+			open, opened = "legend", i
+		case s == "title" || s == "header" || s == "footer":
+			end = closedBy(`^end\s*` + s + `$`)
+			open, opened = s, i
+		case strings.HasPrefix(s, "skinparam") && strings.HasSuffix(s, "{"):
+			end = closedBy(`^\}$`)
+			open, opened = "skinparam", i
+		case plantUMLTitle.MatchString(s):
+			title = plantUMLTitle.FindStringSubmatch(s)[1]
+		case plantUMLIgnored.MatchString(s):
+		default:
+			statements[i] = s
+		}
+	}
+
+	if end != nil {
+		return nil, "", nil, participle.Errorf(lexer.Position{Line: first + opened, Column: 1}, "unclosed %s", open)
+	}
+	return statements, title, notes, nil
+}
+
+// plantUMLKind returns "class" or "sequence" for the type of the diagram with the given
+// statements, or an empty string if none of them tells.
+func plantUMLKind(statements []string) string {
+	message := false
+	for _, s := range statements {
+		switch {
+		case s == "":
+		case plantUMLClassKeyword.MatchString(s):
+			return "class"
+		case plantUMLSequenceKeyword.MatchString(s):
+			return "sequence"
+		case plantUMLArrowMessage(s) != nil:
+			m := plantUMLArrowMessage(s)
+			// Single dashes and open arrow heads are only used by sequence diagrams
+			if m[3] == "-" || m[4] == ">>" || m[2] == "<<" {
+				return "sequence"
+			}
+			message = true
+		case plantUMLRelation.MatchString(plantUMLDirection.ReplaceAllString(s, "$1$2")):
+			return "class"
+		case plantUMLMember.MatchString(s):
+			return "class"
+		}
+	}
+	if message {
+		return "sequence"
+	}
+	return ""
+}
+
+// plantUMLArrowMessage returns the submatches of plantUMLMessage for a message, nil if s is
+// none. Lines without arrow head are no messages.
+func plantUMLArrowMessage(s string) []string {
+	m := plantUMLMessage.FindStringSubmatch(plantUMLArrowStyle.ReplaceAllString(s, "$1"))
+	if m == nil || m[2] == "" && m[4] == "" && m[5] == "" {
+		return nil
+	}
+	return m
+}
+
+// translateClassDiagram translates the statements of a class diagram into Mermaid. It
+// returns a line for every statement and the statements to append after the last line.
+func translateClassDiagram(statements []string, notes map[string]bool) (lines, tail []string) {
+	lines = make([]string, len(statements))
+	// blocks are the open braces: "namespace", "class" or "group" for braces Mermaid has no use for
+	var blocks []string
+	top := func() string {
+		if len(blocks) == 0 {
+			return ""
+		}
+		return blocks[len(blocks)-1]
+	}
+
+	for i, s := range statements {
+		switch {
+		case s == "":
+		case s == "}" && len(blocks) > 0:
+			if top() != "group" {
+				lines[i] = "}"
+			}
+			blocks = blocks[:len(blocks)-1]
+		case top() == "class":
+			if !plantUMLSeparator.MatchString(s) {
+				lines[i] = plantUMLClassMember(s)
+			}
+		case plantUMLClass.MatchString(s):
+			declaration, open, appended := translateClassDeclaration(s)
+			lines[i] = declaration
+			tail = append(tail, appended...)
+			if open {
+				blocks = append(blocks, "class")
+			}
+		case plantUMLNamespace.MatchString(s):
+			m := plantUMLNamespace.FindStringSubmatch(s)
+			if m[2] == "{" {
+				lines[i] = "namespace " + m[1] + " {"
+				blocks = append(blocks, "namespace")
+			}
+		case s == "together {":
+			blocks = append(blocks, "group")
+		default:
+			statement := translateClassStatement(s, notes)
+			// Mermaid namespaces only hold class declarations
+			if top() == "namespace" {
+				if statement != "" {
+					tail = append(tail, statement)
+				}
+			} else {
+				lines[i] = statement
+			}
+		}
+	}
+	return lines, tail
+}
+
+// translateClassDeclaration translates a class, interface or enum declaration. It returns
+// the Mermaid class declaration, whether it opens a body, and the annotations and
+// relationships of the stereotypes and super types.
+func translateClassDeclaration(s string) (declaration string, open bool, tail []string) {
+	m := plantUMLClass.FindStringSubmatch(s)
+	name := m[3] + m[4]
+	kind := m[1] + m[2]
+
+	switch kind {
+	case "abstract", "interface":
+		tail = append(tail, "<<"+kind+">> "+name)
+	case "enum":
+		tail = append(tail, "<<enumeration>> "+name)
+	}
+	rest := m[6]
+	for _, stereotype := range plantUMLStereotype.FindAllStringSubmatch(rest, -1) {
+		if mermaidWord.MatchString(stereotype[1]) {
+			tail = append(tail, "<<"+stereotype[1]+">> "+name)
+		}
+	}
+	rest = plantUMLColor.ReplaceAllString(plantUMLStereotype.ReplaceAllString(rest, ""), "")
+
+	// extends A, B implements C { ... }
+	arrow := "<|--"
+	var body string
+	for _, field := range strings.FieldsFunc(rest, func(r rune) bool { return r == ' ' || r == '\t' || r == ',' }) {
+		switch {
+		case field == "extends":
+			arrow = "<|--"
+		case field == "implements":
+			arrow = "<|.."
+		case strings.HasPrefix(field, "{"):
+			body = field
+		default:
+			if i := strings.IndexByte(field, '<'); i > 0 {
+				field = field[:i]
+			}
+			tail = append(tail, field+" "+arrow+" "+name)
+		}
+	}
+
+	declaration = "class " + name + plantUMLType(m[5])
+	switch body {
+	case "{":
+		declaration += " {"
+		open = true
+	case "{}":
+		declaration += " { }"
+	}
+	return declaration, open, tail
+}
+
+// translateClassStatement translates a relationship or a member declared outside of its
+// class. Relationships of notes are left out.
+func translateClassStatement(s string, notes map[string]bool) string {
+	if m := plantUMLRelation.FindStringSubmatch(plantUMLDirection.ReplaceAllString(s, "$1$2")); m != nil {
+		if notes[m[1]] || notes[m[7]] {
+			return ""
+		}
+		line := m[1]
+		if m[2] != "" {
+			line += " " + m[2]
+		}
+		line += " " + m[3] + m[4][:1] + m[4][:1] + m[5]
+		if m[6] != "" {
+			line += " " + m[6]
+		}
+		line += " " + m[7]
+		// Labels may point to the end they are read towards, Mermaid has no such marker
+		if label := strings.TrimSpace(strings.Trim(m[8], "<> \t")); label != "" {
+			line += " : " + label
+		}
+		return line
+	}
+	if m := plantUMLMember.FindStringSubmatch(s); m != nil && !notes[m[1]] {
+		return m[1] + " : " + plantUMLClassMember(m[2])
+	}
+	return s
+}
+
+// plantUMLClassMember translates an attribute or method. PlantUML writes the types after
+// a colon or in front like Java, Mermaid in front of attributes and parameters and after
+// methods. Modifiers and default values are left out.
+func plantUMLClassMember(s string) string {
+	s = strings.TrimSpace(plantUMLModifier.ReplaceAllString(s, ""))
+	visibility := ""
+	if s != "" && strings.ContainsRune("+-#~", rune(s[0])) {
+		visibility, s = s[:1], strings.TrimSpace(s[1:])
+	}
+
+	open := strings.IndexByte(s, '(')
+	end := strings.LastIndexByte(s, ')')
+	if open < 0 || end < open {
+		return visibility + plantUMLVariable(s)
+	}
+
+	name, result := strings.TrimSpace(s[:open]), ""
+	if i := strings.LastIndexAny(name, " \t"); i >= 0 {
+		result, name = name[:i], name[i+1:]
+	}
+	if rest := strings.TrimSpace(s[end+1:]); strings.HasPrefix(rest, ":") {
+		result = rest[1:]
+	}
+
+	var parameters []string
+	for _, parameter := range splitParameters(s[open+1 : end]) {
+		if parameter = strings.TrimSpace(parameter); parameter != "" {
+			parameters = append(parameters, plantUMLVariable(parameter))
+		}
+	}
+	member := visibility + name + "(" + strings.Join(parameters, ", ") + ")"
+	if result = plantUMLType(result); result != "" {
+		member += " " + result
+	}
+	return member
+}
+
+// plantUMLVariable turns an attribute or parameter "name : Type" into "Type name". "Type name"
+// and a name without type are kept.
+func plantUMLVariable(s string) string {
+	if i := strings.IndexByte(s, '='); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSpace(s)
+	if name, typ, found := strings.Cut(s, ":"); found {
+		return plantUMLType(typ) + " " + strings.TrimSpace(name)
+	}
+	if i := strings.LastIndexAny(s, " \t"); i >= 0 {
+		return plantUMLType(s[:i]) + " " + s[i+1:]
+	}
+	return s
+}
+
+// plantUMLType writes a type like Mermaid, with generic types like List~String~.
+func plantUMLType(t string) string {
+	return strings.NewReplacer("<", "~", ">", "~").Replace(strings.Join(strings.Fields(t), ""))
+}
+
+// splitParameters splits a parameter list at the commas that are not part of a generic type.
+func splitParameters(s string) []string {
+	var parameters []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '<':
+			depth++
+		case '>':
+			depth--
+		case ',':
+			if depth == 0 {
+				parameters = append(parameters, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parameters, s[start:])
+}
+
+// translateSequenceDiagram translates the statements of a sequence diagram into Mermaid.
+// first is the line of the first statement in the diagram text.
+func translateSequenceDiagram(statements []string, first int) ([]string, error) {
+	lines := make([]string, len(statements))
+
+	// activations are the activated participants with the callers that activated them,
+	// return answers the last one like in PlantUML
+	type activation struct{ participant, caller string }
+	var activations []activation
+	callers := make(map[string]string)
+	deactivate := func(participant string) {
+		for j := len(activations) - 1; j >= 0; j-- {
+			if activations[j].participant == participant {
+				activations = append(activations[:j], activations[j+1:]...)
+				return
+			}
+		}
+	}
+
+	for i, s := range statements {
+		s = plantUMLArrowStyle.ReplaceAllString(s, "$1")
+		switch {
+		case s == "":
+		case plantUMLParticipant.MatchString(s):
+			m := plantUMLParticipant.FindStringSubmatch(s)
+			kind := "participant"
+			if m[1] == "actor" {
+				kind = "actor"
+			}
+			switch {
+			case m[2] != "":
+				lines[i] = kind + " " + m[2]
+			case m[4] != "":
+				lines[i] = kind + " " + m[4] + " as " + m[3]
+			default:
+				lines[i] = kind + " " + m[3]
+			}
+		case plantUMLCreate.MatchString(s):
+			m := plantUMLCreate.FindStringSubmatch(s)
+			kind := "participant"
+			if m[1] == "actor" {
+				kind = "actor"
+			}
+			lines[i] = "create " + kind + " " + m[2]
+		case plantUMLActivation.MatchString(s):
+			m := plantUMLActivation.FindStringSubmatch(s)
+			lines[i] = m[1] + " " + m[2]
+			if m[1] == "activate" {
+				activations = append(activations, activation{m[2], callers[m[2]]})
+			} else {
+				deactivate(m[2])
+			}
+		case plantUMLBox.MatchString(s):
+		case plantUMLFragment.MatchString(s):
+			m := plantUMLFragment.FindStringSubmatch(s)
+			switch m[1] {
+			case "opt":
+				// An optional fragment is an alternative without else
+				lines[i] = strings.TrimSpace("alt " + m[2])
+			case "else", "end":
+				lines[i] = m[1]
+			default:
+				lines[i] = strings.TrimSpace(m[1] + " " + m[2])
+			}
+		case s == "return" || strings.HasPrefix(s, "return "):
+			if len(activations) == 0 || activations[len(activations)-1].caller == "" {
+				return nil, participle.Errorf(lexer.Position{Line: first + i, Column: 1}, "return without an activated call")
+			}
+			a := activations[len(activations)-1]
+			activations = activations[:len(activations)-1]
+			if label := strings.TrimSpace(strings.TrimPrefix(s, "return")); label != "" {
+				lines[i] = a.participant + " -->> " + a.caller + ": " + label
+			}
+		case plantUMLArrowMessage(s) != nil:
+			m := plantUMLArrowMessage(s)
+			from, to := m[1], m[6]
+			if m[2] != "" {
+				from, to = to, from
+			}
+			arrow := "->>"
+			if m[3] == "--" {
+				arrow = "-->>"
+			}
+			if m[5] == "x" {
+				arrow = m[3] + "x"
+			}
+
+			if arrow == "->>" {
+				callers[to] = from
+			}
+			// ++ activates the receiver, -- deactivates the sender
+			if strings.Contains(m[7], "--") {
+				deactivate(from)
+			}
+			if strings.Contains(m[7], "++") {
+				activations = append(activations, activation{to, from})
+			}
+			// Messages without label call no method
+			if m[8] != "" {
+				lines[i] = from + " " + arrow + " " + to + ": " + m[8]
+			}
+		case plantUMLSpacing.MatchString(s):
+		default:
+			lines[i] = s
+		}
+	}
+	return lines, nil
+}
@@ -0,0 +1,197 @@
+package reader
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParsePlantUMLClassDiagram(t *testing.T) {
+	input := `@startuml
+title Shop
+skinparam classAttributeIconSize 0
+package com.shop {
+  abstract class Entity {
+    - id : String
+  }
+  class Order<T> extends Entity implements Priced {
+    - items : List<Item>
+    ==
+    + add(item : Item, Map<String, Integer> counts) : void
+    + int total()
+  }
+}
+' a comment
+note "aggregate" as N1
+N1 .. Order
+Order "1" *-- "*" Item : items >
+Order -up-> Status
+Item : + price() : int
+@enduml
+`
+
+	diagram, err := ParseDiagram(input)
+	if err != nil {
+		t.Fatalf("Error parsing diagram: %v", err)
+	}
+	if !diagram.IsClass || diagram.Title != "Shop" || diagram.KeywordLine != 1 {
+		t.Fatalf("Expected the class diagram Shop, got %#v", diagram)
+	}
+
+	var statements []string
+	for _, instruction := range diagram.Class.Instructions {
+		switch {
+		case instruction.Namespace != nil:
+			statements = append(statements, fmt.Sprintf("%d namespace %s", instruction.Pos.Line, instruction.Namespace.Name))
+			for _, class := range instruction.Namespace.Classes {
+				statements = append(statements, fmt.Sprintf("%d class %s", class.Pos.Line, class.Name))
+				for _, member := range class.Members {
+					statements = append(statements, fmt.Sprintf("%d %s", member.Pos.Line, member.Visibility+memberString(member.Operation, member.Attribute)))
+				}
+			}
+		case instruction.Relationship != nil:
+			r := instruction.Relationship
+			statement := fmt.Sprintf("%d %s %q %s %q %s", instruction.Pos.Line, r.LeftClass, r.LeftCardinality, r.Type, r.RightCardinality, r.RightClass)
+			if r.Label != "" {
+				statement += " : " + r.Label
+			}
+			statements = append(statements, strings.ReplaceAll(statement, ` ""`, ""))
+		case instruction.Member != nil:
+			m := instruction.Member
+			statements = append(statements, fmt.Sprintf("%d %s : %s", instruction.Pos.Line, m.Class, m.Visibility+memberString(m.Operation, m.Attribute)))
+		case instruction.Annotation != nil:
+			statements = append(statements, fmt.Sprintf("%d <<%s>> %s", instruction.Pos.Line, instruction.Annotation.Name, instruction.Annotation.Class))
+		}
+	}
+
+	expected := []string{
+		"4 namespace com.shop",
+		"5 class Entity",
+		"6 -String id",
+		"8 class Order~T~",
+		"9 -List~Item~ items",
+		"11 +add(Item item, Map~String,Integer~ counts) void",
+		"12 +total() int",
+		`18 Order "1" *-- "*" Item : items`,
+		"19 Order --> Status",
+		"20 Item : +price() int",
+		// The stereotypes and super types are appended after the last line
+		"22 <<abstract>> Entity",
+		"23 Entity <|-- Order",
+		"24 Priced <|.. Order",
+	}
+	if strings.Join(statements, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(statements, "\n"))
+	}
+}
+
+// memberString writes a member like Mermaid.
+func memberString(operation *Operation, attribute *Attribute) string {
+	if attribute != nil {
+		return strings.TrimSpace(attribute.Type + " " + attribute.Name)
+	}
+	var parameters []string
+	for _, parameter := range operation.Parameters {
+		parameters = append(parameters, strings.TrimSpace(parameter.Type+" "+parameter.Name))
+	}
+	return strings.TrimSpace(operation.Name + "(" + strings.Join(parameters, ", ") + ") " + operation.Return)
+}
+
+func TestParsePlantUMLSequenceDiagram(t *testing.T) {
+	input := `
+@startuml
+actor user
+participant "Data service" as DataService
+autonumber
+user -> Application : login()
+activate Application
+Application -[#red]> DataService ++ : fetch(token)
+note right
+  checks the token
+end note
+opt cached
+  DataService <- Cache : hit
+end
+alt valid
+  DataService -> AuthService : verify(token)
+  AuthService --> DataService : ok
+else invalid
+  DataService ->x Application
+end
+return data
+Application --> user : data
+@enduml
+`
+
+	diagram, err := ParseDiagram(input)
+	if err != nil {
+		t.Fatalf("Error parsing diagram: %v", err)
+	}
+	if !diagram.IsSequence || diagram.KeywordLine != 2 {
+		t.Fatalf("Expected a sequence diagram with the keyword on line 2, got %#v", diagram)
+	}
+
+	var statements []string
+	for _, instruction := range diagram.Sequence.Instructions {
+		var statement string
+		switch {
+		case instruction.Message != nil:
+			m := instruction.Message
+			statement = m.Left + " " + m.Type + " " + m.Right + ": " + m.Name + "(" + strings.Join(m.Parameters, ", ") + ")"
+		case instruction.Member != nil:
+			statement = instruction.Member.Type + " " + instruction.Member.Name
+		case instruction.Switch != nil:
+			statement = instruction.Switch.Type + " " + instruction.Switch.Name
+		case instruction.Alt != nil:
+			statement = "alt " + strings.Join(instruction.Alt.Definition, " ")
+		case instruction.Else != nil:
+			statement = "else"
+		case instruction.End != nil:
+			statement = "end"
+		}
+		statements = append(statements, fmt.Sprintf("%d %s", instruction.Pos.Line, statement))
+	}
+
+	expected := []string{
+		"3 actor user",
+		"4 participant DataService",
+		"6 user ->> Application: login()",
+		"7 activate Application",
+		"8 Application ->> DataService: fetch(token)",
+		"12 alt cached",
+		"13 Cache ->> DataService: hit()",
+		"14 end",
+		"15 alt valid",
+		"16 DataService ->> AuthService: verify(token)",
+		"17 AuthService -->> DataService: ok()",
+		"18 else",
+		"20 end",
+		"21 DataService -->> Application: data()",
+		"22 Application -->> user: data()",
+	}
+	if strings.Join(statements, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(statements, "\n"))
+	}
+}
+
+func TestParsePlantUMLErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"missing end", "@startuml\nclass Order\n", "1:1: missing @enduml"},
+		{"unclosed note", "@startuml\nclass Order\nnote left of Order\ntext\n@enduml\n", "3:1: unclosed note"},
+		{"return without call", "@startuml\nactor user\nreturn data\n@enduml\n", "3:1: return without an activated call"},
+		{"unsupported statement", "@startuml\nA -> B : call()\ngroup retries\nend\n@enduml\n", "3:"},
+		{"unknown type", "@startuml\nstart\n:step;\nstop\n@enduml\n", "unknown diagram type"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseDiagram(test.input)
+			if err == nil || !strings.HasPrefix(err.Error(), test.expected) {
+				t.Errorf("Expected an error starting with %q, got %v", test.expected, err)
+			}
+		})
+	}
+}
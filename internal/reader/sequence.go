@@ -10,6 +10,8 @@ type SequenceDiagram struct {
 }
 
 type SequenceInstruction struct {
+	Pos lexer.Position
+
 	Message *Message        `  @@`
 	Loop    *Loop           `| @@`
 	Alt     *Alt            `| @@`
@@ -46,8 +48,9 @@ type End struct {
 }
 
 type SequenceMember struct {
-	Type string `@( "participant" | "actor" )`
-	Name string `@Word ("as" Word)? Break+`
+	Type  string `@( "participant" | "actor" )`
+	Name  string `@Word`
+	Alias string `( "as" @Word )? Break+`
 }
 
 type Life struct {
@@ -64,13 +67,13 @@ type Switch struct {
 var (
 	SequenceDiagramLexer = lexer.MustSimple([]lexer.SimpleRule{
 		{"diagramType", `sequenceDiagram`},
-		{"Keyword", `(?i)(loop|alt|end|participant|actor|as|create|destroy|(de)?activate)`},
+		{"Keyword", `(?i)(loop|alt|end|participant|actor|as|create|destroy|(de)?activate)\b`},
 		{"Special", `[:,\(\)]`},
 		{"Break", `\n`},
 		{"Arrow", `((<<)?--?>>)|(--?[>x)])`},
 		{"Word", `[a-zA-Z]\w*`},
-		{"comment", `%%[^\n]*`},
-		{"note", `(?i)note[^\n]*`},
+		{"Comment", `%%[^\n]*`},
+		{"note", `(?i)note\b[^\n]*`},
 		{"whitespace", `\s+`},
 		{"String", `"(?:[^"\\]|\\.)*"`}, // Handles double-quoted strings
 		{"Null", `<null>`},              // Add support for <null>
@@ -79,5 +82,6 @@ var (
 
 	SequenceDiagramParser = participle.MustBuild[SequenceDiagram](
 		participle.Lexer(SequenceDiagramLexer),
+		participle.Elide("Comment"),
 	)
 )
@@ -0,0 +1,330 @@
+package reverse
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseGoSources returns the types declared in Go sources. The files of a directory form a
+// package; test files and the files of testdata and vendor directories are skipped.
+//
+// Structs become classes and interfaces interfaces. Embedded types are super types, and
+// defined types with constants, the Go way of writing enums, become enums. The operations of
+// a type are the methods declared for it, exported names are public, the others have package
+// visibility. A struct implements the interfaces of its package that the method set of the
+// struct or of its pointer type satisfy. Unlike in Go, a trailing error result is dropped and
+// further results are written as Tuple~A,B~.
+func parseGoSources(sources []Source) ([]*Type, error) {
+	fset := token.NewFileSet()
+	var errs []error
+
+	// Group the files by directory, in the order of their first appearance
+	var dirs []string
+	packages := make(map[string][]*goFile)
+	for _, source := range sources {
+		slashed := filepath.ToSlash(source.Path)
+		if strings.HasSuffix(slashed, "_test.go") || hasSegment(slashed, "testdata") || hasSegment(slashed, "vendor") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, source.Path, source.Content, parser.SkipObjectResolution)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		dir := path.Dir(slashed)
+		if _, exists := packages[dir]; !exists {
+			dirs = append(dirs, dir)
+		}
+		packages[dir] = append(packages[dir], &goFile{path: source.Path, file: file})
+	}
+
+	var types []*Type
+	for _, dir := range dirs {
+		types = append(types, goPackage(fset, dir, packages[dir])...)
+	}
+	return types, errors.Join(errs...)
+}
+
+type goFile struct {
+	path string
+	file *ast.File
+}
+
+// hasSegment reports whether the slash separated path has a directory of the given name.
+func hasSegment(file, name string) bool {
+	return strings.HasPrefix(file, name+"/") || strings.Contains(file, "/"+name+"/")
+}
+
+// goPackage returns the types of the files of one package.
+func goPackage(fset *token.FileSet, dir string, files []*goFile) []*Type {
+	asts := make([]*ast.File, len(files))
+	for i, file := range files {
+		asts[i] = file.file
+	}
+
+	// Only the declarations are of interest. Imported packages are empty, references to them
+	// are errors, which are ignored like all other type errors.
+	info := &types.Info{Defs: make(map[*ast.Ident]types.Object)}
+	config := types.Config{Importer: emptyImporter{}, Error: func(error) {}}
+	pkg, _ := config.Check(dir, fset, asts, info)
+
+	var result []*Type
+	byName := make(map[string]*Type)
+	named := make(map[string]*types.Named)
+
+	// Types
+	for _, file := range files {
+		for _, decl := range file.file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				spec := spec.(*ast.TypeSpec)
+				t := &Type{Name: spec.Name.Name, Package: file.file.Name.Name, File: file.path, Line: fset.Position(spec.Name.Pos()).Line}
+				switch typ := spec.Type.(type) {
+				case *ast.StructType:
+					goStruct(t, typ)
+				case *ast.InterfaceType:
+					goInterface(t, typ)
+				}
+				result = append(result, t)
+				byName[t.Name] = t
+				if obj, ok := info.Defs[spec.Name].(*types.TypeName); ok {
+					if n, ok := obj.Type().(*types.Named); ok {
+						named[t.Name] = n
+					}
+				}
+			}
+		}
+	}
+
+	// Methods and constants
+	for _, file := range files {
+		for _, decl := range file.file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv == nil || len(decl.Recv.List) == 0 {
+					continue
+				}
+				if t := byName[receiverName(decl.Recv.List[0].Type)]; t != nil {
+					t.Methods = append(t.Methods, goMethod(decl.Name.Name, decl.Type))
+				}
+			case *ast.GenDecl:
+				if decl.Tok != token.CONST {
+					continue
+				}
+				for _, spec := range decl.Specs {
+					for _, name := range spec.(*ast.ValueSpec).Names {
+						constant, ok := info.Defs[name].(*types.Const)
+						if !ok {
+							continue
+						}
+						n, ok := constant.Type().(*types.Named)
+						if !ok || n.Obj().Pkg() != pkg {
+							continue
+						}
+						if t := byName[n.Obj().Name()]; t != nil && name.Name != "_" {
+							t.Kind = Enum
+							t.Constants = append(t.Constants, name.Name)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	goImplements(result, named)
+	return result
+}
+
+// goImplements adds the interfaces of the package that the types implement.
+func goImplements(result []*Type, named map[string]*types.Named) {
+	var interfaces []*Type
+	for _, t := range result {
+		if n := named[t.Name]; t.Kind == Interface && n != nil && n.TypeParams().Len() == 0 && !n.Underlying().(*types.Interface).Empty() {
+			interfaces = append(interfaces, t)
+		}
+	}
+	sort.SliceStable(interfaces, func(i, j int) bool { return interfaces[i].Name < interfaces[j].Name })
+
+	for _, t := range result {
+		n := named[t.Name]
+		if t.Kind == Interface || n == nil || n.TypeParams().Len() > 0 {
+			continue
+		}
+		for _, iface := range interfaces {
+			underlying := named[iface.Name].Underlying().(*types.Interface)
+			if types.Implements(n, underlying) || types.Implements(types.NewPointer(n), underlying) {
+				t.Implements = append(t.Implements, iface.Name)
+			}
+		}
+	}
+}
+
+// emptyImporter returns empty packages, so that type checking does not depend on the
+// dependencies of the sources. The types of the imported packages are unknown.
+type emptyImporter struct{}
+
+func (emptyImporter) Import(importPath string) (*types.Package, error) {
+	pkg := types.NewPackage(importPath, path.Base(importPath))
+	pkg.MarkComplete()
+	return pkg, nil
+}
+
+func goStruct(t *Type, s *ast.StructType) {
+	for _, field := range s.Fields.List {
+		if len(field.Names) == 0 {
+			t.Extends = append(t.Extends, goTypeRef(field.Type).Name)
+			continue
+		}
+		for _, name := range field.Names {
+			if name.Name == "_" {
+				continue
+			}
+			t.Fields = append(t.Fields, Field{Visibility: goVisibility(name.Name), Name: name.Name, Type: goTypeRef(field.Type)})
+		}
+	}
+}
+
+func goInterface(t *Type, i *ast.InterfaceType) {
+	t.Kind = Interface
+	for _, method := range i.Methods.List {
+		signature, ok := method.Type.(*ast.FuncType)
+		switch {
+		case ok:
+			for _, name := range method.Names {
+				t.Methods = append(t.Methods, goMethod(name.Name, signature))
+			}
+		case isTypeName(method.Type):
+			t.Extends = append(t.Extends, goTypeRef(method.Type).Name)
+		}
+		// Type unions of constraint interfaces have no counterpart in the diagrams
+	}
+}
+
+func isTypeName(expr ast.Expr) bool {
+	switch expr.(type) {
+	case *ast.Ident, *ast.SelectorExpr:
+		return true
+	}
+	return false
+}
+
+func goMethod(name string, signature *ast.FuncType) Method {
+	method := Method{Visibility: goVisibility(name), Name: name}
+	for _, field := range signature.Params.List {
+		ref := goTypeRef(field.Type)
+		if len(field.Names) == 0 {
+			method.Parameters = append(method.Parameters, Parameter{Name: "arg" + strconv.Itoa(len(method.Parameters)+1), Type: ref})
+			continue
+		}
+		for _, name := range field.Names {
+			parameter := Parameter{Name: name.Name, Type: ref}
+			if parameter.Name == "_" {
+				parameter.Name = "arg" + strconv.Itoa(len(method.Parameters)+1)
+			}
+			method.Parameters = append(method.Parameters, parameter)
+		}
+	}
+
+	var results []TypeRef
+	if signature.Results != nil {
+		for _, field := range signature.Results.List {
+			for n := max(len(field.Names), 1); n > 0; n-- {
+				results = append(results, goTypeRef(field.Type))
+			}
+		}
+	}
+	if len(results) > 0 && results[len(results)-1].Name == "error" {
+		results = results[:len(results)-1]
+	}
+	switch len(results) {
+	case 0:
+	case 1:
+		method.Return = &results[0]
+	default:
+		method.Return = &TypeRef{Name: "Tuple", Arguments: results}
+	}
+	return method
+}
+
+func goVisibility(name string) Visibility {
+	if ast.IsExported(name) {
+		return Public
+	}
+	return Package
+}
+
+// receiverName returns the name of the type of a method receiver like o, *o or *List[T].
+func receiverName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// goTypeRef converts a Go type expression. Pointers are dropped, slices, arrays and variadic
+// parameters are arrays, maps and channels become Map~K,V~ and Chan~T~, function types Func
+// and anonymous structs and interfaces Struct and any.
+func goTypeRef(expr ast.Expr) TypeRef {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return TypeRef{Name: e.Name}
+	case *ast.SelectorExpr:
+		return TypeRef{Name: e.Sel.Name}
+	case *ast.StarExpr:
+		return goTypeRef(e.X)
+	case *ast.ParenExpr:
+		return goTypeRef(e.X)
+	case *ast.ArrayType:
+		ref := goTypeRef(e.Elt)
+		ref.Dimensions++
+		return ref
+	case *ast.Ellipsis:
+		ref := goTypeRef(e.Elt)
+		ref.Dimensions++
+		return ref
+	case *ast.MapType:
+		return TypeRef{Name: "Map", Arguments: []TypeRef{goTypeRef(e.Key), goTypeRef(e.Value)}}
+	case *ast.ChanType:
+		return TypeRef{Name: "Chan", Arguments: []TypeRef{goTypeRef(e.Value)}}
+	case *ast.FuncType:
+		return TypeRef{Name: "Func"}
+	case *ast.StructType:
+		return TypeRef{Name: "Struct"}
+	case *ast.InterfaceType:
+		return TypeRef{Name: "any"}
+	case *ast.IndexExpr:
+		ref := goTypeRef(e.X)
+		ref.Arguments = []TypeRef{goTypeRef(e.Index)}
+		return ref
+	case *ast.IndexListExpr:
+		ref := goTypeRef(e.X)
+		for _, index := range e.Indices {
+			ref.Arguments = append(ref.Arguments, goTypeRef(index))
+		}
+		return ref
+	}
+	return TypeRef{Name: "any"}
+}
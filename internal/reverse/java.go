@@ -0,0 +1,602 @@
+package reverse
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ParseJava returns the classes, interfaces, enums and records declared in a Java source file,
+// including nested types. Only the declarations are parsed: method bodies, initializers and
+// annotation types are skipped. Records become classes with their components as fields.
+func ParseJava(file string, src []byte) (types []*Type, err error) {
+	tokens, err := scanJava(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", file, err)
+	}
+
+	p := &javaParser{file: file, tokens: tokens}
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(javaError)
+			if !ok {
+				panic(r)
+			}
+			types, err = nil, e
+		}
+	}()
+	p.compilationUnit()
+	return p.types, nil
+}
+
+type javaTokenKind int
+
+const (
+	javaEOF javaTokenKind = iota
+	javaIdent
+	javaLiteral
+	javaSymbol
+)
+
+type javaToken struct {
+	kind javaTokenKind
+	text string
+	line int
+}
+
+// scanJava splits Java source code into tokens. Comments are dropped. Every symbol is a
+// token of its own, except for the ellipsis, so that the closing brackets of nested type
+// arguments are separate tokens.
+func scanJava(src []byte) ([]javaToken, error) {
+	var tokens []javaToken
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			i++
+		case c == '/' && i+1 < len(src) && src[i+1] == '/':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := strings.Index(string(src[i+2:]), "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("%d: comment is not closed", line)
+			}
+			comment := src[i : i+2+end+2]
+			line += strings.Count(string(comment), "\n")
+			i += len(comment)
+		case c == '"' || c == '\'':
+			start, startLine := i, line
+			quote := string(c)
+			if c == '"' && strings.HasPrefix(string(src[i:]), `"""`) {
+				quote = `"""`
+			}
+			i += len(quote)
+			for {
+				if i >= len(src) || quote != `"""` && src[i] == '\n' {
+					return nil, fmt.Errorf("%d: literal is not closed", startLine)
+				}
+				if src[i] == '\\' {
+					i += 2
+					continue
+				}
+				if strings.HasPrefix(string(src[i:]), quote) {
+					i += len(quote)
+					break
+				}
+				if src[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			tokens = append(tokens, javaToken{javaLiteral, string(src[start:i]), startLine})
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(src) && (isIdentifierByte(src[i]) || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, javaToken{javaLiteral, string(src[start:i]), line})
+		case c == '.' && strings.HasPrefix(string(src[i:]), "..."):
+			tokens = append(tokens, javaToken{javaSymbol, "...", line})
+			i += 3
+		default:
+			r, size := utf8.DecodeRune(src[i:])
+			if r == '_' || r == '$' || unicode.IsLetter(r) {
+				start := i
+				for i < len(src) {
+					r, size := utf8.DecodeRune(src[i:])
+					if r != '_' && r != '$' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+						break
+					}
+					i += size
+				}
+				tokens = append(tokens, javaToken{javaIdent, string(src[start:i]), line})
+				continue
+			}
+			tokens = append(tokens, javaToken{javaSymbol, string(src[i : i+size]), line})
+			i += size
+		}
+	}
+	return append(tokens, javaToken{javaEOF, "", line}), nil
+}
+
+func isIdentifierByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// javaError is raised by the parser and turned into the error of ParseJava.
+type javaError struct {
+	file    string
+	line    int
+	message string
+}
+
+func (e javaError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.file, e.line, e.message)
+}
+
+type javaParser struct {
+	file   string
+	tokens []javaToken
+	pos    int
+	pkg    string
+	types  []*Type
+}
+
+// javaModifiers are the modifiers of a declaration.
+type javaModifiers struct {
+	visibility Visibility
+	explicit   bool // the visibility was given
+	static     bool
+}
+
+func (p *javaParser) peek() javaToken {
+	return p.tokens[p.pos]
+}
+
+func (p *javaParser) peekAt(offset int) javaToken {
+	if p.pos+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *javaParser) next() javaToken {
+	t := p.tokens[p.pos]
+	if t.kind != javaEOF {
+		p.pos++
+	}
+	return t
+}
+
+// is reports whether the next token is text, which is a keyword or a symbol.
+func (p *javaParser) is(text string) bool {
+	t := p.peek()
+	return t.kind != javaLiteral && t.kind != javaEOF && t.text == text
+}
+
+// accept consumes the next token if it is text.
+func (p *javaParser) accept(text string) bool {
+	if p.is(text) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *javaParser) expect(text string) {
+	if !p.accept(text) {
+		p.fail("expected %q, found %s", text, p.describe())
+	}
+}
+
+func (p *javaParser) ident() javaToken {
+	t := p.peek()
+	if t.kind != javaIdent {
+		p.fail("expected a name, found %s", p.describe())
+	}
+	return p.next()
+}
+
+func (p *javaParser) describe() string {
+	if t := p.peek(); t.kind != javaEOF {
+		return fmt.Sprintf("%q", t.text)
+	}
+	return "end of file"
+}
+
+func (p *javaParser) fail(format string, args ...any) {
+	panic(javaError{p.file, p.peek().line, fmt.Sprintf(format, args...)})
+}
+
+// skipBalanced skips from an opening bracket to the matching closing bracket.
+func (p *javaParser) skipBalanced(open, closing string) {
+	p.expect(open)
+	for depth := 1; depth > 0; {
+		switch t := p.next(); {
+		case t.kind == javaEOF:
+			p.fail("expected %q, found end of file", closing)
+		case t.kind == javaSymbol && t.text == open:
+			depth++
+		case t.kind == javaSymbol && t.text == closing:
+			depth--
+		}
+	}
+}
+
+// skipUntil skips to the next of the given symbols that is not nested in brackets, without
+// consuming it.
+func (p *javaParser) skipUntil(symbols ...string) {
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == javaEOF:
+			p.fail("expected %q, found end of file", symbols[0])
+		case t.kind != javaSymbol:
+			p.next()
+			continue
+		}
+		for _, symbol := range symbols {
+			if t.text == symbol {
+				return
+			}
+		}
+		switch t.text {
+		case "(":
+			p.skipBalanced("(", ")")
+		case "{":
+			p.skipBalanced("{", "}")
+		case "[":
+			p.skipBalanced("[", "]")
+		case ")", "}", "]":
+			p.fail("expected %q, found %s", symbols[0], p.describe())
+		default:
+			p.next()
+		}
+	}
+}
+
+func (p *javaParser) qualifiedName() string {
+	name := p.ident().text
+	for p.is(".") && p.peekAt(1).kind == javaIdent {
+		p.next()
+		name += "." + p.next().text
+	}
+	return name
+}
+
+func (p *javaParser) compilationUnit() {
+	for p.peek().kind != javaEOF {
+		switch {
+		case p.accept(";"):
+		case p.is("@") && p.peekAt(1).text != "interface":
+			p.annotation()
+		case p.accept("package"):
+			p.pkg = p.qualifiedName()
+			p.expect(";")
+		case p.accept("import"):
+			p.skipUntil(";")
+			p.expect(";")
+		default:
+			p.modifiers()
+			if !p.typeDeclaration() {
+				p.fail("expected a type declaration, found %s", p.describe())
+			}
+		}
+	}
+}
+
+// annotation skips an annotation like @Override or @Table(name = "orders").
+func (p *javaParser) annotation() {
+	p.expect("@")
+	p.qualifiedName()
+	if p.is("(") {
+		p.skipBalanced("(", ")")
+	}
+}
+
+func (p *javaParser) modifiers() javaModifiers {
+	var m javaModifiers
+	for {
+		switch t := p.peek(); {
+		case p.is("@") && p.peekAt(1).text != "interface":
+			p.annotation()
+		case p.is("public"):
+			m.visibility, m.explicit = Public, true
+			p.next()
+		case p.is("protected"):
+			m.visibility, m.explicit = Protected, true
+			p.next()
+		case p.is("private"):
+			m.visibility, m.explicit = Private, true
+			p.next()
+		case p.is("static"):
+			m.static = true
+			p.next()
+		case p.is("non") && p.peekAt(1).text == "-" && p.peekAt(2).text == "sealed":
+			p.pos += 3
+		case t.kind == javaIdent && javaModifierKeywords[t.text]:
+			p.next()
+		default:
+			return m
+		}
+	}
+}
+
+var javaModifierKeywords = map[string]bool{
+	"abstract": true, "final": true, "sealed": true, "strictfp": true, "transient": true,
+	"volatile": true, "synchronized": true, "native": true, "default": true,
+}
+
+// typeDeclaration parses a class, interface, enum, record or annotation type if one follows
+// and reports whether it did.
+func (p *javaParser) typeDeclaration() bool {
+	var kind Kind
+	record := false
+	switch {
+	case p.is("@") && p.peekAt(1).text == "interface":
+		// Annotation types have no counterpart in the diagrams
+		p.pos += 2
+		p.ident()
+		p.skipBalanced("{", "}")
+		return true
+	case p.accept("class"):
+		kind = Class
+	case p.accept("interface"):
+		kind = Interface
+	case p.accept("enum"):
+		kind = Enum
+	case p.is("record") && p.peekAt(1).kind == javaIdent:
+		p.next()
+		kind, record = Class, true
+	default:
+		return false
+	}
+
+	name := p.ident()
+	t := &Type{Name: name.text, Package: p.pkg, Kind: kind, File: p.file, Line: name.line}
+	p.types = append(p.types, t)
+
+	if p.is("<") {
+		p.skipBalanced("<", ">")
+	}
+	if record {
+		for _, parameter := range p.parameters() {
+			t.Fields = append(t.Fields, Field{Visibility: Private, Name: parameter.Name, Type: parameter.Type})
+		}
+	}
+	for {
+		switch {
+		case p.accept("extends"):
+			t.Extends = append(t.Extends, p.typeNames()...)
+		case p.accept("implements"):
+			t.Implements = append(t.Implements, p.typeNames()...)
+		case p.accept("permits"):
+			p.typeNames()
+		default:
+			p.body(t)
+			return true
+		}
+	}
+}
+
+func (p *javaParser) typeNames() []string {
+	var names []string
+	for {
+		ref, _ := p.typeRef()
+		names = append(names, ref.Name)
+		if !p.accept(",") {
+			return names
+		}
+	}
+}
+
+// body parses the body of a type declaration.
+func (p *javaParser) body(t *Type) {
+	p.expect("{")
+	if t.Kind == Enum {
+		p.enumConstants(t)
+	}
+
+	for !p.accept("}") {
+		if p.peek().kind == javaEOF {
+			p.fail("expected \"}\", found end of file")
+		}
+		if p.accept(";") {
+			continue
+		}
+
+		modifiers := p.modifiers()
+		if !modifiers.explicit && t.Kind == Interface {
+			modifiers.visibility = Public
+		}
+		if p.is("{") {
+			// Initializer block
+			p.skipBalanced("{", "}")
+			continue
+		}
+		if p.typeDeclaration() {
+			continue
+		}
+		if p.is("<") {
+			p.skipBalanced("<", ">")
+		}
+
+		// Constructors, including the compact constructors of records
+		if p.is(t.Name) && (p.peekAt(1).text == "(" || p.peekAt(1).text == "{") {
+			p.next()
+			if p.is("(") {
+				p.skipBalanced("(", ")")
+			}
+			p.skipUntil("{", ";")
+			p.memberBody()
+			continue
+		}
+
+		ref, void := p.typeRef()
+		name := p.ident()
+		if p.is("(") {
+			method := Method{Visibility: modifiers.visibility, Name: name.text, Parameters: p.parameters(), Static: modifiers.static}
+			ref.Dimensions += p.dimensions()
+			if !void {
+				method.Return = &ref
+			}
+			p.skipUntil("{", ";")
+			method.body = p.memberBody()
+			t.Methods = append(t.Methods, method)
+			continue
+		}
+
+		// The fields of an interface are constants
+		static := modifiers.static || t.Kind == Interface
+		for {
+			field := Field{Visibility: modifiers.visibility, Name: name.text, Type: ref, Static: static}
+			field.Type.Dimensions += p.dimensions()
+			t.Fields = append(t.Fields, field)
+
+			// Commas of type arguments in the initializer are not followed by a declarator
+			p.skipUntil(",", ";")
+			for p.is(",") && !p.declaratorFollows() {
+				p.next()
+				p.skipUntil(",", ";")
+			}
+			if p.accept(";") {
+				break
+			}
+			p.expect(",")
+			name = p.ident()
+		}
+	}
+}
+
+// declaratorFollows reports whether the comma at the current position is followed by the
+// declaration of another variable.
+func (p *javaParser) declaratorFollows() bool {
+	if p.peekAt(1).kind != javaIdent {
+		return false
+	}
+	switch p.peekAt(2).text {
+	case "=", ",", ";", "[":
+		return true
+	}
+	return false
+}
+
+// memberBody skips the body of a method or constructor, or the semicolon of an abstract method,
+// and returns the tokens of the body including the braces.
+func (p *javaParser) memberBody() []javaToken {
+	if p.accept(";") {
+		return nil
+	}
+	start := p.pos
+	p.skipBalanced("{", "}")
+	return p.tokens[start:p.pos]
+}
+
+func (p *javaParser) enumConstants(t *Type) {
+	for {
+		for p.is("@") {
+			p.annotation()
+		}
+		if p.peek().kind != javaIdent {
+			break
+		}
+		t.Constants = append(t.Constants, p.next().text)
+		if p.is("(") {
+			p.skipBalanced("(", ")")
+		}
+		if p.is("{") {
+			p.skipBalanced("{", "}")
+		}
+		if !p.accept(",") {
+			break
+		}
+	}
+	if !p.is("}") {
+		p.expect(";")
+	}
+}
+
+// parameters parses a parameter list in parentheses.
+func (p *javaParser) parameters() []Parameter {
+	var parameters []Parameter
+	p.expect("(")
+	for !p.accept(")") {
+		if len(parameters) > 0 {
+			p.expect(",")
+		}
+		p.modifiers()
+		ref, _ := p.typeRef()
+		if p.accept("this") {
+			// Receiver parameter
+			continue
+		}
+		name := p.ident().text
+		ref.Dimensions += p.dimensions()
+		parameters = append(parameters, Parameter{Name: name, Type: ref})
+	}
+	return parameters
+}
+
+// typeRef parses a type and reports whether it is void. Qualifiers and annotations are dropped,
+// varargs are arrays and wildcards are replaced by their bound or Object.
+func (p *javaParser) typeRef() (ref TypeRef, void bool) {
+	for p.is("@") {
+		p.annotation()
+	}
+	if p.accept("void") {
+		return TypeRef{}, true
+	}
+
+	ref.Name = p.ident().text
+	ref.Arguments = p.typeArguments()
+	for p.is(".") && p.peekAt(1).kind == javaIdent {
+		p.next()
+		ref.Name = p.next().text
+		ref.Arguments = p.typeArguments()
+	}
+	ref.Dimensions = p.dimensions()
+	if p.accept("...") {
+		ref.Dimensions++
+	}
+	return ref, false
+}
+
+func (p *javaParser) typeArguments() []TypeRef {
+	if !p.accept("<") {
+		return nil
+	}
+	var arguments []TypeRef
+	for !p.accept(">") {
+		if len(arguments) > 0 {
+			p.expect(",")
+		}
+		for p.is("@") {
+			p.annotation()
+		}
+		if p.accept("?") {
+			if !p.accept("extends") && !p.accept("super") {
+				arguments = append(arguments, TypeRef{Name: "Object"})
+				continue
+			}
+		}
+		ref, _ := p.typeRef()
+		arguments = append(arguments, ref)
+	}
+	return arguments
+}
+
+func (p *javaParser) dimensions() int {
+	n := 0
+	for p.is("[") && p.peekAt(1).text == "]" {
+		p.pos += 2
+		n++
+	}
+	return n
+}
@@ -0,0 +1,60 @@
+package reverse
+
+import (
+	"errors"
+	"sort"
+)
+
+// Source is a source file to reverse engineer.
+type Source struct {
+	// Path names the file in warnings and errors.
+	Path    string
+	Content []byte
+}
+
+// Language is a source language that can be reverse engineered.
+type Language struct {
+	Name string
+	// Extension is the file extension of the sources, including the dot.
+	Extension string
+	// Parse returns the types declared in sources. The types of the files that could be parsed
+	// are returned even if other files have errors.
+	Parse func(sources []Source) ([]*Type, error)
+	// Sequences reports whether the front end keeps the method bodies, from which
+	// SequenceDiagram extracts the calls.
+	Sequences bool
+}
+
+var languages = map[string]*Language{
+	"go":   {Name: "go", Extension: ".go", Parse: parseGoSources},
+	"java": {Name: "java", Extension: ".java", Parse: parseJavaSources, Sequences: true},
+}
+
+// Lookup returns the language of the given name, nil if it is not supported.
+func Lookup(name string) *Language {
+	return languages[name]
+}
+
+// Languages returns the names of the supported languages in lexical order.
+func Languages() []string {
+	names := make([]string, 0, len(languages))
+	for name := range languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func parseJavaSources(sources []Source) ([]*Type, error) {
+	var types []*Type
+	var errs []error
+	for _, source := range sources {
+		parsed, err := ParseJava(source.Path, source.Content)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		types = append(types, parsed...)
+	}
+	return types, errors.Join(errs...)
+}
@@ -0,0 +1,191 @@
+package reverse
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Warning is a declaration that could not be expressed in the diagram.
+type Warning struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (w Warning) String() string {
+	if w.Line == 0 {
+		return w.File + ": " + w.Message
+	}
+	return fmt.Sprintf("%s:%d: %s", w.File, w.Line, w.Message)
+}
+
+// word matches the names the class diagram lexer accepts.
+var word = regexp.MustCompile(`^[a-zA-Z]\w*$`)
+
+// validName reports whether name can be used in a class diagram. The diagram keyword is a
+// token of its own.
+func validName(name string) bool {
+	return word.MatchString(name) && name != "classDiagram"
+}
+
+const indent = "    "
+
+// ClassDiagram prints types as a Mermaid class diagram in the layout of the fmt command.
+// Types of a package are declared in a namespace of the same name. Inheritance and the
+// associations given by fields are drawn between the types of the diagram only. Names the
+// diagram syntax cannot express and static members are left out; the names are reported.
+func ClassDiagram(types []*Type, report func(Warning)) string {
+	var lines []string
+	add := func(depth int, line string) {
+		lines = append(lines, strings.Repeat(indent, depth)+line)
+	}
+	add(0, "classDiagram")
+
+	// Types with invalid or repeated names cannot be declared
+	declared := make(map[string]bool)
+	var valid []*Type
+	for _, t := range types {
+		switch {
+		case !validName(t.Name):
+			report(Warning{t.File, t.Line, fmt.Sprintf("type %s is left out, its name is not supported by the diagram syntax", t.Name)})
+		case declared[t.Name]:
+			report(Warning{t.File, t.Line, fmt.Sprintf("type %s is left out, a type of the same name is already declared", t.Name)})
+		default:
+			declared[t.Name] = true
+			valid = append(valid, t)
+		}
+	}
+
+	// Types are grouped by package in the order the packages appear
+	var packages []string
+	byPackage := make(map[string][]*Type)
+	for _, t := range valid {
+		if _, exists := byPackage[t.Package]; !exists {
+			packages = append(packages, t.Package)
+		}
+		byPackage[t.Package] = append(byPackage[t.Package], t)
+	}
+
+	for _, pkg := range packages {
+		depth := 1
+		if pkg != "" {
+			add(1, "namespace "+pkg+" {")
+			depth = 2
+		}
+		for _, t := range byPackage[pkg] {
+			members := classMembers(t, report)
+			if len(members) == 0 {
+				add(depth, "class "+t.Name)
+				continue
+			}
+			add(depth, "class "+t.Name+" {")
+			for _, member := range members {
+				add(depth+1, member)
+			}
+			add(depth, "}")
+		}
+		if pkg != "" {
+			add(1, "}")
+		}
+	}
+
+	for _, t := range valid {
+		switch t.Kind {
+		case Interface:
+			add(1, "<<interface>> "+t.Name)
+		case Enum:
+			add(1, "<<enumeration>> "+t.Name)
+		}
+	}
+
+	for _, t := range valid {
+		for _, super := range t.Extends {
+			if declared[super] {
+				add(1, super+" <|-- "+t.Name)
+			}
+		}
+		for _, iface := range t.Implements {
+			if declared[iface] {
+				add(1, iface+" <|.. "+t.Name)
+			}
+		}
+		for _, field := range t.Fields {
+			element, cardinality := field.Type.element()
+			if !field.Static && declared[element.Name] && validName(field.Name) {
+				add(1, fmt.Sprintf(`%s "1" --> "%s" %s : %s`, t.Name, cardinality, element.Name, field.Name))
+			}
+		}
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// classMembers returns the member lines of a type.
+func classMembers(t *Type, report func(Warning)) []string {
+	var members []string
+	unsupported := func(kind, name string) {
+		report(Warning{t.File, t.Line, fmt.Sprintf("%s %s.%s is left out, its name is not supported by the diagram syntax", kind, t.Name, name)})
+	}
+
+	for _, constant := range t.Constants {
+		if !validName(constant) {
+			unsupported("constant", constant)
+			continue
+		}
+		members = append(members, constant)
+	}
+
+	for _, field := range t.Fields {
+		if field.Static {
+			continue
+		}
+		if !validName(field.Name) {
+			unsupported("field", field.Name)
+			continue
+		}
+		members = append(members, field.Visibility.marker()+mermaidType(field.Type)+" "+field.Name)
+	}
+
+methods:
+	for _, method := range t.Methods {
+		if method.Static {
+			continue
+		}
+		if !validName(method.Name) {
+			unsupported("method", method.Name)
+			continue
+		}
+		parameters := make([]string, 0, len(method.Parameters))
+		for _, parameter := range method.Parameters {
+			if !validName(parameter.Name) {
+				unsupported("method", method.Name)
+				continue methods
+			}
+			parameters = append(parameters, mermaidType(parameter.Type)+" "+parameter.Name)
+		}
+		result := "void"
+		if method.Return != nil {
+			result = mermaidType(*method.Return)
+		}
+		members = append(members, method.Visibility.marker()+method.Name+"("+strings.Join(parameters, ", ")+") "+result)
+	}
+	return members
+}
+
+// mermaidType writes a type reference with the tilde syntax for generic types. Arrays are
+// written as lists, List~int~ for int[].
+func mermaidType(t TypeRef) string {
+	text := t.Name
+	if len(t.Arguments) > 0 {
+		arguments := make([]string, len(t.Arguments))
+		for i, argument := range t.Arguments {
+			arguments[i] = mermaidType(argument)
+		}
+		text += "~" + strings.Join(arguments, ",") + "~"
+	}
+	for i := 0; i < t.Dimensions; i++ {
+		text = "List~" + text + "~"
+	}
+	return text
+}
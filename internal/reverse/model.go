@@ -0,0 +1,128 @@
+// Package reverse turns source code back into Mermaid class and sequence diagrams.
+//
+// The language front ends parse source files into the language-neutral Type model, which
+// ClassDiagram prints in the syntax the reader package accepts, so that the diagrams can be
+// converted into code again. SequenceDiagram follows the calls of a method through the method
+// bodies the Java front end keeps. The diagram syntax is a subset of Mermaid: it has no markers for
+// static or abstract members and no array types, so static members are left out and arrays are
+// written as lists.
+package reverse
+
+// Kind is the kind of a type.
+type Kind int
+
+const (
+	Class Kind = iota
+	Interface
+	Enum
+)
+
+// Visibility is the access level of a type member.
+type Visibility int
+
+const (
+	Package Visibility = iota
+	Public
+	Protected
+	Private
+)
+
+// marker returns the Mermaid visibility marker.
+func (v Visibility) marker() string {
+	switch v {
+	case Public:
+		return "+"
+	case Protected:
+		return "#"
+	case Private:
+		return "-"
+	}
+	return "~"
+}
+
+// Type is a class, interface or enum found in the sources.
+type Type struct {
+	Name string
+	// Package is the dotted package name, empty for the default package.
+	Package string
+	Kind    Kind
+	// Extends are the names of the super types, Implements the names of the implemented interfaces.
+	Extends    []string
+	Implements []string
+	Fields     []Field
+	Methods    []Method
+	// Constants are the constants of an enum.
+	Constants []string
+	// File and Line locate the declaration.
+	File string
+	Line int
+}
+
+// Field is an attribute of a type.
+type Field struct {
+	Visibility Visibility
+	Name       string
+	Type       TypeRef
+	Static     bool
+}
+
+// Method is an operation of a type.
+type Method struct {
+	Visibility Visibility
+	Name       string
+	Parameters []Parameter
+	// Return is the result type, nil for methods without result.
+	Return *TypeRef
+	Static bool
+
+	// body are the Java tokens of the method body, nil for abstract methods
+	body []javaToken
+}
+
+// Parameter is a method parameter.
+type Parameter struct {
+	Name string
+	Type TypeRef
+}
+
+// TypeRef is a reference to a type in a declaration.
+type TypeRef struct {
+	// Name is the simple name of the type, qualifiers are removed.
+	Name string
+	// Arguments are the type arguments of a generic type.
+	Arguments []TypeRef
+	// Dimensions is the number of array dimensions.
+	Dimensions int
+}
+
+// collectionTypes are the types that hold many elements, the element type is their last type
+// argument.
+var collectionTypes = map[string]bool{
+	"List": true, "ArrayList": true, "LinkedList": true,
+	"Set": true, "HashSet": true, "LinkedHashSet": true, "TreeSet": true, "SortedSet": true,
+	"Collection": true, "Iterable": true, "Queue": true, "Deque": true, "ArrayDeque": true,
+	"Map": true, "HashMap": true, "LinkedHashMap": true, "TreeMap": true, "SortedMap": true,
+	"Stream": true,
+}
+
+// optionalTypes are the types that hold at most one element.
+var optionalTypes = map[string]bool{
+	"Optional": true,
+}
+
+// element returns the type of the elements of a collection, array or optional type and the
+// cardinality of the reference, "1", "0..1" or "*".
+func (t TypeRef) element() (TypeRef, string) {
+	switch {
+	case t.Dimensions > 0:
+		element, _ := TypeRef{Name: t.Name, Arguments: t.Arguments}.element()
+		return element, "*"
+	case len(t.Arguments) > 0 && collectionTypes[t.Name]:
+		element, _ := t.Arguments[len(t.Arguments)-1].element()
+		return element, "*"
+	case len(t.Arguments) > 0 && optionalTypes[t.Name]:
+		element, _ := t.Arguments[0].element()
+		return element, "0..1"
+	}
+	return t, "1"
+}
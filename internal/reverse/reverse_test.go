@@ -0,0 +1,637 @@
+package reverse
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/config"
+	"github.com/MarmaidTranspiler/Merfolk/internal/connector"
+	"github.com/MarmaidTranspiler/Merfolk/internal/format"
+	"github.com/MarmaidTranspiler/Merfolk/internal/project"
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+)
+
+const orderJava = `package com.shop.orders;
+
+import java.util.*;
+import java.util.function.Function;
+
+/**
+ * An order of a customer.
+ */
+@Entity
+@Table(name = "orders")
+public class Order extends Entity implements Comparable<Order>, Auditable {
+    public static final int MAX_ITEMS = 100;
+
+    private long id;
+    protected Customer customer;
+    private final List<Item> items = new ArrayList<>();
+    private Map<String, List<Item>> byCategory = new HashMap<String, List<Item>>(), cache;
+    Optional<Address> shipping;
+    private int[] codes = {1, 2};
+    private String note = "a } in a string";
+
+    static {
+        System.out.println("loaded");
+    }
+
+    public Order(long id) {
+        this.id = id;
+    }
+
+    @Override
+    public int compareTo(Order other) {
+        return Long.compare(id, other.id);
+    }
+
+    public <T extends Comparable<T>> T max(Function<? super Item, T> key, T... defaults) {
+        return null;
+    }
+
+    public void add(final Item item) throws IllegalStateException {
+        if (items.size() >= MAX_ITEMS) { throw new IllegalStateException(); }
+        items.add(item);
+    }
+
+    static Order empty() {
+        return new Order(0);
+    }
+
+    public enum Status {
+        NEW("n"), PAID("p") {
+            @Override public String toString() { return "paid"; }
+        },
+        SHIPPED;
+
+        private final String code;
+
+        Status(String code) { this.code = code; }
+        Status() { this("s"); }
+    }
+}
+`
+
+const customerJava = `package com.shop.orders;
+
+public interface Auditable {
+    String AUDIT = "audit";
+
+    String createdBy();
+
+    default boolean audited() { return true; }
+}
+
+record Address(String street, @NonNull String city) {
+    Address {
+        java.util.Objects.requireNonNull(street);
+    }
+}
+
+abstract sealed class Entity permits Order {
+}
+
+class Customer {
+    private java.util.Set<Order> orders;
+    private Address address;
+}
+
+class Item {
+    String name;
+    double price;
+}
+`
+
+func parse(t *testing.T) []*Type {
+	t.Helper()
+	types, err := parseJavaSources([]Source{{"Order.java", []byte(orderJava)}, {"Customer.java", []byte(customerJava)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return types
+}
+
+func TestParseJava(t *testing.T) {
+	types := parse(t)
+
+	var names []string
+	byName := make(map[string]*Type)
+	for _, typ := range types {
+		names = append(names, typ.Name)
+		byName[typ.Name] = typ
+	}
+	if got, want := strings.Join(names, " "), "Order Status Auditable Address Entity Customer Item"; got != want {
+		t.Fatalf("types: got %s, want %s", got, want)
+	}
+
+	order := byName["Order"]
+	if order.Package != "com.shop.orders" || order.Line != 11 || order.File != "Order.java" {
+		t.Errorf("Order: package %q at %s:%d", order.Package, order.File, order.Line)
+	}
+	if strings.Join(order.Extends, ",") != "Entity" || strings.Join(order.Implements, ",") != "Comparable,Auditable" {
+		t.Errorf("Order: extends %v, implements %v", order.Extends, order.Implements)
+	}
+
+	var fields []string
+	for _, field := range order.Fields {
+		fields = append(fields, field.Visibility.marker()+mermaidType(field.Type)+" "+field.Name)
+	}
+	want := "+int MAX_ITEMS,-long id,#Customer customer,-List~Item~ items,-Map~String,List~Item~~ byCategory," +
+		"-Map~String,List~Item~~ cache,~Optional~Address~ shipping,-List~int~ codes,-String note"
+	if got := strings.Join(fields, ","); got != want {
+		t.Errorf("Order fields:\ngot  %s\nwant %s", got, want)
+	}
+	if !order.Fields[0].Static {
+		t.Error("MAX_ITEMS is not static")
+	}
+
+	var methods []string
+	for _, method := range order.Methods {
+		methods = append(methods, method.Name)
+	}
+	if got := strings.Join(methods, ","); got != "compareTo,max,add,empty" {
+		t.Errorf("Order methods: %s", got)
+	}
+	if max := order.Methods[1]; mermaidType(*max.Return) != "T" || mermaidType(max.Parameters[0].Type) != "Function~Item,T~" || mermaidType(max.Parameters[1].Type) != "List~T~" {
+		t.Errorf("max: %+v", max)
+	}
+	if add := order.Methods[2]; add.Return != nil || len(add.Parameters) != 1 || add.Parameters[0].Name != "item" {
+		t.Errorf("add: %+v", add)
+	}
+
+	status := byName["Status"]
+	if status.Kind != Enum || strings.Join(status.Constants, ",") != "NEW,PAID,SHIPPED" || len(status.Fields) != 1 {
+		t.Errorf("Status: %+v", status)
+	}
+
+	auditable := byName["Auditable"]
+	if auditable.Kind != Interface || len(auditable.Methods) != 2 || auditable.Methods[0].Visibility != Public || !auditable.Fields[0].Static {
+		t.Errorf("Auditable: %+v", auditable)
+	}
+
+	address := byName["Address"]
+	if len(address.Fields) != 2 || address.Fields[1].Name != "city" || address.Fields[1].Visibility != Private {
+		t.Errorf("Address: %+v", address)
+	}
+}
+
+func TestParseJavaErrors(t *testing.T) {
+	for src, want := range map[string]string{
+		"class Order {\n  int id\n}":             `Order.java:3: expected ",", found "}"`,
+		"class Order {\n  /* open":               "Order.java:2: comment is not closed",
+		"class Order {\n  String s = \"open;\n}": "Order.java:2: literal is not closed",
+		"class Order {\n  void f() {\n":          `Order.java:3: expected "}", found end of file`,
+		"package shop;\nint x;":                  `Order.java:2: expected a type declaration, found "int"`,
+	} {
+		if _, err := ParseJava("Order.java", []byte(src)); err == nil || err.Error() != want {
+			t.Errorf("%q: expected %s, got %v", src, want, err)
+		}
+	}
+}
+
+func TestClassDiagram(t *testing.T) {
+	var warnings []string
+	diagram := ClassDiagram(parse(t), func(w Warning) { warnings = append(warnings, w.String()) })
+
+	for _, want := range []string{
+		"    namespace com.shop.orders {\n        class Order {\n            -long id\n",
+		"            +compareTo(Order other) int\n            +max(Function~Item,T~ key, List~T~ defaults) T\n            +add(Item item) void\n        }\n",
+		"        class Status {\n            NEW\n            PAID\n            SHIPPED\n            -String code\n        }\n",
+		"        class Entity\n",
+		"    <<enumeration>> Status\n    <<interface>> Auditable\n",
+		"    Entity <|-- Order\n    Auditable <|.. Order\n",
+		"    Order \"1\" --> \"1\" Customer : customer\n    Order \"1\" --> \"*\" Item : items\n",
+		"    Order \"1\" --> \"*\" Item : byCategory\n",
+		"    Order \"1\" --> \"0..1\" Address : shipping\n",
+		"    Customer \"1\" --> \"*\" Order : orders\n",
+	} {
+		if !strings.Contains(diagram, want) {
+			t.Errorf("expected %q in\n%s", want, diagram)
+		}
+	}
+	for _, unwanted := range []string{"MAX_ITEMS", "empty()", "AUDIT", "Comparable"} {
+		if strings.Contains(diagram, unwanted) {
+			t.Errorf("unexpected %q in\n%s", unwanted, diagram)
+		}
+	}
+	if len(warnings) > 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+
+	// The diagram is formatted and can be converted again
+	formatted, err := format.Diagram(diagram, format.Options{})
+	if err != nil {
+		t.Fatalf("diagram does not parse: %v\n%s", err, diagram)
+	}
+	if formatted != diagram {
+		t.Errorf("diagram is not formatted:\n%s\nformatted:\n%s", diagram, formatted)
+	}
+
+	parsed, err := reader.ParseDiagram(diagram)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := connector.Log
+	connector.Log = io.Discard
+	defer func() { connector.Log = log }()
+	p := project.Build(config.Default(), []project.Document{{Path: "model.md", Diagrams: []reader.Diagram{*parsed}}}, func(d project.Diagnostic) {
+		t.Errorf("unexpected diagnostic: %s", d)
+	})
+	if class := p.Model.Classes["Order"]; class == nil || class.Package != "com.shop.orders" || len(class.Methods) != 3 {
+		t.Errorf("Order not converted: %+v", class)
+	}
+}
+
+func TestClassDiagramWarnings(t *testing.T) {
+	types := []*Type{
+		{Name: "Order", File: "Order.java", Line: 3, Fields: []Field{{Name: "_id", Type: TypeRef{Name: "int"}}}},
+		{Name: "Order", File: "Other.java", Line: 1},
+		{Name: "$Proxy", File: "Proxy.java", Line: 2},
+	}
+	var warnings []string
+	diagram := ClassDiagram(types, func(w Warning) { warnings = append(warnings, w.String()) })
+
+	want := []string{
+		"Other.java:1: type Order is left out, a type of the same name is already declared",
+		"Proxy.java:2: type $Proxy is left out, its name is not supported by the diagram syntax",
+		"Order.java:3: field Order._id is left out, its name is not supported by the diagram syntax",
+	}
+	if strings.Join(warnings, "\n") != strings.Join(want, "\n") {
+		t.Errorf("warnings:\n%s", strings.Join(warnings, "\n"))
+	}
+	if diagram != "classDiagram\n    class Order\n" {
+		t.Errorf("diagram:\n%s", diagram)
+	}
+}
+
+const shopGo = `package shop
+
+import (
+	"io"
+	"time"
+)
+
+// Status is the state of an order.
+type Status int
+
+const (
+	StatusNew Status = iota
+	StatusPaid
+	_
+	StatusShipped
+)
+
+const Limit = 10
+
+type Entity struct {
+	ID      int64
+	Created time.Time
+}
+
+type Order struct {
+	Entity
+	*Audit
+	Customer *Customer
+	items    []Item
+	byName   map[string]*Item
+	Shipping *Address
+	status   Status
+	done     chan struct{}
+	_        int
+}
+
+func (o *Order) Total() (float64, error) { return 0, nil }
+
+func (o Order) Split(n int, _ bool, parts ...Item) ([]Order, int, error) { return nil, 0, nil }
+
+func (o *Order) Write(w io.Writer) error { return nil }
+
+func (o *Order) String() string { return "" }
+
+func (o *Order) cancel() {}
+
+type Totaler interface {
+	Total() (float64, error)
+}
+
+type Named interface {
+	fmt.Stringer
+	Name() string
+}
+`
+
+const modelGo = `package shop
+
+type Customer struct {
+	Orders []*Order
+}
+
+type Item struct{ Name string; Price float64 }
+
+type Audit struct{}
+
+type Address struct{}
+
+type List[T any] struct {
+	items []T
+}
+
+func (l *List[T]) Add(item T) {}
+
+type Number interface {
+	~int | ~float64
+}
+
+func helper() {}
+`
+
+func TestParseGo(t *testing.T) {
+	types, err := parseGoSources([]Source{
+		{"shop/shop.go", []byte(shopGo)},
+		{"shop/model.go", []byte(modelGo)},
+		{"shop/shop_test.go", []byte("package shop\n\ntype fixture struct{}\n")},
+		{"shop/testdata/data.go", []byte("package data\n\ntype Data struct{}\n")},
+		{"broken/broken.go", []byte("package broken\n\ntype Broken struct {\n")},
+	})
+	if err == nil || !strings.Contains(err.Error(), "broken/broken.go:3") {
+		t.Errorf("expected an error in broken.go, got %v", err)
+	}
+
+	var names []string
+	byName := make(map[string]*Type)
+	for _, typ := range types {
+		names = append(names, typ.Name)
+		byName[typ.Name] = typ
+	}
+	if got, want := strings.Join(names, " "), "Status Entity Order Totaler Named Customer Item Audit Address List Number"; got != want {
+		t.Fatalf("types: got %s, want %s", got, want)
+	}
+
+	status := byName["Status"]
+	if status.Kind != Enum || strings.Join(status.Constants, ",") != "StatusNew,StatusPaid,StatusShipped" || status.Package != "shop" || status.Line != 9 {
+		t.Errorf("Status: %+v", status)
+	}
+
+	order := byName["Order"]
+	if strings.Join(order.Extends, ",") != "Entity,Audit" || strings.Join(order.Implements, ",") != "Totaler" {
+		t.Errorf("Order: extends %v, implements %v", order.Extends, order.Implements)
+	}
+	var fields []string
+	for _, field := range order.Fields {
+		fields = append(fields, field.Visibility.marker()+mermaidType(field.Type)+" "+field.Name)
+	}
+	if got, want := strings.Join(fields, ","), "+Customer Customer,~List~Item~ items,~Map~string,Item~ byName,+Address Shipping,~Status status,~Chan~Struct~ done"; got != want {
+		t.Errorf("Order fields:\ngot  %s\nwant %s", got, want)
+	}
+
+	var methods []string
+	for _, method := range order.Methods {
+		parameters := make([]string, len(method.Parameters))
+		for i, parameter := range method.Parameters {
+			parameters[i] = mermaidType(parameter.Type) + " " + parameter.Name
+		}
+		result := "void"
+		if method.Return != nil {
+			result = mermaidType(*method.Return)
+		}
+		methods = append(methods, method.Visibility.marker()+method.Name+"("+strings.Join(parameters, ", ")+") "+result)
+	}
+	want := "+Total() float64,+Split(int n, bool arg2, List~Item~ parts) Tuple~List~Order~,int~,+Write(Writer w) void,+String() string,~cancel() void"
+	if got := strings.Join(methods, ","); got != want {
+		t.Errorf("Order methods:\ngot  %s\nwant %s", got, want)
+	}
+
+	named := byName["Named"]
+	if named.Kind != Interface || strings.Join(named.Extends, ",") != "Stringer" || len(named.Methods) != 1 {
+		t.Errorf("Named: %+v", named)
+	}
+	if list := byName["List"]; len(list.Methods) != 1 || len(list.Implements) != 0 {
+		t.Errorf("List: %+v", list)
+	}
+
+	// The diagram parses and is formatted
+	diagram := ClassDiagram(types, func(w Warning) { t.Errorf("unexpected warning: %s", w) })
+	for _, want := range []string{
+		"    namespace shop {\n        class Status {\n            StatusNew\n",
+		"    <<enumeration>> Status\n    <<interface>> Totaler\n    <<interface>> Named\n    <<interface>> Number\n",
+		"    Entity <|-- Order\n    Audit <|-- Order\n    Totaler <|.. Order\n",
+		"    Order \"1\" --> \"*\" Item : byName\n",
+		"    Customer \"1\" --> \"*\" Order : Orders\n",
+	} {
+		if !strings.Contains(diagram, want) {
+			t.Errorf("expected %q in\n%s", want, diagram)
+		}
+	}
+	if formatted, err := format.Diagram(diagram, format.Options{}); err != nil || formatted != diagram {
+		t.Errorf("diagram is not formatted (%v):\n%s", err, diagram)
+	}
+}
+
+const applicationJava = `package app;
+
+import java.util.List;
+
+public class Application {
+    private AuthService authService = new AuthService();
+    private DataService dataService;
+
+    public Data login(String user, String password) {
+        String sessionToken = authService.authenticate(user, password);
+        Data userData = dataService.fetch(sessionToken);
+        for (String line : userData.lines()) {
+            System.out.println(line);
+            dataService.log(line);
+        }
+        return userData;
+    }
+
+    public void retry(int times) {
+        try {
+            while (times-- > 0 && !authService.verify("token")) {
+                login("user", "secret");
+            }
+        } catch (IllegalStateException | IllegalArgumentException e) {
+            dataService.log(e.getMessage());
+        }
+        authService.tokens().forEach(token -> dataService.log(token));
+    }
+}
+
+class AuthService {
+    public String authenticate(String user, String password) {
+        String token = user + password;
+        return token;
+    }
+
+    public boolean verify(String token) {
+        return token != null;
+    }
+
+    public List<String> tokens() {
+        return List.of();
+    }
+}
+
+class DataService {
+    private AuthService authService;
+    private Data data;
+
+    public Data fetch(String sessionToken) {
+        boolean valid = authService.verify(sessionToken);
+        Data userData = new Data();
+        if (valid && sessionToken.length() > 8) {
+            userData.setContent("content");
+        } else {
+            userData.setContent(null);
+        }
+        return userData;
+    }
+
+    public void log(String message) {
+    }
+}
+
+class Data {
+    private String content;
+
+    public void setContent(String content) {
+        this.content = content;
+    }
+
+    public List<String> lines() {
+        return List.of(content.split("\n"));
+    }
+}
+`
+
+func TestSequenceDiagram(t *testing.T) {
+	types, err := ParseJava("Application.java", []byte(applicationJava))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := func(w Warning) { t.Errorf("unexpected warning: %s", w) }
+
+	diagram, err := SequenceDiagram(types, "Application.login", SequenceOptions{}, report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `sequenceDiagram
+    actor user
+    user->>Application: login(user, password)
+    Application->>AuthService: authenticate(user, password)
+    AuthService-->>Application: sessionToken
+    Application->>DataService: fetch(sessionToken)
+    DataService->>AuthService: verify(sessionToken)
+    AuthService-->>DataService: valid
+    create participant Data
+    DataService->>Data: Data()
+    Data-->>DataService: userData
+    alt valid and sessionToken length greater than
+        DataService->>Data: setContent("content")
+    else
+        DataService->>Data: setContent(null)
+    end
+    DataService-->>Application: userData
+    Application->>Data: lines()
+    Data-->>Application: linesResult
+    loop each line in userData lines
+        Application->>DataService: log(line)
+    end
+    Application-->>user: userData
+`
+	if diagram != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, diagram)
+	}
+
+	// Recursion is cut by the depth, lambdas are not followed
+	retry, err := SequenceDiagram(types, "Application.retry", SequenceOptions{Actor: "scheduler", MaxDepth: 1}, report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"    scheduler->>Application: retry(times)\n    Application->>AuthService: verify(\"token\")\n",
+		"    loop times greater than and not authService verify\n        Application->>Application: login(\"user\", \"secret\")\n        Application-->>Application: loginResult\n    end\n",
+		"    alt catch IllegalStateException IllegalArgumentException\n        Application->>DataService: log(\"e.getMessage()\")\n    end\n",
+		"    Application->>AuthService: tokens()\n    AuthService-->>Application: tokensResult\n",
+	} {
+		if !strings.Contains(retry, want) {
+			t.Errorf("expected %q in\n%s", want, retry)
+		}
+	}
+	if strings.Contains(retry, "log(token)") {
+		t.Errorf("unexpected call in lambda in\n%s", retry)
+	}
+
+	formatted, err := format.Diagram(diagram, format.Options{})
+	if err != nil {
+		t.Fatalf("diagram does not parse: %v\n%s", err, diagram)
+	}
+	if formatted != diagram {
+		t.Errorf("diagram is not formatted:\n%s\nformatted:\n%s", diagram, formatted)
+	}
+
+	// The class and sequence diagrams are converted into code again
+	var diagrams []reader.Diagram
+	for _, text := range []string{ClassDiagram(types, report), diagram} {
+		parsed, err := reader.ParseDiagram(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		diagrams = append(diagrams, *parsed)
+	}
+	log := connector.Log
+	connector.Log = io.Discard
+	defer func() { connector.Log = log }()
+	p := project.Build(config.Default(), []project.Document{{Path: "model.md", Diagrams: diagrams}}, func(d project.Diagnostic) {
+		t.Errorf("unexpected diagnostic: %s", d)
+	})
+	for _, method := range p.Model.Classes["Application"].Methods {
+		if method.Name == "login" && (len(method.MethodBody) == 0 || method.ReturnValue != "userData") {
+			t.Errorf("login not converted: %+v", method)
+		}
+	}
+}
+
+func TestSequenceDiagramErrors(t *testing.T) {
+	types, err := ParseJava("Application.java", []byte(applicationJava))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for entry, message := range map[string]string{
+		"login":              `invalid entry method "login", expected Class.method`,
+		"Application.":       `invalid entry method "Application.", expected Class.method`,
+		"Session.login":      "class Session not found",
+		"Application.logout": "method Application.logout not found",
+	} {
+		_, err := SequenceDiagram(types, entry, SequenceOptions{}, func(Warning) {})
+		if err == nil || err.Error() != message {
+			t.Errorf("%s: expected error %q, got %v", entry, message, err)
+		}
+	}
+
+	// The calls of bodies that cannot be walked are left out
+	broken, err := ParseJava("Broken.java", []byte(`class Broken {
+    AuthService auth;
+    void run() {
+        auth.verify("x") auth.tokens();
+    }
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var warnings []string
+	diagram, err := SequenceDiagram(append(broken, types...), "Broken.run", SequenceOptions{}, func(w Warning) {
+		warnings = append(warnings, w.String())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diagram != "sequenceDiagram\n    actor user\n    user->>Broken: run()\n" {
+		t.Errorf("unexpected diagram\n%s", diagram)
+	}
+	expected := []string{`Broken.java:4: calls of Broken.run are left out: expected ";", found "auth"`}
+	if strings.Join(warnings, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected warnings %q, got %q", expected, warnings)
+	}
+}
@@ -0,0 +1,963 @@
+package reverse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SequenceOptions control the extraction of sequence diagrams.
+type SequenceOptions struct {
+	// Actor is the participant that calls the entry method, "user" if empty.
+	Actor string
+	// MaxDepth limits how deep calls are followed into the called methods, 10 if zero.
+	MaxDepth int
+}
+
+// SequenceDiagram walks the body of the entry method, given as "Class.method", and prints the
+// calls to the types of the diagram as a Mermaid sequence diagram in the layout of the fmt
+// command. Calls are followed into the bodies of the called methods, recursive calls are not.
+//
+// Calls of methods with a result are answered by a return message, which is named after the
+// variable the result is assigned to, the variable the called method returns or, failing
+// that, <method>Result. Objects created with new are answered by a return message, like
+// constructors in the diagrams convert reads. If-statements and switches become alt blocks,
+// loops loop blocks and catch clauses alt blocks of their own. The labels of the blocks are the
+// words of the conditions; calls in lambda bodies are not followed.
+//
+// Bodies that cannot be parsed are reported and their calls are left out.
+func SequenceDiagram(types []*Type, entry string, options SequenceOptions, report func(Warning)) (string, error) {
+	if options.Actor == "" {
+		options.Actor = "user"
+	}
+	if options.MaxDepth == 0 {
+		options.MaxDepth = 10
+	}
+
+	dot := strings.LastIndex(entry, ".")
+	if dot <= 0 || dot == len(entry)-1 {
+		return "", fmt.Errorf("invalid entry method %q, expected Class.method", entry)
+	}
+	s := &sequencer{types: make(map[string]*Type), options: options, report: report, appeared: make(map[string]bool)}
+	for _, t := range types {
+		if _, exists := s.types[t.Name]; !exists {
+			s.types[t.Name] = t
+		}
+	}
+
+	t := s.types[entry[:dot]]
+	if t == nil {
+		return "", fmt.Errorf("class %s not found", entry[:dot])
+	}
+	owner, method := s.method(t, entry[dot+1:], -1)
+	if method == nil {
+		return "", fmt.Errorf("method %s not found", entry)
+	}
+
+	actor := sequenceName(options.Actor)
+	s.appeared[actor] = true
+	items := []sequenceItem{{line: "actor " + actor}}
+
+	parameters := make([]string, len(method.Parameters))
+	for i, parameter := range method.Parameters {
+		parameters[i] = argument(parameter.Name)
+	}
+	items = append(items, s.call(actor, owner.Name, method.Name, parameters))
+
+	body, returned := s.walk(owner, method, 1)
+	items = append(items, body...)
+	if method.Return != nil {
+		items = append(items, s.answer(owner.Name, actor, resultName(method.Name, returned)))
+	}
+
+	var lines []string
+	printSequence(&lines, items, 1)
+	return "sequenceDiagram\n" + strings.Join(lines, "\n") + "\n", nil
+}
+
+// sequenceItem is a line of a sequence diagram or a block with its branches.
+type sequenceItem struct {
+	line string
+	// block is the header of an alt or loop block, the alt blocks have a branch per else.
+	block    string
+	branches [][]sequenceItem
+}
+
+func printSequence(lines *[]string, items []sequenceItem, depth int) {
+	prefix := strings.Repeat(indent, depth)
+	for _, item := range items {
+		if item.block == "" {
+			*lines = append(*lines, prefix+item.line)
+			continue
+		}
+		*lines = append(*lines, prefix+item.block)
+		for i, branch := range item.branches {
+			if i > 0 {
+				*lines = append(*lines, prefix+"else")
+			}
+			printSequence(lines, branch, depth+1)
+		}
+		*lines = append(*lines, prefix+"end")
+	}
+}
+
+type sequencer struct {
+	types    map[string]*Type
+	options  SequenceOptions
+	report   func(Warning)
+	appeared map[string]bool
+	// active are the methods that are walked, they are not entered again
+	active []*Method
+}
+
+// method returns the method of t or of its super types with the given name, preferring the one
+// with the given number of parameters, and the type that declares it.
+func (s *sequencer) method(t *Type, name string, parameters int) (*Type, *Method) {
+	seen := make(map[*Type]bool)
+	var found *Method
+	var owner *Type
+	var search func(t *Type) bool
+	search = func(t *Type) bool {
+		if t == nil || seen[t] {
+			return false
+		}
+		seen[t] = true
+		for i := range t.Methods {
+			m := &t.Methods[i]
+			if m.Name != name {
+				continue
+			}
+			if found == nil {
+				owner, found = t, m
+			}
+			if parameters < 0 || len(m.Parameters) == parameters {
+				owner, found = t, m
+				return true
+			}
+		}
+		for _, super := range append(append([]string(nil), t.Extends...), t.Implements...) {
+			if search(s.types[super]) {
+				return true
+			}
+		}
+		return false
+	}
+	search(t)
+	return owner, found
+}
+
+// field returns the type of a field of t or of its super types.
+func (s *sequencer) field(t *Type, name string) (TypeRef, bool) {
+	for seen := make(map[*Type]bool); t != nil && !seen[t]; {
+		seen[t] = true
+		for _, field := range t.Fields {
+			if field.Name == name {
+				return field.Type, true
+			}
+		}
+		if len(t.Extends) == 0 {
+			break
+		}
+		t = s.types[t.Extends[0]]
+	}
+	return TypeRef{}, false
+}
+
+func (s *sequencer) call(from, to, name string, arguments []string) sequenceItem {
+	s.appeared[from], s.appeared[to] = true, true
+	text := sequenceName(from) + "->>" + sequenceName(to) + ": " + sequenceName(name) + "("
+	return sequenceItem{line: text + strings.Join(arguments, ", ") + ")"}
+}
+
+func (s *sequencer) answer(from, to, name string) sequenceItem {
+	return sequenceItem{line: sequenceName(from) + "-->>" + sequenceName(to) + ": " + sequenceName(name)}
+}
+
+// walk returns the items of the body of a method and the variable it returns.
+func (s *sequencer) walk(t *Type, m *Method, depth int) (items []sequenceItem, returned string) {
+	if m.body == nil || depth > s.options.MaxDepth {
+		return nil, ""
+	}
+	for _, active := range s.active {
+		if active == m {
+			return nil, ""
+		}
+	}
+	s.active = append(s.active, m)
+	defer func() { s.active = s.active[:len(s.active)-1] }()
+
+	tokens := append(append([]javaToken(nil), m.body...), javaToken{kind: javaEOF, line: m.body[len(m.body)-1].line})
+	w := &walker{
+		javaParser: javaParser{file: t.File, tokens: tokens},
+		s:          s,
+		t:          t,
+		depth:      depth,
+		locals:     make(map[string]TypeRef),
+	}
+	for _, parameter := range m.Parameters {
+		w.locals[parameter.Name] = parameter.Type
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(javaError)
+			if !ok {
+				panic(r)
+			}
+			s.report(Warning{e.file, e.line, fmt.Sprintf("calls of %s.%s are left out: %s", t.Name, m.Name, e.message)})
+			items, returned = nil, ""
+		}
+	}()
+	w.block()
+	return w.items, w.returned
+}
+
+// walker walks a method body and collects the calls.
+type walker struct {
+	javaParser
+	s     *sequencer
+	t     *Type
+	depth int
+	// locals are the types of the parameters and local variables
+	locals map[string]TypeRef
+	items  []sequenceItem
+	// returned is the variable returned last
+	returned string
+	// target is the variable the expression starting at targetPos is assigned to
+	target    string
+	targetPos int
+	// muted is set in lambda bodies, whose calls are not recorded
+	muted int
+}
+
+// value is the result of an expression.
+type value struct {
+	// text is the expression as argument of a message
+	text string
+	// typ is the type of the expression, empty if unknown
+	typ string
+	// name is set if the expression is a variable or the result of a call
+	name bool
+}
+
+func (w *walker) add(item sequenceItem) {
+	if w.muted == 0 {
+		w.items = append(w.items, item)
+	}
+}
+
+// branch returns the items collected by parse.
+func (w *walker) branch(parse func()) []sequenceItem {
+	outer := w.items
+	w.items = nil
+	parse()
+	items := w.items
+	w.items = outer
+	return items
+}
+
+// try runs parse and resets the position if parse fails.
+func (w *walker) try(parse func()) (ok bool) {
+	pos := w.pos
+	defer func() {
+		if r := recover(); r != nil {
+			if _, isError := r.(javaError); !isError {
+				panic(r)
+			}
+			w.pos, ok = pos, false
+		}
+	}()
+	parse()
+	return true
+}
+
+func (w *walker) block() {
+	w.expect("{")
+	for !w.accept("}") {
+		w.statement()
+	}
+}
+
+func (w *walker) statement() {
+	switch {
+	case w.is("{"):
+		w.block()
+	case w.accept(";"):
+	case w.accept("if"):
+		label := w.condition()
+		then := w.branch(w.statement)
+		var otherwise []sequenceItem
+		if w.accept("else") {
+			otherwise = w.branch(w.statement)
+		}
+		w.alt(label, then, otherwise)
+	case w.accept("while"):
+		label := w.condition()
+		w.loop(label, w.branch(w.statement))
+	case w.accept("do"):
+		body := w.branch(w.statement)
+		w.expect("while")
+		label := w.condition()
+		w.expect(";")
+		w.loop(label, body)
+	case w.accept("for"):
+		w.forStatement()
+	case w.accept("return"):
+		if !w.is(";") {
+			if v := w.expression(); v.name {
+				w.returned = v.text
+			}
+		}
+		w.expect(";")
+	case w.accept("throw"):
+		w.expression()
+		w.expect(";")
+	case w.accept("try"):
+		w.tryStatement()
+	case w.accept("switch"):
+		w.switchStatement()
+	case w.accept("break"), w.accept("continue"):
+		if w.peek().kind == javaIdent {
+			w.next()
+		}
+		w.expect(";")
+	case w.accept("synchronized"):
+		w.condition()
+		w.block()
+	case w.is("assert"):
+		w.skipUntil(";")
+		w.expect(";")
+	case w.is("class") || w.is("interface") || w.is("enum") || w.is("record") && w.peekAt(1).kind == javaIdent:
+		// Local types
+		w.skipUntil("{")
+		w.skipBalanced("{", "}")
+	case w.peek().kind == javaIdent && w.peekAt(1).text == ":" && w.peekAt(2).text != ":":
+		// Labeled statement
+		w.pos += 2
+		w.statement()
+	case w.localDeclaration():
+	default:
+		if w.peek().kind == javaIdent && w.peekAt(1).text == "=" && w.peekAt(2).text != "=" {
+			w.target, w.targetPos = w.peek().text, w.pos+2
+		}
+		w.expression()
+		w.expect(";")
+	}
+}
+
+// condition parses an expression in parentheses and returns its words as label of a block.
+func (w *walker) condition() string {
+	w.expect("(")
+	start := w.pos
+	w.expression()
+	label := labelWords(w.tokens[start:w.pos])
+	w.expect(")")
+	return label
+}
+
+func (w *walker) alt(label string, then, otherwise []sequenceItem) {
+	if len(then) == 0 && len(otherwise) == 0 {
+		return
+	}
+	if label == "" {
+		label = "condition"
+	}
+	branches := [][]sequenceItem{then}
+	if len(otherwise) > 0 {
+		branches = append(branches, otherwise)
+	}
+	w.add(sequenceItem{block: "alt " + label, branches: branches})
+}
+
+func (w *walker) loop(label string, body []sequenceItem) {
+	if len(body) == 0 {
+		return
+	}
+	if label == "" {
+		label = "repeat"
+	}
+	w.add(sequenceItem{block: "loop " + label, branches: [][]sequenceItem{body}})
+}
+
+func (w *walker) forStatement() {
+	w.expect("(")
+
+	// Enhanced for statement
+	var element TypeRef
+	var name string
+	if w.try(func() {
+		w.modifiers()
+		element, _ = w.typeRef()
+		name = w.ident().text
+		w.expect(":")
+	}) {
+		start := w.pos
+		w.expression()
+		label := "each " + name + " in " + labelWords(w.tokens[start:w.pos])
+		w.expect(")")
+		w.locals[name] = element
+		w.loop(strings.TrimSpace(label), w.branch(w.statement))
+		return
+	}
+
+	if !w.localDeclaration() {
+		for !w.accept(";") {
+			w.expression()
+			w.accept(",")
+		}
+	}
+	start := w.pos
+	if !w.is(";") {
+		w.expression()
+	}
+	label := labelWords(w.tokens[start:w.pos])
+	w.expect(";")
+	update := w.branch(func() {
+		for !w.accept(")") {
+			w.expression()
+			w.accept(",")
+		}
+	})
+	body := w.branch(w.statement)
+	if label == "" {
+		label = "forever"
+	}
+	w.loop(label, append(body, update...))
+}
+
+func (w *walker) tryStatement() {
+	if w.accept("(") {
+		for !w.accept(")") {
+			if !w.localDeclaration() {
+				w.expression()
+				w.accept(";")
+			}
+		}
+	}
+	w.block()
+	for w.accept("catch") {
+		w.expect("(")
+		w.modifiers()
+		var types []string
+		for {
+			ref, _ := w.typeRef()
+			types = append(types, ref.Name)
+			if !w.accept("|") {
+				break
+			}
+		}
+		w.ident()
+		w.expect(")")
+		w.alt(labelWords(nil, append([]string{"catch"}, types...)...), w.branch(w.block), nil)
+	}
+	if w.accept("finally") {
+		w.block()
+	}
+}
+
+func (w *walker) switchStatement() {
+	selector := w.condition()
+	w.expect("{")
+
+	var branches [][]sequenceItem
+	var labels []string
+	for !w.accept("}") {
+		// Labels of a case, the statements up to the next case are its body
+		switch {
+		case w.accept("default"):
+			labels = append(labels, "default")
+		case w.accept("case"):
+			start := w.pos
+			for !w.is(":") && !(w.is("-") && w.peekAt(1).text == ">") {
+				if w.peek().kind == javaEOF {
+					w.fail("expected \":\", found end of file")
+				}
+				w.next()
+			}
+			labels = append(labels, labelWords(w.tokens[start:w.pos]))
+		default:
+			w.fail("expected \"case\", found %s", w.describe())
+		}
+		if !w.accept(":") {
+			w.pos += 2
+		}
+
+		body := w.branch(func() {
+			for !w.is("case") && !w.is("default") && !w.is("}") {
+				w.statement()
+			}
+		})
+		branches = append(branches, body)
+	}
+
+	// Only the label of the first case can be written, else has no label
+	for len(branches) > 0 && len(branches[len(branches)-1]) == 0 {
+		branches = branches[:len(branches)-1]
+	}
+	if len(branches) == 0 {
+		return
+	}
+	label := strings.TrimSpace(selector + " " + labels[0])
+	w.add(sequenceItem{block: "alt " + label, branches: branches})
+}
+
+// localDeclaration parses the declaration of local variables if one follows and reports
+// whether it did.
+func (w *walker) localDeclaration() bool {
+	start := w.pos
+	if !w.try(func() {
+		w.modifiers()
+		if _, void := w.typeRef(); void {
+			w.fail("void variable")
+		}
+		w.ident()
+		switch w.peek().text {
+		case "=", ";", ",", "[", ":":
+		default:
+			w.fail("not a declaration")
+		}
+	}) {
+		return false
+	}
+
+	w.pos = start
+	w.modifiers()
+	ref, _ := w.typeRef()
+	for {
+		name := w.ident().text
+		declared := ref
+		declared.Dimensions += w.dimensions()
+		if w.accept("=") {
+			if w.is("{") {
+				w.skipBalanced("{", "}")
+			} else {
+				w.target, w.targetPos = name, w.pos
+				if v := w.expression(); ref.Name == "var" {
+					declared = TypeRef{Name: v.typ}
+				}
+			}
+		}
+		w.locals[name] = declared
+		if !w.accept(",") {
+			break
+		}
+	}
+	w.expect(";")
+	return true
+}
+
+// binaryOperators are the symbols that form binary, assignment and conditional operators.
+const binaryOperators = "+-*/%&|^<>=!?:"
+
+// expression parses an expression and records its calls.
+func (w *walker) expression() value {
+	start := w.pos
+	v := w.unary()
+	operators := false
+	ternaries := 0
+	for {
+		t := w.peek()
+		switch {
+		case t.kind == javaSymbol && strings.Contains(binaryOperators, t.text) && (t.text != ":" || ternaries > 0):
+			switch t.text {
+			case "?":
+				ternaries++
+			case ":":
+				ternaries--
+			}
+			w.next()
+			if !w.is("(") && w.peek().kind == javaSymbol && strings.Contains(binaryOperators, w.peek().text) {
+				// Operators of several symbols
+				continue
+			}
+			w.unary()
+			operators = true
+		case w.accept("instanceof"):
+			w.modifiers()
+			w.typeRef()
+			if w.peek().kind == javaIdent {
+				w.next()
+			}
+			operators = true
+		default:
+			if operators {
+				return value{text: strconv.Quote(sourceText(w.tokens[start:w.pos]))}
+			}
+			return v
+		}
+	}
+}
+
+// unary parses an operand with its prefix operators.
+func (w *walker) unary() value {
+	prefixed := false
+	for w.is("!") || w.is("~") || w.is("-") || w.is("+") {
+		w.next()
+		prefixed = true
+	}
+	start := w.pos
+
+	var v value
+	switch {
+	case w.is("(") && w.lambdaFollows():
+		w.skipBalanced("(", ")")
+		v = w.lambda()
+	case w.accept("("):
+		inner := w.expression()
+		w.expect(")")
+		if w.castFollows() {
+			// The operand of a cast has the type of the cast
+			operand := w.unary()
+			if t := w.tokens[start+1]; t.kind == javaIdent {
+				operand.typ = t.text
+			}
+			return operand
+		}
+		v = w.postfix(inner, start)
+	default:
+		v = w.postfix(w.primary(), start)
+	}
+	if prefixed {
+		v = value{text: strconv.Quote(sourceText(w.tokens[start-1 : w.pos]))}
+	}
+	return v
+}
+
+// lambdaFollows reports whether the parenthesis at the current position starts the parameters
+// of a lambda expression.
+func (w *walker) lambdaFollows() bool {
+	depth := 0
+	for i := w.pos; i < len(w.tokens); i++ {
+		switch w.tokens[i].text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return i+2 < len(w.tokens) && w.tokens[i+1].text == "-" && w.tokens[i+2].text == ">"
+			}
+		}
+	}
+	return false
+}
+
+// castFollows reports whether the parenthesized expression just parsed is a cast.
+func (w *walker) castFollows() bool {
+	t := w.peek()
+	switch {
+	case t.kind == javaIdent:
+		return !w.is("instanceof")
+	case t.kind == javaLiteral:
+		return true
+	}
+	return w.is("(") || w.is("!") || w.is("~")
+}
+
+// lambda parses the body of a lambda expression after the arrow. Its calls are not recorded.
+func (w *walker) lambda() value {
+	w.expect("-")
+	w.expect(">")
+	w.muted++
+	defer func() { w.muted-- }()
+	if w.is("{") {
+		w.skipBalanced("{", "}")
+	} else {
+		w.expression()
+	}
+	return value{text: strconv.Quote("lambda")}
+}
+
+func (w *walker) primary() value {
+	t := w.peek()
+	switch {
+	case t.kind == javaLiteral:
+		w.next()
+		if strings.HasPrefix(t.text, `"`) && !strings.HasPrefix(t.text, `"""`) {
+			return value{text: t.text, typ: "String"}
+		}
+		return value{text: strconv.Quote(t.text)}
+	case w.accept("new"):
+		return w.creation()
+	case w.accept("this"):
+		if w.is("(") {
+			w.arguments()
+			return value{}
+		}
+		return value{text: "this", typ: w.t.Name}
+	case w.accept("super"):
+		if w.is("(") {
+			w.arguments()
+			return value{}
+		}
+		if len(w.t.Extends) > 0 {
+			return value{text: "super", typ: w.t.Extends[0]}
+		}
+		return value{text: "super"}
+	case w.accept("switch"):
+		w.condition()
+		w.muted++
+		w.skipBalanced("{", "}")
+		w.muted--
+		return value{text: strconv.Quote("switch")}
+	case t.kind == javaIdent && w.peekAt(1).text == "-" && w.peekAt(2).text == ">":
+		w.next()
+		return w.lambda()
+	case t.kind == javaIdent:
+		w.next()
+		switch t.text {
+		case "null", "true", "false":
+			return value{text: t.text}
+		}
+		if w.is("(") {
+			// Method of the own class
+			return w.invoke(value{text: "this", typ: w.t.Name}, t.text, w.pos-1)
+		}
+		if ref, ok := w.locals[t.text]; ok {
+			return value{text: argument(t.text), typ: typeName(ref), name: true}
+		}
+		if ref, ok := w.s.field(w.t, t.text); ok {
+			return value{text: argument(t.text), typ: typeName(ref), name: true}
+		}
+		if _, ok := w.s.types[t.text]; ok {
+			// Static access
+			return value{text: argument(t.text), typ: t.text}
+		}
+		return value{text: argument(t.text), name: true}
+	}
+	w.fail("unexpected %s", w.describe())
+	return value{}
+}
+
+// postfix parses the member accesses, calls and array accesses that follow an operand.
+func (w *walker) postfix(v value, start int) value {
+	for {
+		switch {
+		case w.accept("."):
+			if w.is("<") {
+				w.skipBalanced("<", ">")
+			}
+			if w.accept("new") {
+				v = w.creation()
+				continue
+			}
+			name := w.ident().text
+			if w.is("(") {
+				v = w.invoke(v, name, start)
+				continue
+			}
+			ref, _ := w.s.field(w.s.types[v.typ], name)
+			v = value{text: argument(name), typ: typeName(ref), name: true}
+		case w.accept("["):
+			w.expression()
+			w.expect("]")
+			v = value{text: strconv.Quote(sourceText(w.tokens[start:w.pos]))}
+		case w.is(":") && w.peekAt(1).text == ":":
+			// Method reference
+			w.pos += 2
+			w.next()
+			v = value{text: strconv.Quote(sourceText(w.tokens[start:w.pos]))}
+		case w.is("+") && w.peekAt(1).text == "+", w.is("-") && w.peekAt(1).text == "-":
+			w.pos += 2
+		default:
+			return v
+		}
+	}
+}
+
+// arguments parses the arguments of a call.
+func (w *walker) arguments() []string {
+	var arguments []string
+	w.expect("(")
+	for !w.accept(")") {
+		if len(arguments) > 0 {
+			w.expect(",")
+		}
+		arguments = append(arguments, w.expression().text)
+	}
+	return arguments
+}
+
+// assigned returns the variable the result of the call that started at start is assigned to,
+// if the call is the whole expression.
+func (w *walker) assigned(start int) string {
+	if w.target != "" && start == w.targetPos && (w.is(";") || w.is(",") || w.is(")")) {
+		return w.target
+	}
+	return ""
+}
+
+// invoke records the call of a method of the receiver and follows it into the method body.
+func (w *walker) invoke(receiver value, name string, start int) value {
+	arguments := w.arguments()
+	receiverType := w.s.types[receiver.typ]
+	if receiverType == nil {
+		return value{text: strconv.Quote(sourceText(w.tokens[start:w.pos]))}
+	}
+
+	owner, method := w.s.method(receiverType, name, len(arguments))
+	if method == nil || w.muted > 0 {
+		// Methods of classes outside the diagram, like Object.toString
+		return value{text: strconv.Quote(sourceText(w.tokens[start:w.pos]))}
+	}
+	to := receiverType.Name
+	if owner.Kind != Interface {
+		to = owner.Name
+	}
+	w.add(w.s.call(w.t.Name, to, name, arguments))
+
+	target := w.assigned(start)
+	body, returned := w.s.walk(owner, method, w.depth+1)
+	for _, item := range body {
+		w.add(item)
+	}
+	if method.Return == nil {
+		return value{text: strconv.Quote(sourceText(w.tokens[start:w.pos]))}
+	}
+
+	result := target
+	if result == "" {
+		result = resultName(name, returned)
+	}
+	w.add(w.s.answer(to, w.t.Name, result))
+	return value{text: result, typ: typeName(*method.Return), name: true}
+}
+
+// creation parses the creation of an object or array after new.
+func (w *walker) creation() value {
+	start := w.pos - 1
+	ref, _ := w.typeRef()
+	if w.is("[") || ref.Dimensions > 0 {
+		for w.accept("[") {
+			if !w.is("]") {
+				w.expression()
+			}
+			w.expect("]")
+		}
+		if w.is("{") {
+			w.skipBalanced("{", "}")
+		}
+		return value{text: strconv.Quote(sourceText(w.tokens[start:w.pos]))}
+	}
+
+	arguments := w.arguments()
+	if w.is("{") {
+		// Anonymous class
+		w.skipBalanced("{", "}")
+	}
+	t := w.s.types[ref.Name]
+	if t == nil || t.Kind == Interface || w.muted > 0 {
+		return value{text: strconv.Quote(sourceText(w.tokens[start:w.pos])), typ: ref.Name}
+	}
+
+	if !w.s.appeared[t.Name] {
+		w.add(sequenceItem{line: "create participant " + sequenceName(t.Name)})
+	}
+	w.add(w.s.call(w.t.Name, t.Name, t.Name, arguments))
+	result := w.assigned(start)
+	if result == "" {
+		result = strings.ToLower(t.Name[:1]) + t.Name[1:]
+	}
+	w.add(w.s.answer(t.Name, w.t.Name, result))
+	return value{text: result, typ: t.Name, name: true}
+}
+
+// typeName returns the name of a type reference, empty for arrays, whose elements are not
+// objects of the type.
+func typeName(ref TypeRef) string {
+	if ref.Dimensions > 0 {
+		return ""
+	}
+	return ref.Name
+}
+
+// resultName names the result of a call of method after the variable the method returns.
+func resultName(method, returned string) string {
+	if returned != "" && returned != "this" && !strings.HasPrefix(returned, `"`) {
+		return returned
+	}
+	return method + "Result"
+}
+
+// sequenceKeyword matches the names the sequence diagram lexer reads as keywords.
+var sequenceKeyword = regexp.MustCompile(`(?i)^(loop|alt|end|participant|actor|as|create|destroy|(de)?activate|note|sequenceDiagram)$`)
+
+// sequenceName turns a name into a word of the sequence diagram syntax. Characters the syntax
+// does not allow are replaced and keywords get a trailing underscore.
+func sequenceName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && (r == '_' || r >= '0' && r <= '9'):
+		case i == 0:
+			b.WriteByte('x')
+			if r == '_' || r >= '0' && r <= '9' {
+				break
+			}
+			r = '_'
+		default:
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	if sequenceKeyword.MatchString(b.String()) {
+		b.WriteByte('_')
+	}
+	return b.String()
+}
+
+// argument returns a variable name as argument of a message. Names that are not words of the
+// sequence diagram syntax are quoted.
+func argument(name string) string {
+	if name != sequenceName(name) {
+		return strconv.Quote(name)
+	}
+	return name
+}
+
+// sourceText joins tokens to the source text of an expression.
+func sourceText(tokens []javaToken) string {
+	var b strings.Builder
+	for i, t := range tokens {
+		if i > 0 && t.kind != javaSymbol && tokens[i-1].kind != javaSymbol {
+			b.WriteByte(' ')
+		}
+		b.WriteString(t.text)
+	}
+	return b.String()
+}
+
+// operatorWords are the words that replace operators in the labels of blocks.
+var operatorWords = map[string]string{
+	"!": "not", "&&": "and", "||": "or", "==": "is", "!=": "is not",
+	"<": "less than", ">": "greater than", "<=": "at most", ">=": "at least",
+}
+
+// labelWords returns the words of an expression that the label of an alt or loop block may
+// contain: names and the words of the operators in operatorWords. Further words can be given.
+func labelWords(tokens []javaToken, words ...string) string {
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch t.kind {
+		case javaIdent:
+			if t.text == "this" || t.text == "new" || sequenceKeyword.MatchString(t.text) || t.text != sequenceName(t.text) {
+				continue
+			}
+			words = append(words, t.text)
+		case javaSymbol:
+			// The scanner splits operators into their symbols
+			operator := t.text
+			if i+1 < len(tokens) && tokens[i+1].kind == javaSymbol {
+				if _, ok := operatorWords[operator+tokens[i+1].text]; ok {
+					i++
+					operator += tokens[i].text
+				}
+			}
+			if word, ok := operatorWords[operator]; ok {
+				words = append(words, word)
+			}
+		}
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,280 @@
+package semantic
+
+import (
+	"sort"
+)
+
+// ChangeKind is the kind of a change.
+type ChangeKind int
+
+const (
+	Added ChangeKind = iota
+	Removed
+	Changed
+	// Moved is a call that is made at another position of its sequence.
+	Moved
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	}
+	return "moved"
+}
+
+// MarshalText writes the kind as the verb of String.
+func (k ChangeKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Change is a difference between two models.
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+	// Element is the kind of element that changed: class, interface, enumeration, attribute,
+	// method, relationship, sequence or call.
+	Element string `json:"element"`
+	// Subject names the element, like Order.cancel() or DataService ->> AuthService: verify.
+	Subject string `json:"subject"`
+	// Property is the property of a changed element, like "return type", with its old and
+	// new value.
+	Property string `json:"property,omitempty"`
+	Old      string `json:"old,omitempty"`
+	New      string `json:"new,omitempty"`
+	// Sequence is the entry method of the sequence of a call.
+	Sequence string `json:"sequence,omitempty"`
+}
+
+func (c Change) String() string {
+	return c.format(func(s string) string { return s })
+}
+
+// Markdown returns the change as a sentence with the names and types in code spans.
+func (c Change) Markdown() string {
+	return c.format(func(s string) string { return "`" + s + "`" })
+}
+
+func (c Change) format(code func(string) string) string {
+	var text string
+	if c.Kind == Changed {
+		text = "changed " + code(c.Subject) + " " + c.Property + " from " + code(c.Old) + " to " + code(c.New)
+	} else {
+		text = c.Kind.String() + " " + c.Element + " " + code(c.Subject)
+	}
+	if c.Sequence != "" {
+		text += " in " + code(c.Sequence)
+	}
+	return text
+}
+
+// Compare returns the changes from the old to the new model, sorted by the names of the
+// classes, relationships and sequences they concern. Classes that were added or removed are
+// reported without their members.
+func Compare(old, new *Model) []Change {
+	var changes []Change
+	add := func(change Change) {
+		changes = append(changes, change)
+	}
+
+	for _, name := range sortedKeys(old.Classes, new.Classes) {
+		before, after := old.Classes[name], new.Classes[name]
+		switch {
+		case after == nil:
+			add(Change{Kind: Removed, Element: before.Kind, Subject: name})
+		case before == nil:
+			add(Change{Kind: Added, Element: after.Kind, Subject: name})
+		default:
+			compareClasses(before, after, add)
+		}
+	}
+
+	for _, key := range sortedKeys(old.Relationships, new.Relationships) {
+		before, wasThere := old.Relationships[key]
+		after, isThere := new.Relationships[key]
+		switch {
+		case !isThere:
+			add(Change{Kind: Removed, Element: "relationship", Subject: before.String()})
+		case !wasThere:
+			add(Change{Kind: Added, Element: "relationship", Subject: after.String()})
+		case before.LeftCardinality != after.LeftCardinality || before.RightCardinality != after.RightCardinality:
+			add(Change{
+				Kind: Changed, Element: "relationship", Subject: Relationship{Left: after.Left, Type: after.Type, Right: after.Right, Label: after.Label}.String(),
+				Property: "cardinality", Old: cardinality(before), New: cardinality(after),
+			})
+		}
+	}
+
+	for _, entry := range sortedKeys(old.Sequences, new.Sequences) {
+		before, wasThere := old.Sequences[entry]
+		after, isThere := new.Sequences[entry]
+		switch {
+		case !isThere:
+			add(Change{Kind: Removed, Element: "sequence", Subject: entry})
+		case !wasThere:
+			add(Change{Kind: Added, Element: "sequence", Subject: entry})
+		default:
+			for _, change := range compareCalls(before, after) {
+				change.Sequence = entry
+				add(change)
+			}
+		}
+	}
+	return changes
+}
+
+func compareClasses(before, after *Class, add func(Change)) {
+	name := after.Name
+	if before.Kind != after.Kind {
+		add(Change{Kind: Changed, Element: after.Kind, Subject: name, Property: "kind", Old: before.Kind, New: after.Kind})
+	}
+	if before.Package != after.Package {
+		add(Change{Kind: Changed, Element: after.Kind, Subject: name, Property: "package", Old: none(before.Package), New: none(after.Package)})
+	}
+
+	for _, attribute := range sortedKeys(before.Attributes, after.Attributes) {
+		old, wasThere := before.Attributes[attribute]
+		new, isThere := after.Attributes[attribute]
+		subject := name + "." + attribute
+		switch {
+		case !isThere:
+			add(Change{Kind: Removed, Element: "attribute", Subject: subject})
+		case !wasThere:
+			add(Change{Kind: Added, Element: "attribute", Subject: subject})
+		default:
+			if old.Type != new.Type {
+				add(Change{Kind: Changed, Element: "attribute", Subject: subject, Property: "type", Old: none(old.Type), New: none(new.Type)})
+			}
+			if old.Visibility != new.Visibility {
+				add(Change{Kind: Changed, Element: "attribute", Subject: subject, Property: "visibility", Old: none(old.Visibility), New: none(new.Visibility)})
+			}
+		}
+	}
+
+	for _, key := range sortedKeys(before.Methods, after.Methods) {
+		old, wasThere := before.Methods[key]
+		new, isThere := after.Methods[key]
+		switch {
+		case !isThere:
+			add(Change{Kind: Removed, Element: "method", Subject: name + "." + old.Name + old.Signature()})
+		case !wasThere:
+			add(Change{Kind: Added, Element: "method", Subject: name + "." + new.Name + new.Signature()})
+		default:
+			subject := name + "." + new.Name
+			if old.Signature() != new.Signature() {
+				add(Change{Kind: Changed, Element: "method", Subject: subject, Property: "parameters", Old: old.Signature(), New: new.Signature()})
+			}
+			if old.Return != new.Return {
+				add(Change{Kind: Changed, Element: "method", Subject: subject, Property: "return type", Old: void(old.Return), New: void(new.Return)})
+			}
+			if old.Visibility != new.Visibility {
+				add(Change{Kind: Changed, Element: "method", Subject: subject, Property: "visibility", Old: none(old.Visibility), New: none(new.Visibility)})
+			}
+		}
+	}
+}
+
+// compareCalls returns the calls that were removed from or added to a sequence. Calls that
+// were removed at one position and added at another were moved.
+func compareCalls(before, after []Call) []Change {
+	// Longest common subsequence of the calls
+	n, m := len(before), len(after)
+	common := make([][]int, n+1)
+	for i := range common {
+		common[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var changes []Change
+	for i, j := 0, 0; i < n || j < m; {
+		switch {
+		case i < n && j < m && before[i] == after[j]:
+			i++
+			j++
+		case j == m || i < n && common[i+1][j] >= common[i][j+1]:
+			changes = append(changes, Change{Kind: Removed, Element: "call", Subject: before[i].String()})
+			i++
+		default:
+			changes = append(changes, Change{Kind: Added, Element: "call", Subject: after[j].String()})
+			j++
+		}
+	}
+
+	// A call that is removed and added again is moved, it is reported where it is added
+	removed, added := make(map[string]int), make(map[string]int)
+	for _, change := range changes {
+		if change.Kind == Removed {
+			removed[change.Subject]++
+		} else {
+			added[change.Subject]++
+		}
+	}
+	dropped, moved := make(map[string]int), make(map[string]int)
+	var result []Change
+	for _, change := range changes {
+		pairs := min(removed[change.Subject], added[change.Subject])
+		switch {
+		case change.Kind == Removed && dropped[change.Subject] < pairs:
+			dropped[change.Subject]++
+			continue
+		case change.Kind == Added && moved[change.Subject] < pairs:
+			moved[change.Subject]++
+			change.Kind = Moved
+		}
+		result = append(result, change)
+	}
+	return result
+}
+
+// cardinality returns the cardinalities of both ends of a relationship, like "1" "*".
+func cardinality(r Relationship) string {
+	quote := func(cardinality string) string {
+		if cardinality == "" {
+			return "none"
+		}
+		return `"` + cardinality + `"`
+	}
+	return quote(r.LeftCardinality) + " " + quote(r.RightCardinality)
+}
+
+// none returns s, or "none" if s is empty.
+func none(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// void returns the return type t, or "void" for methods without result.
+func void(t string) string {
+	if t == "" {
+		return "void"
+	}
+	return t
+}
+
+// sortedKeys returns the keys of both maps in lexical order.
+func sortedKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, exists := a[key]; !exists {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
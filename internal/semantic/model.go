@@ -0,0 +1,270 @@
+// Package semantic compares diagrams by their meaning instead of their text.
+//
+// Build collects the classes, members and relationships of class diagrams and the calls of
+// sequence diagrams into a Model. The model does not depend on the layout of the diagrams:
+// members are the same whether they are declared in a class body or with "Class : member",
+// relationships are the same in both directions of writing, and the diagrams may be spread
+// over any number of documents. Compare lists the changes between two models.
+package semantic
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+)
+
+// Model is the meaning of a set of diagrams.
+type Model struct {
+	// Classes are the classes, interfaces and enumerations by name.
+	Classes map[string]*Class
+	// Relationships are the relationships by key, see Relationship.Key.
+	Relationships map[string]Relationship
+	// Sequences are the calls of the sequence diagrams by entry method, like Order.total.
+	Sequences map[string][]Call
+}
+
+// Class is a class, interface or enumeration.
+type Class struct {
+	Name    string
+	Package string
+	// Kind is "class", "interface" or "enumeration".
+	Kind       string
+	Attributes map[string]Attribute
+	// Methods are keyed by name and number of parameters, see Method.Key.
+	Methods map[string]Method
+}
+
+// Attribute is an attribute of a class or a constant of an enumeration, which has no type.
+type Attribute struct {
+	Name       string
+	Visibility string
+	Type       string
+}
+
+// Method is an operation of a class.
+type Method struct {
+	Name       string
+	Visibility string
+	Parameters []Parameter
+	// Return is the result type, empty for methods without result.
+	Return string
+}
+
+// Parameter is a method parameter.
+type Parameter struct {
+	Type string
+	Name string
+}
+
+// Relationship is a relationship between two classes. Relationships written with the arrow
+// head on the other side are turned around: inheritance, realization, composition and
+// aggregation point to the left, associations and dependencies to the right.
+type Relationship struct {
+	Left             string
+	LeftCardinality  string
+	Type             string
+	RightCardinality string
+	Right            string
+	Label            string
+}
+
+// Call is a synchronous message of a sequence diagram.
+type Call struct {
+	From string
+	To   string
+	Name string
+}
+
+// Key identifies a method among the methods of its class.
+func (m Method) Key() string {
+	return m.Name + "/" + strconv.Itoa(len(m.Parameters))
+}
+
+// Signature returns the parameter list of the method, like (Item item, int count).
+func (m Method) Signature() string {
+	parameters := make([]string, len(m.Parameters))
+	for i, parameter := range m.Parameters {
+		parameters[i] = strings.TrimSpace(parameter.Type + " " + parameter.Name)
+	}
+	return "(" + strings.Join(parameters, ", ") + ")"
+}
+
+// Key identifies a relationship: its classes, its type and its label. The cardinalities can
+// change.
+func (r Relationship) Key() string {
+	return r.Left + " " + r.Type + " " + r.Right + " : " + r.Label
+}
+
+func (r Relationship) String() string {
+	text := r.Left
+	if r.LeftCardinality != "" {
+		text += ` "` + r.LeftCardinality + `"`
+	}
+	text += " " + r.Type + " "
+	if r.RightCardinality != "" {
+		text += `"` + r.RightCardinality + `" `
+	}
+	text += r.Right
+	if r.Label != "" {
+		text += " : " + r.Label
+	}
+	return text
+}
+
+func (c Call) String() string {
+	return c.From + " ->> " + c.To + ": " + c.Name
+}
+
+// NewModel returns an empty model.
+func NewModel() *Model {
+	return &Model{
+		Classes:       make(map[string]*Class),
+		Relationships: make(map[string]Relationship),
+		Sequences:     make(map[string][]Call),
+	}
+}
+
+// Build returns the model of diagrams.
+func Build(diagrams []reader.Diagram) *Model {
+	m := NewModel()
+	for _, diagram := range diagrams {
+		m.Add(diagram)
+	}
+	return m
+}
+
+// Add adds the classes, relationships or calls of a diagram to the model. Members that are
+// declared twice keep their first declaration, sequence diagrams of the same entry method are
+// joined.
+func (m *Model) Add(diagram reader.Diagram) {
+	switch {
+	case diagram.IsClass && diagram.Class != nil:
+		m.addClassDiagram(diagram.Class)
+	case diagram.IsSequence && diagram.Sequence != nil:
+		m.addSequenceDiagram(diagram.Sequence)
+	}
+}
+
+func (m *Model) class(name string) *Class {
+	class, exists := m.Classes[name]
+	if !exists {
+		class = &Class{Name: name, Kind: "class", Attributes: make(map[string]Attribute), Methods: make(map[string]Method)}
+		m.Classes[name] = class
+	}
+	return class
+}
+
+func (m *Model) addClassDiagram(diagram *reader.ClassDiagram) {
+	for _, instruction := range diagram.Instructions {
+		switch {
+		case instruction.Namespace != nil:
+			pkg := instruction.Namespace.Name
+			for _, declaration := range instruction.Namespace.Classes {
+				m.addClassDeclaration(declaration).Package = pkg
+			}
+		case instruction.Class != nil:
+			m.addClassDeclaration(instruction.Class)
+		case instruction.Member != nil:
+			member := instruction.Member
+			m.class(member.Class).addMember(member.Visibility, member.Operation, member.Attribute)
+		case instruction.Annotation != nil:
+			m.class(instruction.Annotation.Class).Kind = kind(instruction.Annotation.Name)
+		case instruction.Relationship != nil:
+			r := relationship(instruction.Relationship)
+			m.class(r.Left)
+			m.class(r.Right)
+			if _, exists := m.Relationships[r.Key()]; !exists {
+				m.Relationships[r.Key()] = r
+			}
+		}
+	}
+}
+
+func (m *Model) addClassDeclaration(declaration *reader.ClassDeclaration) *Class {
+	class := m.class(declaration.Name)
+	for _, member := range declaration.Members {
+		class.addMember(member.Visibility, member.Operation, member.Attribute)
+	}
+	return class
+}
+
+func (c *Class) addMember(visibility string, operation *reader.Operation, attribute *reader.Attribute) {
+	switch {
+	case operation != nil:
+		method := Method{Name: operation.Name, Visibility: visibility, Return: operation.Return}
+		if method.Return == "void" {
+			method.Return = ""
+		}
+		for _, parameter := range operation.Parameters {
+			method.Parameters = append(method.Parameters, Parameter{Type: parameter.Type, Name: parameter.Name})
+		}
+		if _, exists := c.Methods[method.Key()]; !exists {
+			c.Methods[method.Key()] = method
+		}
+	case attribute != nil:
+		if _, exists := c.Attributes[attribute.Name]; !exists {
+			c.Attributes[attribute.Name] = Attribute{Name: attribute.Name, Visibility: visibility, Type: attribute.Type}
+		}
+	}
+}
+
+// kind returns the kind of a class annotated with name.
+func kind(name string) string {
+	switch strings.ToLower(name) {
+	case "interface":
+		return "interface"
+	case "enumeration", "enum":
+		return "enumeration"
+	}
+	return "class"
+}
+
+// arrowHeads are the arrow heads of relationships and their mirror images.
+var arrowHeads = map[string]string{"<|": "|>", "|>": "<|", "<": ">", ">": "<", "*": "*", "o": "o"}
+
+// relationship returns the relationship written in the direction described at Relationship.
+func relationship(r *reader.Relationship) Relationship {
+	result := Relationship{
+		Left: r.LeftClass, LeftCardinality: r.LeftCardinality, Type: r.Type,
+		RightCardinality: r.RightCardinality, Right: r.RightClass, Label: r.Label,
+	}
+
+	i := strings.IndexAny(r.Type, "-.")
+	left, line, right := r.Type[:i], r.Type[i:i+2], r.Type[i+2:]
+
+	turn := false
+	switch {
+	case left == "" && (right == "|>" || right == "*" || right == "o"):
+		turn = true
+	case left == "<" && right == "":
+		turn = true
+	}
+	if turn {
+		result = Relationship{
+			Left: result.Right, LeftCardinality: result.RightCardinality, Type: arrowHeads[right] + line + arrowHeads[left],
+			RightCardinality: result.LeftCardinality, Right: result.Left, Label: result.Label,
+		}
+	}
+	return result
+}
+
+func (m *Model) addSequenceDiagram(diagram *reader.SequenceDiagram) {
+	var entry string
+	var calls []Call
+	for _, instruction := range diagram.Instructions {
+		message := instruction.Message
+		if message == nil || strings.HasPrefix(message.Type, "--") {
+			continue
+		}
+		if entry == "" {
+			// The first message calls the method the diagram describes
+			entry = message.Right + "." + message.Name
+			continue
+		}
+		calls = append(calls, Call{From: message.Left, To: message.Right, Name: message.Name})
+	}
+	if entry != "" {
+		m.Sequences[entry] = append(m.Sequences[entry], calls...)
+	}
+}
@@ -0,0 +1,138 @@
+package semantic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+)
+
+func build(t *testing.T, diagrams ...string) *Model {
+	t.Helper()
+	var parsed []reader.Diagram
+	for _, diagram := range diagrams {
+		d, err := reader.ParseDiagram(diagram)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed = append(parsed, *d)
+	}
+	return Build(parsed)
+}
+
+const oldClasses = `classDiagram
+    namespace com.shop {
+        class Order {
+            -String id
+            +total() int
+        }
+    }
+    DataService : +fetch(String token) Data
+    DataService : -Cache cache
+    class Data
+    class Cache
+    Order --|> Entity
+    Order "1" --> "*" Item : items
+    Data <-- DataService
+`
+
+const oldSequence = `sequenceDiagram
+    actor user
+    user->>Application: login(user, password)
+    Application->>AuthService: authenticate(user, password)
+    AuthService-->>Application: token
+    Application->>DataService: fetch(token)
+    DataService->>AuthService: verify(token)
+    AuthService-->>DataService: valid
+    DataService-->>Application: data
+    Application-->>user: data
+`
+
+func TestBuild(t *testing.T) {
+	m := build(t, oldClasses, oldSequence)
+
+	order := m.Classes["Order"]
+	if order == nil || order.Package != "com.shop" || order.Kind != "class" {
+		t.Fatalf("unexpected Order %+v", order)
+	}
+	if total := order.Methods["total/0"]; total.Return != "int" || total.Visibility != "+" {
+		t.Errorf("unexpected total %+v", total)
+	}
+	if fetch := m.Classes["DataService"].Methods["fetch/1"]; fetch.Signature() != "(String token)" {
+		t.Errorf("unexpected fetch %+v", fetch)
+	}
+
+	var relationships []string
+	for _, key := range sortedKeys(m.Relationships, nil) {
+		relationships = append(relationships, m.Relationships[key].String())
+	}
+	expected := []string{"DataService --> Data", "Entity <|-- Order", `Order "1" --> "*" Item : items`}
+	if strings.Join(relationships, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected relationships %q, got %q", expected, relationships)
+	}
+
+	calls := m.Sequences["Application.login"]
+	if len(calls) != 3 || calls[2].String() != "DataService ->> AuthService: verify" {
+		t.Errorf("unexpected calls %v", calls)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	old := build(t, oldClasses, oldSequence)
+	new := build(t, `classDiagram
+    namespace com.shop {
+        class Order {
+            +String id
+            +total() int
+            +cancel() void
+        }
+    }
+    DataService : +fetch(String token) Optional~Data~
+    class Data
+    class Audit
+    <<interface>> Audit
+    Entity <|-- Order
+    Order "1" --> "0..1" Item : items
+`, `sequenceDiagram
+    actor user
+    user->>Application: login(user, password)
+    Application->>DataService: fetch(token)
+    Application->>AuthService: authenticate(user, password)
+    AuthService-->>Application: token
+    DataService-->>Application: data
+    Application-->>user: data
+`, `sequenceDiagram
+    actor user
+    user->>Order: cancel()
+`)
+
+	var changes []string
+	for _, change := range Compare(old, new) {
+		changes = append(changes, change.String())
+	}
+	expected := []string{
+		"added interface Audit",
+		"removed class Cache",
+		"removed attribute DataService.cache",
+		"changed DataService.fetch return type from Data to Optional~Data~",
+		"changed Order.id visibility from - to +",
+		"added method Order.cancel()",
+		"removed relationship DataService --> Data",
+		`changed Order --> Item : items cardinality from "1" "*" to "1" "0..1"`,
+		"removed call DataService ->> AuthService: verify in Application.login",
+		"moved call Application ->> AuthService: authenticate in Application.login",
+		"added sequence Order.cancel",
+	}
+	if strings.Join(changes, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(changes, "\n"))
+	}
+
+	markdown := Change{Kind: Changed, Element: "method", Subject: "DataService.fetch", Property: "return type", Old: "Data", New: "Optional~Data~"}.Markdown()
+	if markdown != "changed `DataService.fetch` return type from `Data` to `Optional~Data~`" {
+		t.Errorf("unexpected Markdown %q", markdown)
+	}
+
+	if changes := Compare(old, build(t, oldSequence, oldClasses)); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+}
@@ -0,0 +1,404 @@
+// Package server exposes the code generation as a JSON API over HTTP.
+//
+//	GET  /healthz       reports that the server is running
+//	POST /v1/generate   generates the Java files of the posted documents
+//
+// A generate request is either a JSON object
+//
+//	{
+//	  "documents": [{"path": "orders.md", "content": "...", "syntax": "markdown"}],
+//	  "options": {"basePackage": "com.acme", "setters": false}
+//	}
+//
+// or the raw text of a single Markdown, AsciiDoc or Mermaid document, with the syntax taken
+// from the Content-Type or the syntax query parameter. The response lists the generated files
+// and the diagnostics; with ?format=zip or Accept: application/zip the files are returned as a
+// zip archive instead.
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	generator "github.com/MarmaidTranspiler/Merfolk/internal/CodeTemplateGenerator"
+	"github.com/MarmaidTranspiler/Merfolk/internal/config"
+	"github.com/MarmaidTranspiler/Merfolk/internal/lint"
+	"github.com/MarmaidTranspiler/Merfolk/internal/project"
+	"github.com/MarmaidTranspiler/Merfolk/internal/reader"
+)
+
+// Limits restrict the resources a single request may use.
+type Limits struct {
+	// MaxBodyBytes is the maximum size of a request body.
+	MaxBodyBytes int64
+	// Timeout is the maximum time to handle a request.
+	Timeout time.Duration
+}
+
+// DefaultLimits are used for limits that are zero.
+var DefaultLimits = Limits{MaxBodyBytes: 1 << 20, Timeout: 10 * time.Second}
+
+// Server generates code for HTTP requests. The project configuration supplies the defaults of
+// the generation options, requests may override the options but not the templates.
+type Server struct {
+	config  *config.Config
+	limits  Limits
+	version string
+}
+
+// New returns a server that generates code with the settings of cfg.
+func New(cfg *config.Config, limits Limits, version string) *Server {
+	if limits.MaxBodyBytes <= 0 {
+		limits.MaxBodyBytes = DefaultLimits.MaxBodyBytes
+	}
+	if limits.Timeout <= 0 {
+		limits.Timeout = DefaultLimits.Timeout
+	}
+	return &Server{config: cfg, limits: limits, version: version}
+}
+
+// Handler returns the HTTP handler of the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.health)
+	mux.HandleFunc("/v1/generate", s.generate)
+	return http.TimeoutHandler(mux, s.limits.Timeout, `{"error":"request timed out"}`)
+}
+
+// Document is a document of a generate request.
+type Document struct {
+	// Path names the document in diagnostics and, with mirror, places the generated files.
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	// Syntax is markdown, asciidoc, mermaid or plantuml. If empty it is derived from the extension of Path.
+	Syntax string `json:"syntax,omitempty"`
+}
+
+// Options override the generation settings of the project configuration.
+type Options struct {
+	BasePackage     string                           `json:"basePackage,omitempty"`
+	InterfacePrefix *string                          `json:"interfacePrefix,omitempty"`
+	Mirror          *bool                            `json:"mirror,omitempty"`
+	Getters         *bool                            `json:"getters,omitempty"`
+	Setters         *bool                            `json:"setters,omitempty"`
+	Constructors    *bool                            `json:"constructors,omitempty"`
+	Types           map[string]generator.TypeMapping `json:"types,omitempty"`
+	Imports         map[string]string                `json:"imports,omitempty"`
+}
+
+// Request is the JSON body of a generate request.
+type Request struct {
+	Documents []Document `json:"documents"`
+	Options   Options    `json:"options"`
+}
+
+// File is a generated file.
+type File struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// Diagnostic is a problem found in a document.
+type Diagnostic struct {
+	Path     string `json:"path,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Response is the JSON body of a generate response.
+type Response struct {
+	Files       []File       `json:"files"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	// Errors is the number of diagnostics with severity error.
+	Errors int `json:"errors"`
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "version": s.version})
+}
+
+func (s *Server) generate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, s.limits.MaxBodyBytes)
+	request, status, err := readRequest(r)
+	if err != nil {
+		writeError(w, status, err.Error())
+		return
+	}
+	if len(request.Documents) == 0 {
+		writeError(w, http.StatusBadRequest, "no documents given")
+		return
+	}
+
+	cfg, err := s.requestConfig(request.Options)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := run(cfg, request.Documents)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if r.URL.Query().Get("format") == "zip" || acceptsZip(r) {
+		writeZip(w, response)
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// readRequest decodes a JSON request or wraps a raw document into a request.
+func readRequest(r *http.Request) (*Request, int, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, http.StatusRequestEntityTooLarge, fmt.Errorf("request body exceeds %d bytes", tooLarge.Limit)
+		}
+		return nil, http.StatusBadRequest, err
+	}
+
+	mediaType := "application/json"
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err = mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, http.StatusUnsupportedMediaType, err
+		}
+	}
+
+	query := r.URL.Query()
+	switch mediaType {
+	case "application/json":
+		var request Request
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&request); err != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err)
+		}
+		return &request, http.StatusOK, nil
+
+	case "text/markdown", "text/x-markdown", "text/plain", "text/asciidoc", "text/x-asciidoc", "text/vnd.mermaid", "text/x-mermaid", "text/x-plantuml":
+		syntax := query.Get("syntax")
+		if syntax == "" {
+			switch mediaType {
+			case "text/asciidoc", "text/x-asciidoc":
+				syntax = "asciidoc"
+			case "text/vnd.mermaid", "text/x-mermaid":
+				syntax = "mermaid"
+			case "text/x-plantuml":
+				syntax = "plantuml"
+			default:
+				syntax = "markdown"
+			}
+		}
+		name := query.Get("path")
+		if name == "" {
+			name = "document"
+		}
+
+		request := &Request{Documents: []Document{{Path: name, Content: string(body), Syntax: syntax}}}
+		request.Options.BasePackage = query.Get("basePackage")
+		if prefix, given := query["interfacePrefix"]; given {
+			request.Options.InterfacePrefix = &prefix[0]
+		}
+		return request, http.StatusOK, nil
+	}
+
+	return nil, http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content type %s", mediaType)
+}
+
+// requestConfig applies the options of a request to a copy of the server configuration.
+func (s *Server) requestConfig(options Options) (*config.Config, error) {
+	cfg := *s.config
+	if options.BasePackage != "" {
+		cfg.BasePackage = options.BasePackage
+	}
+	if options.InterfacePrefix != nil {
+		cfg.Naming.InterfacePrefix = *options.InterfacePrefix
+	}
+	if options.Mirror != nil {
+		cfg.Mirror = *options.Mirror
+	}
+	if options.Getters != nil {
+		cfg.Features.Getters = *options.Getters
+	}
+	if options.Setters != nil {
+		cfg.Features.Setters = *options.Setters
+	}
+	if options.Constructors != nil {
+		cfg.Features.Constructors = *options.Constructors
+	}
+	if len(options.Types) > 0 {
+		cfg.Types = make(map[string]generator.TypeMapping, len(s.config.Types)+len(options.Types))
+		for name, mapping := range s.config.Types {
+			cfg.Types[name] = mapping
+		}
+		for name, mapping := range options.Types {
+			cfg.Types[name] = mapping
+		}
+	}
+	if len(options.Imports) > 0 {
+		cfg.Imports = make(map[string]string, len(s.config.Imports)+len(options.Imports))
+		for name, fullName := range s.config.Imports {
+			cfg.Imports[name] = fullName
+		}
+		for name, fullName := range options.Imports {
+			cfg.Imports[name] = fullName
+		}
+	}
+	return &cfg, cfg.Validate()
+}
+
+// run lints and parses the documents and generates the code.
+func run(cfg *config.Config, documents []Document) (*Response, error) {
+	rules, err := lint.NewConfig(cfg.Lint.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &Response{Files: []File{}, Diagnostics: []Diagnostic{}}
+	var parsed []project.Document
+	for i, document := range documents {
+		if document.Path == "" {
+			document.Path = fmt.Sprintf("document%d", i+1)
+		}
+		syntax, err := parseSyntax(document.Syntax, document.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		// Lint reports parse errors as well, documents that do not parse are skipped
+		findings, err := lint.Document(document.Content, syntax, rules)
+		if err != nil {
+			response.add(Diagnostic{Path: document.Path, Severity: lint.Error.String(), Message: err.Error()})
+			continue
+		}
+		for _, finding := range findings {
+			response.add(Diagnostic{
+				Path:     document.Path,
+				Line:     finding.Line,
+				Severity: finding.Severity.String(),
+				Rule:     finding.Rule,
+				Message:  finding.Message,
+			})
+		}
+
+		diagrams, err := reader.ParseDocument(document.Content, syntax)
+		if err != nil {
+			continue
+		}
+		parsed = append(parsed, project.Document{Path: path.Clean("/" + document.Path)[1:], Diagrams: diagrams})
+	}
+
+	report := func(d project.Diagnostic) {
+		response.add(Diagnostic{Path: d.Path, Line: d.Line, Severity: lint.Error.String(), Message: d.Message})
+	}
+	p := project.Build(cfg, parsed, report)
+	for _, file := range project.Render(cfg, p, report) {
+		response.Files = append(response.Files, File{Path: file.Path, Content: string(file.Content)})
+	}
+
+	return response, nil
+}
+
+func (response *Response) add(d Diagnostic) {
+	if d.Severity == lint.Error.String() {
+		response.Errors++
+	}
+	response.Diagnostics = append(response.Diagnostics, d)
+}
+
+func parseSyntax(name, documentPath string) (reader.Syntax, error) {
+	switch strings.ToLower(name) {
+	case "":
+		return reader.SyntaxOf(documentPath), nil
+	case "markdown", "md", "mdx":
+		return reader.Markdown, nil
+	case "asciidoc", "adoc":
+		return reader.AsciiDoc, nil
+	case "mermaid", "mmd":
+		return reader.Mermaid, nil
+	case "plantuml", "puml":
+		return reader.PlantUML, nil
+	}
+	return reader.Markdown, fmt.Errorf("unknown syntax %q, expected markdown, asciidoc, mermaid or plantuml", name)
+}
+
+func acceptsZip(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept)); err == nil && mediaType == "application/zip" {
+			return true
+		}
+	}
+	return false
+}
+
+// writeZip writes the generated files as zip archive. Diagnostics are added as
+// merfolk-diagnostics.json if there are any.
+func writeZip(w http.ResponseWriter, response *Response) {
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	add := func(name string, content []byte) error {
+		file, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = file.Write(content)
+		return err
+	}
+
+	for _, file := range response.Files {
+		if err := add(file.Path, []byte(file.Content)); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if len(response.Diagnostics) > 0 {
+		content, _ := json.MarshalIndent(response.Diagnostics, "", "  ")
+		if err := add("merfolk-diagnostics.json", content); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="merfolk.zip"`)
+	w.Header().Set("X-Merfolk-Errors", fmt.Sprint(response.Errors))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buffer.Bytes())
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
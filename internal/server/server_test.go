@@ -0,0 +1,190 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MarmaidTranspiler/Merfolk/internal/config"
+)
+
+const orders = "# Orders\n\n```mermaid\nclassDiagram\nclass Order\nOrder : +int id\n```\n"
+
+func newTestServer(t *testing.T, limits Limits) *httptest.Server {
+	t.Helper()
+	s := httptest.NewServer(New(config.Default(), limits, "test").Handler())
+	t.Cleanup(s.Close)
+	return s
+}
+
+func post(t *testing.T, url, contentType string, body string) *http.Response {
+	t.Helper()
+	response, err := http.Post(url, contentType, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { response.Body.Close() })
+	return response
+}
+
+func decode(t *testing.T, response *http.Response) *Response {
+	t.Helper()
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(response.Body)
+		t.Fatalf("status %d: %s", response.StatusCode, body)
+	}
+	var result Response
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	return &result
+}
+
+func TestHealth(t *testing.T) {
+	s := newTestServer(t, Limits{})
+	response, err := http.Get(s.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	var status map[string]string
+	if err := json.NewDecoder(response.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusOK || status["status"] != "ok" || status["version"] != "test" {
+		t.Errorf("unexpected health response %d %v", response.StatusCode, status)
+	}
+}
+
+func TestGenerateJSON(t *testing.T) {
+	s := newTestServer(t, Limits{})
+	request, _ := json.Marshal(Request{
+		Documents: []Document{{Path: "orders.md", Content: orders}},
+		Options:   Options{BasePackage: "com.acme"},
+	})
+
+	result := decode(t, post(t, s.URL+"/v1/generate", "application/json", string(request)))
+	if len(result.Files) != 2 || result.Files[0].Path != "com/acme/Order.java" {
+		t.Fatalf("unexpected files %+v", result.Files)
+	}
+	if !strings.Contains(result.Files[0].Content, "package com.acme;") {
+		t.Errorf("base package not applied:\n%s", result.Files[0].Content)
+	}
+	if result.Errors != 0 {
+		t.Errorf("unexpected diagnostics %+v", result.Diagnostics)
+	}
+}
+
+func TestGenerateRaw(t *testing.T) {
+	s := newTestServer(t, Limits{})
+	diagram := "classDiagram\nclass Order\nOrder : +int id\nOrder : +total() int\n"
+
+	result := decode(t, post(t, s.URL+"/v1/generate?path=orders.mmd", "text/vnd.mermaid", diagram))
+	if len(result.Files) == 0 || !strings.Contains(result.Files[0].Content, "total()") {
+		t.Fatalf("unexpected files %+v", result.Files)
+	}
+
+	plantUML := "@startuml\nclass Order {\n  + id : int\n  + total() : int\n}\n@enduml\n"
+	result = decode(t, post(t, s.URL+"/v1/generate", "text/x-plantuml", plantUML))
+	if len(result.Files) == 0 || !strings.Contains(result.Files[0].Content, "total()") {
+		t.Fatalf("unexpected files %+v", result.Files)
+	}
+}
+
+func TestGenerateDiagnostics(t *testing.T) {
+	s := newTestServer(t, Limits{})
+	request, _ := json.Marshal(Request{Documents: []Document{
+		{Path: "orders.md", Content: orders},
+		{Path: "broken.md", Content: "# Broken\n\n```mermaid\nclassDiagram\nclass\n```\n"},
+	}})
+
+	// The broken document is reported, the other one is still generated
+	result := decode(t, post(t, s.URL+"/v1/generate", "application/json", string(request)))
+	if result.Errors != 1 || len(result.Files) != 2 {
+		t.Fatalf("expected one error and two files, got %+v", result)
+	}
+	var syntax *Diagnostic
+	for i := range result.Diagnostics {
+		if result.Diagnostics[i].Severity == "error" {
+			syntax = &result.Diagnostics[i]
+		}
+	}
+	if syntax == nil || syntax.Path != "broken.md" || syntax.Line != 5 {
+		t.Errorf("unexpected diagnostics %+v", result.Diagnostics)
+	}
+}
+
+func TestGenerateZip(t *testing.T) {
+	s := newTestServer(t, Limits{})
+	response := post(t, s.URL+"/v1/generate?format=zip", "text/markdown", orders)
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "application/zip" {
+		t.Fatalf("unexpected response %d %s", response.StatusCode, response.Header.Get("Content-Type"))
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	if strings.Join(names, " ") != "Order.java IOrder.java" {
+		t.Errorf("unexpected archive entries %v", names)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	s := newTestServer(t, Limits{MaxBodyBytes: 64})
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"invalid JSON", "application/json", "{", http.StatusBadRequest},
+		{"unknown field", "application/json", `{"documents":[],"template":"x"}`, http.StatusBadRequest},
+		{"no documents", "application/json", `{"documents":[]}`, http.StatusBadRequest},
+		{"unknown syntax", "application/json", `{"documents":[{"content":"","syntax":"rst"}]}`, http.StatusBadRequest},
+		{"unsupported type", "image/png", "x", http.StatusUnsupportedMediaType},
+		{"too large", "text/markdown", strings.Repeat("x", 65), http.StatusRequestEntityTooLarge},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := post(t, s.URL+"/v1/generate", test.contentType, test.body)
+			if response.StatusCode != test.status {
+				t.Errorf("expected status %d, got %d", test.status, response.StatusCode)
+			}
+		})
+	}
+
+	response, err := http.Get(s.URL + "/v1/generate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET: expected status %d, got %d", http.StatusMethodNotAllowed, response.StatusCode)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	s := New(config.Default(), Limits{Timeout: time.Nanosecond}, "test")
+	recorder := httptest.NewRecorder()
+	s.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/generate", strings.NewReader(orders)))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, recorder.Code)
+	}
+}